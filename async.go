@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+)
+
+// SendResult represents the result of the asynchronous delivery of a single Msg via
+// Client.SendAsync.
+type SendResult struct {
+	// Msg is the Msg the result belongs to.
+	Msg *Msg
+
+	// Err is the error that caused the delivery of the Msg to fail, or nil if the Msg has been
+	// delivered. If the Msg has been rejected by the SMTP server, Err is its SendError.
+	Err error
+}
+
+// SendAsync delivers the provided messages in the background and returns immediately, e.g. so
+// that an HTTP handler does not have to wait for the SMTP server.
+//
+// The messages are delivered in the same way as with DialAndSendWithContext, on a new connection
+// to the SMTP server that is closed afterwards, including the retries of the RetryPolicy of the
+// Client, if set. Since each call of SendAsync uses its own connection, SendAsync can be called
+// concurrently.
+//
+// Once the delivery has finished, a SendResult for each of the messages is sent to the returned
+// channel, in the order of the messages, and the channel is closed. The channel is buffered for
+// all results, so the delivery never blocks on the caller, and the caller may drop the channel if
+// it is not interested in the results. The delivery is canceled when the provided context is
+// canceled, so a context that outlives the caller should be used for a fire-and-forget delivery,
+// e.g. via context.WithoutCancel.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - messages: A variadic list of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - A channel that receives the SendResult of each of the messages.
+func (c *Client) SendAsync(ctx context.Context, messages ...*Msg) <-chan SendResult {
+	pending := make([]*Msg, 0, len(messages))
+	for _, message := range messages {
+		if message != nil {
+			pending = append(pending, message)
+		}
+	}
+	results := make(chan SendResult, len(pending))
+	if len(pending) == 0 {
+		close(results)
+		return results
+	}
+
+	go func() {
+		defer close(results)
+		err := c.DialAndSendWithContext(ctx, pending...)
+		for _, message := range pending {
+			result := SendResult{Msg: message}
+			switch {
+			case message.isDelivered:
+			case message.sendError != nil:
+				result.Err = message.sendError
+			case ctx.Err() != nil:
+				result.Err = ctx.Err()
+			default:
+				// The message has not been attempted, e.g. because the dial failed
+				result.Err = err
+			}
+			results <- result
+		}
+	}()
+	return results
+}
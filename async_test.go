@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/wneessen/go-mail/smtptest"
+)
+
+func TestClient_SendAsync(t *testing.T) {
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+
+	t.Run("results are delivered per message", func(t *testing.T) {
+		t.Cleanup(server.Reset)
+		server.SetReplyFunc("RCPT", func(arg string) string {
+			if arg == "TO:<unknown@example.com>" {
+				return "550 5.1.1 User unknown"
+			}
+			return ""
+		})
+		t.Cleanup(func() { server.SetReplyFunc("RCPT", nil) })
+		rejected := testMessage(t)
+		if err := rejected.To("unknown@example.com"); err != nil {
+			t.Fatalf("failed to set recipient address: %s", err)
+		}
+		messages := []*Msg{testMessage(t), rejected, testMessage(t)}
+
+		var results []SendResult
+		for result := range client.SendAsync(t.Context(), messages[0], nil, messages[1], messages[2]) {
+			results = append(results, result)
+		}
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got: %d", len(results))
+		}
+		for i, result := range results {
+			if result.Msg != messages[i] {
+				t.Errorf("expected result %d to belong to message %d", i, i)
+			}
+		}
+		if results[0].Err != nil || results[2].Err != nil {
+			t.Errorf("expected messages to be delivered, got: %s, %s", results[0].Err, results[2].Err)
+		}
+		var sendErr *SendError
+		if !errors.As(results[1].Err, &sendErr) || sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected SendError with ErrSMTPRcptTo, got: %s", results[1].Err)
+		}
+		if len(server.Messages()) != 2 {
+			t.Errorf("expected 2 messages on the test server, got: %d", len(server.Messages()))
+		}
+	})
+	t.Run("concurrent calls", func(t *testing.T) {
+		t.Cleanup(server.Reset)
+		var wg sync.WaitGroup
+		wg.Add(5)
+		for range 5 {
+			go func() {
+				defer wg.Done()
+				for result := range client.SendAsync(t.Context(), testMessage(t)) {
+					if result.Err != nil {
+						t.Errorf("failed to send message: %s", result.Err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if len(server.Messages()) != 5 {
+			t.Errorf("expected 5 messages on the test server, got: %d", len(server.Messages()))
+		}
+	})
+	t.Run("canceled context fails all messages", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		for result := range client.SendAsync(ctx, testMessage(t), testMessage(t)) {
+			if result.Err == nil {
+				t.Error("expected canceled context to fail")
+			}
+			if result.Msg.IsDelivered() {
+				t.Error("expected message not to be delivered")
+			}
+		}
+	})
+	t.Run("no messages closes the channel", func(t *testing.T) {
+		for range client.SendAsync(t.Context(), nil) {
+			t.Error("expected no results")
+		}
+	})
+	t.Run("unreachable server fails all messages", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %s", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		_ = listener.Close()
+		unreachable, err := NewClient("127.0.0.1", WithPort(port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		count := 0
+		for result := range unreachable.SendAsync(t.Context(), testMessage(t), testMessage(t)) {
+			count++
+			if result.Err == nil {
+				t.Error("expected unreachable server to fail")
+			}
+		}
+		if count != 2 {
+			t.Errorf("expected 2 results, got: %d", count)
+		}
+	})
+}
@@ -5,10 +5,12 @@
 package mail
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"slices"
@@ -127,9 +129,21 @@ type (
 		// connTimeout specifies timeout for the connection to the SMTP server.
 		connTimeout time.Duration
 
+		// daneFallback is the TLSPolicy that is used with the TLSDANE policy, if no usable TLSA records exist.
+		daneFallback TLSPolicy
+
+		// daneResolver is the TLSAResolver that looks up the TLSA records with the TLSDANE policy.
+		//
+		// https://datatracker.ietf.org/doc/html/rfc7672
+		daneResolver TLSAResolver
+
 		// dialContextFunc is the DialContextFunc that is used by the Client to connect to the SMTP server.
 		dialContextFunc DialContextFunc
 
+		// proxyDialContextFunc is the DialContextFunc that connects to the SMTP server through a proxy,
+		// as configured with WithSOCKS5Proxy or WithHTTPConnectProxy.
+		proxyDialContextFunc DialContextFunc
+
 		// dkim is the DKIM configuration for the Client.
 		dkim *dkim.Signer
 
@@ -141,6 +155,9 @@ type (
 		// email.
 		dsnReturnType DSNMailReturnOption
 
+		// fallbackHosts holds the relay hosts that are tried if the connection to the primary host fails.
+		fallbackHosts []RelayHost
+
 		// fallbackPort is used as an alternative port number in case the primary port is unavailable or
 		// fails to bind.
 		//
@@ -155,16 +172,33 @@ type (
 		// host is the hostname of the SMTP server we are connecting to.
 		host string
 
+		// lmtp indicates that the Client speaks LMTP instead of SMTP.
+		lmtp bool
+
 		// logAuthData indicates whether authentication-related data should be logged.
 		logAuthData bool
 
 		// logger is a logger that satisfies the log.Logger interface.
 		logger log.Logger
 
+		// mtastsCache is the MTASTSPolicyCache used to resolve MTA-STS policies with the TLSMTASTS policy.
+		//
+		// If nil, a package-wide cache with the MTASTSHTTPResolver is used.
+		mtastsCache *MTASTSPolicyCache
+
+		// mtastsDomain is the MTA-STS policy domain used with the TLSMTASTS policy.
+		//
+		// https://datatracker.ietf.org/doc/html/rfc8461
+		mtastsDomain string
+
 		// mutex is used to synchronize access to shared resources, ensuring that only one goroutine can
 		// modify them at a time.
 		mutex sync.RWMutex
 
+		// noChunking indicates that the Client should not use the BDAT command for the message
+		// transmission, even if the server advertises the CHUNKING extension.
+		noChunking bool
+
 		// noNoop indicates that the Client should skip the "NOOP" command during the dial.
 		//
 		// This is useful for servers which delay potentially unwanted clients when they perform commands
@@ -180,6 +214,14 @@ type (
 		// ntDomain represents a NT domain name used for the SMTP authentication (NTLM only).
 		ntDomain string
 
+		// originalClient holds the information about the original SMTP client that is forwarded to
+		// the server via XCLIENT or XFORWARD.
+		originalClient *OriginalClient
+
+		// partialDelivery indicates that the Client should deliver the message to the accepted
+		// recipients, even if some of the recipients have been rejected by the server.
+		partialDelivery bool
+
 		// pass represents a password or a secret token used for the SMTP authentication.
 		pass string
 
@@ -193,9 +235,17 @@ type (
 		// requestDSN indicates wether we want to request DSN (Delivery Status Notifications).
 		requestDSN bool
 
+		// retryPolicy is the RetryPolicy for temporarily failed deliveries in DialAndSendWithContext.
+		//
+		// If nil, failed deliveries are not retried.
+		retryPolicy *RetryPolicy
+
 		// sendMutex is used to synchronize access to shared resources during the dial and send methods.
 		sendMutex sync.Mutex
 
+		// sessionHook is the SessionHook that observes the SMTP sessions of the Client.
+		sessionHook SessionHook
+
 		// skipUTF8 indicates that the Client should skip the "SMTPUTF8" in a "MAIL FROM" even if the server
 		// claims to support it
 		skipUTF8 bool
@@ -262,6 +312,15 @@ var (
 	// ErrServerNoUnencoded indicates that the server does not support 8BITMIME for unencoded 8-bit messages.
 	ErrServerNoUnencoded = errors.New("message is 8bit unencoded, but server does not support 8BITMIME")
 
+	// ErrServerMessageSize indicates that the message exceeds the maximum message size advertised by the
+	// server with the SIZE extension.
+	ErrServerMessageSize = errors.New("message exceeds the maximum message size of the server")
+
+	// ErrServerNoRequireTLS indicates that the message requires TLS, but the connection is not TLS protected
+	// or the server does not support REQUIRETLS.
+	ErrServerNoRequireTLS = errors.New("message requires TLS, but connection is not TLS protected or " +
+		"server does not support REQUIRETLS")
+
 	// ErrInvalidDSNMailReturnOption is returned when an invalid DSNMailReturnOption is provided as argument
 	// to the WithDSN Option.
 	ErrInvalidDSNMailReturnOption = errors.New("DSN mail return option can only be HDRS or FULL")
@@ -404,9 +463,10 @@ func WithSSL() Option {
 // automatically set.
 //
 // When this option is used with NewClient, the default port 25 is overridden with port 465 for SSL/TLS connections.
-// If fallback is set to true and the SSL/TLS connection fails, the Client attempts to connect on port 25 using an
-// unencrypted connection. If WithPort has already been used to set a different port, that port takes precedence,
-// and the automatic fallback mechanism is skipped.
+// If fallback is set to true and the SSL/TLS connection fails, the Client attempts to connect on port 25 without
+// implicit SSL/TLS. The fallback connection is then secured with STARTTLS according to the TLSPolicy of the Client.
+// If WithPort has already been used to set a different port, that port takes precedence, and the automatic
+// fallback mechanism is skipped.
 //
 // Parameters:
 //   - fallback: A boolean indicating whether to fall back to port 25 without SSL/TLS if the connection fails.
@@ -752,6 +812,25 @@ func WithoutNoop() Option {
 	}
 }
 
+// WithoutChunking indicates that the Client should not use the BDAT command of the CHUNKING
+// extension to transmit messages, even if the server advertises support for it.
+//
+// This option is useful for servers that advertise support for CHUNKING but do not properly
+// implement it. Without CHUNKING, messages are transmitted with the DATA command and unencoded
+// files cannot be sent using BINARYMIME.
+//
+// Returns:
+//   - An Option function that configures the Client to skip the BDAT command.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3030
+func WithoutChunking() Option {
+	return func(c *Client) error {
+		c.noChunking = true
+		return nil
+	}
+}
+
 // WithoutRset indicates that the Client should not send a "RSET" command after successful mail
 // delivery.
 //
@@ -944,8 +1023,8 @@ func (c *Client) SetSSL(ssl bool) {
 // The correct port is automatically set.
 //
 // If ssl is set to true, the default port 25 will be overridden with port 465. If fallback is set to true
-// and the SSL/TLS connection fails, the Client will attempt to connect on port 25 using an unencrypted
-// connection.
+// and the SSL/TLS connection fails, the Client will attempt to connect on port 25 without implicit SSL/TLS.
+// The fallback connection is then secured with STARTTLS according to the TLSPolicy of the Client.
 //
 // Note: If a different port has already been set using WithPort, that port takes precedence and is used
 // to establish the SSL/TLS connection, skipping the automatic fallback mechanism.
@@ -1182,7 +1261,8 @@ func (c *Client) DialWithContext(ctxDial context.Context) error {
 // If SSL is enabled, it uses a TLS connection. After successfully connecting, it initializes
 // an smtp.Client, sends the HELO/EHLO command, and optionally performs STARTTLS and SMTP AUTH
 // based on the Client's configuration. Debug and authentication logging are enabled if
-// configured.
+// configured. If fallback hosts are configured with WithFallbackHosts and the primary host is
+// unavailable, the fallback hosts are tried in order.
 //
 // Parameters:
 //   - ctxDial: The context used to control the connection timeout and cancellation.
@@ -1190,53 +1270,126 @@ func (c *Client) DialWithContext(ctxDial context.Context) error {
 // Returns:
 //   - A pointer to the initialized smtp.Client.
 //   - An error if the connection fails, the smtp.Client cannot be created, or any subsequent commands fail.
+//     If all relay hosts are unavailable, the errors of all relay hosts are joined.
 func (c *Client) DialToSMTPClientWithContext(ctxDial context.Context) (*smtp.Client, error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
+	relays := c.relayHosts()
+	errs := make([]error, 0, len(relays))
+	for i, relay := range relays {
+		client, unavailable, err := c.dialRelay(ctxDial, relay, i == 0)
+		if err == nil {
+			return client, nil
+		}
+		if !unavailable || len(relays) == 1 {
+			c.hook().OnError(ctxDial, SessionEvent{Host: relay.Host, Err: err})
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("relay host %s: %w", relay.Host, err))
+		if ctxDial.Err() != nil {
+			break
+		}
+	}
+	err := errors.Join(errs...)
+	c.hook().OnError(ctxDial, SessionEvent{Host: c.host, Err: err})
+	return nil, err
+}
+
+// dialRelay establishes a connection to the provided relay host and returns a connected smtp.Client.
+//
+// The connection is established with the TLSPolicy and the tls.Config of the Client. For the
+// TLSMTASTS and TLSDANE policies, the effective TLSPolicy is determined for the relay host. If the
+// connection to the primary host fails and a fallback port is configured, the fallback port is
+// tried. Afterward, the Client greets the server, negotiates TLS and authenticates.
+//
+// Parameters:
+//   - ctxDial: The context.Context that controls the connection timing.
+//   - relay: The RelayHost to connect to.
+//   - primary: Indicates whether the relay is the primary host of the Client.
+//
+// Returns:
+//   - A pointer to the smtp.Client connected to the relay host.
+//   - A boolean indicating whether the relay host is unavailable, i. e. the connection failed or
+//     the server rejected the connection in its greeting, so the next relay host can be tried.
+//   - An error if the connection or any subsequent step fails.
+func (c *Client) dialRelay(ctxDial context.Context, relay RelayHost, primary bool) (*smtp.Client, bool, error) {
 	ctx, cancel := context.WithDeadline(ctxDial, time.Now().Add(c.connTimeout))
 	defer cancel()
 
-	isEncrypted := false
-	dialContextFunc := c.dialContextFunc
+	var err error
+	tlsPolicy, tlsConfig := c.tlspolicy, c.tlsconfig
+	switch tlsPolicy {
+	case TLSMTASTS:
+		tlsPolicy, err = c.mtastsPolicy(ctx, relay.Host)
+	case TLSDANE:
+		tlsPolicy, tlsConfig, err = c.danePolicy(ctx, relay.Host, relay.Port)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	isEncrypted, implicitTLS := false, c.useSSL
+	dialContextFunc, fallbackDialContextFunc := c.dialContextFunc, c.dialContextFunc
 	if c.dialContextFunc == nil {
 		netDialer := net.Dialer{}
 		dialContextFunc = netDialer.DialContext
+		if c.proxyDialContextFunc != nil {
+			dialContextFunc = c.proxyDialContextFunc
+		}
+		// The fallback port is connected to without implicit SSL/TLS, so that the connection is
+		// secured with STARTTLS according to the TLSPolicy instead
+		fallbackDialContextFunc = dialContextFunc
 		if c.useSSL {
-			tlsDialer := tls.Dialer{NetDialer: &netDialer, Config: c.tlsconfig}
 			isEncrypted = true
-			dialContextFunc = tlsDialer.DialContext
+			dialContextFunc = tlsDialContextFunc(dialContextFunc, c.relayTLSConfig(tlsConfig, relay.Host))
 		}
 	}
 
-	network := "tcp"
-	if c.useUnixSocket {
-		network = "unix"
+	network, address := "tcp", fmt.Sprintf("%s:%d", relay.Host, relay.Port)
+	if primary {
+		address = c.ServerAddr()
+		if c.useUnixSocket {
+			network = "unix"
+		}
 	}
 
-	connection, err := dialContextFunc(ctx, network, c.ServerAddr())
-	if err != nil && !c.useUnixSocket && c.fallbackPort != 0 {
+	var timings DeliveryTimings
+	phaseStart := time.Now()
+	connection, err := dialContextFunc(ctx, network, address)
+	if err != nil && primary && !c.useUnixSocket && c.fallbackPort != 0 {
 		// TODO: should we somehow log or append the previous error?
-		connection, err = dialContextFunc(ctx, "tcp", c.serverFallbackAddr())
+		connection, err = fallbackDialContextFunc(ctx, "tcp", c.serverFallbackAddr())
+		if c.dialContextFunc == nil {
+			isEncrypted, implicitTLS = false, false
+		}
+	}
+	timings.Dial = time.Since(phaseStart)
+	dialEvent := SessionEvent{Host: relay.Host, Duration: timings.Dial, Err: err}
+	if tlsConn, ok := connection.(*tls.Conn); ok && err == nil {
+		state := tlsConn.ConnectionState()
+		dialEvent.TLSState = &state
 	}
+	c.hook().OnDial(ctx, dialEvent)
 	if err != nil {
-		return nil, err
+		return nil, true, err
 	}
 
 	err = connection.SetDeadline(time.Now().Add(c.connTimeout))
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	client, err := smtp.NewClient(connection, c.host)
+	client, err := smtp.NewClient(connection, relay.Host)
 	if err != nil {
-		return nil, err
+		return nil, true, err
 	}
 	client.ErrorHandlerRegistry = c.ErrorHandlerRegistry
+	client.SetLMTP(c.lmtp)
 
 	err = client.UpdateDeadline(c.connTimeout)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if c.logger != nil {
@@ -1250,18 +1403,42 @@ func (c *Client) DialToSMTPClientWithContext(ctxDial context.Context) (*smtp.Cli
 	}
 	client.SkipSMTPUTF8(c.skipUTF8)
 	if err = client.Hello(c.helo); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	if err = c.tls(client, &isEncrypted); err != nil {
-		return nil, err
+	wasEncrypted := isEncrypted
+	phaseStart = time.Now()
+	err = c.tls(client, tlsPolicy, tlsConfig, implicitTLS, &isEncrypted)
+	if err != nil || isEncrypted != wasEncrypted {
+		tlsEvent := SessionEvent{Host: relay.Host, Duration: time.Since(phaseStart), Err: err}
+		if state, ok := client.TLSConnectionState(); ok && err == nil {
+			tlsEvent.TLSState = &state
+			timings.TLS = tlsEvent.Duration
+		}
+		c.hook().OnTLSHandshake(ctx, tlsEvent)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err = c.xclient(client); err != nil {
+		return nil, false, err
 	}
 
-	if err = c.auth(client, isEncrypted); err != nil {
-		return nil, err
+	phaseStart = time.Now()
+	err = c.auth(client, isEncrypted)
+	if mechanism := client.AuthMechanism(); err != nil || mechanism != "" {
+		authEvent := SessionEvent{Host: relay.Host, AuthMechanism: mechanism, Duration: time.Since(phaseStart), Err: err}
+		if err == nil {
+			timings.Auth = authEvent.Duration
+		}
+		c.hook().OnAuth(ctx, authEvent)
+	}
+	if err != nil {
+		return nil, false, err
 	}
 
-	return client, nil
+	storeSetupTimings(client, timings)
+	return client, false, nil
 }
 
 // Close terminates the connection to the SMTP server, returning an error if the disconnection
@@ -1291,10 +1468,26 @@ func (c *Client) Close() error {
 // Returns:
 //   - An error if the disconnection fails; otherwise, returns nil.
 func (c *Client) CloseWithSMTPClient(client *smtp.Client) error {
+	return c.closeWithSMTPClient(context.Background(), client)
+}
+
+// closeWithSMTPClient terminates the connection of the provided smtp.Client to the SMTP server
+// like CloseWithSMTPClient, passing the provided context.Context to the SessionHook.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that handles the connection to the server.
+//
+// Returns:
+//   - An error if the disconnection fails; otherwise, returns nil.
+func (c *Client) closeWithSMTPClient(ctx context.Context, client *smtp.Client) error {
 	if client == nil || !client.HasConnection() {
 		return nil
 	}
-	if err := client.Quit(); err != nil {
+	quitStart := time.Now()
+	err := client.Quit()
+	c.hook().OnQuit(ctx, SessionEvent{Host: client.ServerName(), Duration: time.Since(quitStart), Err: err})
+	if err != nil {
 		return fmt.Errorf("failed to close SMTP client: %w", err)
 	}
 
@@ -1365,6 +1558,9 @@ func (c *Client) DialAndSend(messages ...*Msg) error {
 // Upon successful connection, it sends the specified messages and ensures that the connection
 // is closed after the operation, regardless of success or failure in sending the messages.
 //
+// If a RetryPolicy has been set via WithRetryPolicy, messages that failed with a temporary error
+// or due to a dropped connection are retried on a new connection, according to the RetryPolicy.
+//
 // Parameters:
 //   - ctx: The context.Context to control the connection timeout and cancellation.
 //   - messages: A variadic list of pointers to Msg objects to be sent.
@@ -1372,22 +1568,11 @@ func (c *Client) DialAndSend(messages ...*Msg) error {
 // Returns:
 //   - An error if the connection fails, if sending the messages fails, or if closing the
 //     connection fails; otherwise, returns nil.
-func (c *Client) DialAndSendWithContext(ctx context.Context, messages ...*Msg) (err error) {
-	client, err := c.DialToSMTPClientWithContext(ctx)
-	if err != nil {
-		return fmt.Errorf("dial failed: %w", err)
-	}
-	defer func() {
-		if closeErr := c.CloseWithSMTPClient(client); closeErr != nil {
-			err = errors.Join(err, fmt.Errorf("failed to close connection: %w", closeErr))
-		}
-	}()
-
-	if err = c.SendWithSMTPClient(client, messages...); err != nil {
-		return fmt.Errorf("send failed: %w", err)
+func (c *Client) DialAndSendWithContext(ctx context.Context, messages ...*Msg) error {
+	if c.retryPolicy != nil {
+		return c.dialAndSendWithRetry(ctx, messages)
 	}
-
-	return nil
+	return c.dialAndSend(ctx, messages)
 }
 
 // Send attempts to send one or more Msg using the SMTP client that is assigned to the Client.
@@ -1401,17 +1586,24 @@ func (c *Client) DialAndSendWithContext(ctx context.Context, messages ...*Msg) (
 // associates it with the corresponding Msg. If multiple errors are encountered, it aggregates
 // them into a single SendError to be returned.
 //
+// Send implements the Sender interface. Unlike SMTPSender, it does not establish a connection
+// itself, but requires the Client to be connected via DialWithContext first.
+//
 // Parameters:
-//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server
+//   - ctx: The context.Context that is passed to the SessionHook. If it is already canceled, no
+//     Msg is sent.
 //   - messages: A variadic list of pointers to Msg objects to be sent.
 //
 // Returns:
 //   - An error that represents the sending result, which may include multiple SendErrors if
 //     any occurred; otherwise, returns nil.
-func (c *Client) Send(messages ...*Msg) (returnErr error) {
+func (c *Client) Send(ctx context.Context, messages ...*Msg) (returnErr error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	c.sendMutex.Lock()
 	defer c.sendMutex.Unlock()
-	return c.SendWithSMTPClient(c.smtpClient, messages...)
+	return c.sendWithSMTPClient(ctx, c.smtpClient, messages)
 }
 
 // SendWithSMTPClient attempts to send one or more Msg using a provided smtp.Client with an
@@ -1433,6 +1625,21 @@ func (c *Client) Send(messages ...*Msg) (returnErr error) {
 //   - An error that represents the sending result, which may include multiple SendErrors if
 //     any occurred; otherwise, returns nil.
 func (c *Client) SendWithSMTPClient(client *smtp.Client, messages ...*Msg) (returnErr error) {
+	return c.sendWithSMTPClient(context.Background(), client, messages)
+}
+
+// sendWithSMTPClient sends the provided Msg using the provided smtp.Client like
+// SendWithSMTPClient, passing the provided context.Context to the SessionHook.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server
+//   - messages: A slice of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error that represents the sending result, which may include multiple SendErrors if
+//     any occurred; otherwise, returns nil.
+func (c *Client) sendWithSMTPClient(ctx context.Context, client *smtp.Client, messages []*Msg) error {
 	if client == nil {
 		return &SendError{
 			Reason: ErrConnCheck, errlist: []error{ErrClientIsNil}, isTemp: isTempError(ErrClientIsNil),
@@ -1448,6 +1655,25 @@ func (c *Client) SendWithSMTPClient(client *smtp.Client, messages ...*Msg) (retu
 		}
 	}
 
+	return c.sendMessages(ctx, client, messages)
+}
+
+// sendMessages sends each of the provided Msg using the provided smtp.Client, without checking
+// the connection to the SMTP server first. The caller is responsible for making sure that the
+// smtp.Client holds a valid connection.
+//
+// For each Msg that fails to be sent, the corresponding SendError is associated with the Msg. If
+// the provided context.Context is canceled, the remaining messages are not sent.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server
+//   - messages: A slice of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error that represents the sending result, which may include multiple SendErrors if
+//     any occurred; otherwise, returns nil.
+func (c *Client) sendMessages(ctx context.Context, client *smtp.Client, messages []*Msg) (returnErr error) {
 	var errs []error
 	defer func() {
 		returnErr = errors.Join(errs...)
@@ -1457,7 +1683,11 @@ func (c *Client) SendWithSMTPClient(client *smtp.Client, messages ...*Msg) (retu
 		if message == nil {
 			continue
 		}
-		if sendErr := c.sendSingleMsg(client, message); sendErr != nil {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if sendErr := c.sendSingleMsg(ctx, client, message); sendErr != nil {
 			messages[id].sendError = sendErr
 			errs = append(errs, sendErr)
 		}
@@ -1517,22 +1747,22 @@ func (c *Client) auth(client *smtp.Client, isEnc bool) error {
 			if !strings.Contains(smtpAuthType, string(SMTPAuthPlain)) {
 				return ErrPlainAuthNotSupported
 			}
-			smtpAuth = smtp.PlainAuth("", c.user, c.pass, c.host, false)
+			smtpAuth = smtp.PlainAuth("", c.user, c.pass, client.ServerName(), false)
 		case SMTPAuthPlainNoEnc:
 			if !strings.Contains(smtpAuthType, string(SMTPAuthPlain)) {
 				return ErrPlainAuthNotSupported
 			}
-			smtpAuth = smtp.PlainAuth("", c.user, c.pass, c.host, true)
+			smtpAuth = smtp.PlainAuth("", c.user, c.pass, client.ServerName(), true)
 		case SMTPAuthLogin:
 			if !strings.Contains(smtpAuthType, string(SMTPAuthLogin)) {
 				return ErrLoginAuthNotSupported
 			}
-			smtpAuth = smtp.LoginAuth(c.user, c.pass, c.host, false)
+			smtpAuth = smtp.LoginAuth(c.user, c.pass, client.ServerName(), false)
 		case SMTPAuthLoginNoEnc:
 			if !strings.Contains(smtpAuthType, string(SMTPAuthLogin)) {
 				return ErrLoginAuthNotSupported
 			}
-			smtpAuth = smtp.LoginAuth(c.user, c.pass, c.host, true)
+			smtpAuth = smtp.LoginAuth(c.user, c.pass, client.ServerName(), true)
 		case SMTPAuthCramMD5:
 			if !strings.Contains(smtpAuthType, string(SMTPAuthCramMD5)) {
 				return ErrCramMD5AuthNotSupported
@@ -1665,20 +1895,62 @@ func (c *Client) authTypeSelectPreferred(supported string) SMTPAuthType {
 // the SMTP client if an error occurs).
 //
 // Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
 //   - message: A pointer to the Msg object representing the email message to be sent.
 //
 // Returns:
 //   - An error if any part of the sending process fails; otherwise, returns nil.
-func (c *Client) sendSingleMsg(client *smtp.Client, message *Msg) error {
+func (c *Client) sendSingleMsg(ctx context.Context, client *smtp.Client, message *Msg) error {
+	return c.sendSingleMsgTo(ctx, client, message, nil)
+}
+
+// sendSingleMsgTo sends out a single message to the provided envelope recipients and returns an
+// error if the transmission or delivery fails.
+//
+// This method behaves like sendSingleMsg, but allows to restrict the envelope recipients of the
+// mail transaction to a subset of the recipients of the Msg, i. e. the recipients of a single
+// domain for the delivery to their MX hosts.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg object representing the email message to be sent.
+//   - envelopeRcpts: The envelope recipients for the mail transaction. If nil, the recipients
+//     returned by Msg.GetRecipients are used.
+//
+// Returns:
+//   - An error if any part of the sending process fails; otherwise, returns nil.
+func (c *Client) sendSingleMsgTo(ctx context.Context, client *smtp.Client, message *Msg, envelopeRcpts []string,
+) (returnErr error) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
+	defer func() {
+		if returnErr != nil {
+			c.hook().OnError(ctx, SessionEvent{Host: client.ServerName(), Message: message, Err: returnErr})
+		}
+	}()
 	escSupport, _ := client.Extension("ENHANCEDSTATUSCODES")
+	message.rcptResults = nil
+	message.deliveryResult = nil
 
-	if message.encoding == NoEncoding {
+	useChunking := c.useChunking(client)
+	hasBinaryMIME, _ := client.Extension("BINARYMIME")
+	useBinaryMIME := useChunking && hasBinaryMIME && message.hasUnencodedFiles()
+	if message.encoding == NoEncoding && !useBinaryMIME {
 		if ok, _ := client.Extension("8BITMIME"); !ok {
 			return &SendError{Reason: ErrNoUnencoded, isTemp: false, affectedMsg: message}
 		}
 	}
+	if message.requireTLS {
+		_, isTLS := client.TLSConnectionState()
+		hasRequireTLS, _ := client.Extension("REQUIRETLS")
+		if !isTLS || !hasRequireTLS {
+			return &SendError{Reason: ErrNoRequireTLS, isTemp: false, affectedMsg: message}
+		}
+	}
+	client.SetBinaryMIME(useBinaryMIME)
+	client.SetRequireTLS(message.requireTLS)
 	from, err := message.GetSender(false)
 	if err != nil {
 		return &SendError{
@@ -1687,7 +1959,10 @@ func (c *Client) sendSingleMsg(client *smtp.Client, message *Msg) error {
 			enhancedStatusCode: enhancedStatusCode(err, escSupport),
 		}
 	}
-	rcpts, err := message.GetRecipients()
+	rcpts := envelopeRcpts
+	if rcpts == nil {
+		rcpts, err = message.GetRecipients()
+	}
 	if err != nil {
 		return &SendError{
 			Reason: ErrGetRcpts, errlist: []error{err}, isTemp: isTempError(err),
@@ -1701,72 +1976,77 @@ func (c *Client) sendSingleMsg(client *smtp.Client, message *Msg) error {
 			client.SetDSNMailReturnOption(string(c.dsnReturnType))
 		}
 	}
-	if err = client.Mail(from); err != nil {
-		retError := &SendError{
-			Reason: ErrSMTPMailFrom, errlist: []error{err}, isTemp: isTempError(err),
-			affectedMsg: message, errcode: errorCode(err),
-			enhancedStatusCode: enhancedStatusCode(err, escSupport),
-		}
-		if resetSendErr := client.Reset(); resetSendErr != nil {
-			retError.errlist = append(retError.errlist, resetSendErr)
-		}
-		return retError
-	}
-	hasError := false
-	rcptSendErr := &SendError{affectedMsg: message}
-	rcptSendErr.errlist = make([]error, 0)
-	rcptSendErr.rcpt = make([]string, 0)
-	rcptNotifyOpt := strings.Join(c.dsnRcptNotifyType, ",")
-	client.SetDSNRcptNotifyOption(rcptNotifyOpt)
-	for _, rcpt := range rcpts {
-		if err = client.Rcpt(rcpt); err != nil {
-			rcptSendErr.Reason = ErrSMTPRcptTo
-			rcptSendErr.errlist = append(rcptSendErr.errlist, err)
-			rcptSendErr.rcpt = append(rcptSendErr.rcpt, rcpt)
-			rcptSendErr.isTemp = isTempError(err)
-			rcptSendErr.errcode = errorCode(err)
-			rcptSendErr.enhancedStatusCode = enhancedStatusCode(err, escSupport)
-			hasError = true
-		}
-	}
-	if hasError {
-		if resetSendErr := client.Reset(); resetSendErr != nil {
-			rcptSendErr.errlist = append(rcptSendErr.errlist, resetSendErr)
-		}
-		return rcptSendErr
-	}
-	writer, err := client.Data()
-	if err != nil {
-		return &SendError{
-			Reason: ErrSMTPData, errlist: []error{err}, isTemp: isTempError(err),
-			affectedMsg: message, errcode: errorCode(err),
-			enhancedStatusCode: enhancedStatusCode(err, escSupport),
-		}
-	}
+	client.SetDSNRcptNotifyOption(strings.Join(c.dsnRcptNotifyType, ","))
+	client.SetDSNEnvelopeID(message.envelopeID)
+	client.SetDSNOriginalRcpts(message.originalRcpts)
 
 	// the Client is instructed to always DKIM sign the Msg, this will override the
 	// Msg's own DKIM configuration if set
 	if c.dkim != nil {
 		message.dkim = c.dkim
 	}
-	_, err = message.WriteTo(writer)
+	rendered, sendErr := c.checkMessageSize(client, message, useBinaryMIME, escSupport)
+	if sendErr != nil {
+		return sendErr
+	}
+
+	if sendErr := c.xforward(client, message, escSupport); sendErr != nil {
+		return sendErr
+	}
+
+	var writer io.WriteCloser
+	if ok, _ := client.Extension("PIPELINING"); ok {
+		writer, sendErr = c.sendEnvelopePipelined(ctx, client, message, from, rcpts, escSupport)
+	} else {
+		writer, sendErr = c.sendEnvelope(ctx, client, message, from, rcpts, escSupport)
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+
+	dataStart := time.Now()
+	if rendered != nil {
+		_, err = writer.Write(rendered)
+	} else {
+		_, err = messageWriter(message).WriteTo(writer)
+	}
 	if err != nil {
+		c.hook().OnData(ctx, SessionEvent{
+			Host: client.ServerName(), Message: message, Duration: time.Since(dataStart), Err: err,
+		})
 		return &SendError{
 			Reason: ErrWriteContent, errlist: []error{err}, isTemp: isTempError(err),
 			affectedMsg: message, errcode: errorCode(err),
 			enhancedStatusCode: enhancedStatusCode(err, escSupport),
 		}
 	}
-	if err = writer.Close(); err != nil {
-		return &SendError{
+	err = writer.Close()
+	dataDuration := time.Since(dataStart)
+	if dc, ok := writer.(interface{ RcptResponses() []smtp.RcptResponse }); ok && c.lmtp {
+		sendErr = lmtpSendError(message, dc.RcptResponses(), err, escSupport)
+	}
+	if sendErr == nil && err != nil {
+		sendErr = &SendError{
 			Reason: ErrSMTPDataClose, errlist: []error{err}, isTemp: isTempError(err),
 			affectedMsg: message, errcode: errorCode(err),
 			enhancedStatusCode: enhancedStatusCode(err, escSupport),
 		}
 	}
-	if dc, ok := writer.(*smtp.DataCloser); ok {
+	c.hook().OnData(ctx, SessionEvent{Host: client.ServerName(), Message: message, Duration: dataDuration, Err: err})
+	if sendErr != nil && sendErr.Reason != ErrPartialDelivery {
+		return sendErr
+	}
+	if dc, ok := writer.(interface{ ServerResponse() string }); ok {
 		message.serverResponse = dc.ServerResponse()
 	}
+	message.serverHost = client.ServerName()
+	message.deliveryResult = c.newDeliveryResult(client, message, dataDuration)
+	if sendErr != nil {
+		return sendErr
+	}
+	if sendErr = partialSendError(message, escSupport); sendErr != nil {
+		return sendErr
+	}
 	message.isDelivered = true
 
 	if err = c.ResetWithSMTPClient(client); err != nil {
@@ -1779,6 +2059,278 @@ func (c *Client) sendSingleMsg(client *smtp.Client, message *Msg) error {
 	return nil
 }
 
+// checkMessageSize compares the size of the provided Msg with the maximum message size the server
+// advertises with the SIZE extension and sets the message size for the SIZE parameter of the
+// "MAIL FROM" command.
+//
+// Determining the size requires rendering the Msg, so this only happens if the server announces
+// a maximum message size. The rendered Msg is returned, so that it can be transmitted without
+// rendering it a second time. Unless the Msg is transmitted with BODY=BINARYMIME, its size is
+// counted with CRLF line endings, as they are transmitted to the server. If the server does not
+// announce a maximum message size, the Msg is not rendered and the SIZE parameter is omitted.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg that is being sent.
+//   - binaryMIME: Indicates whether the Msg is transmitted with BODY=BINARYMIME.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The rendered Msg, or nil if the Msg has not been rendered.
+//   - A SendError if the Msg could not be rendered or exceeds the maximum message size of the
+//     server; otherwise, returns nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc1870
+func (c *Client) checkMessageSize(client *smtp.Client, message *Msg, binaryMIME, escSupport bool) ([]byte, *SendError) {
+	client.SetMessageSize(0)
+	maxSize, ok := client.MaxMessageSize()
+	if !ok {
+		return nil, nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	if _, err := messageWriter(message).WriteTo(buffer); err != nil {
+		return nil, &SendError{
+			Reason: ErrWriteContent, errlist: []error{err}, isTemp: isTempError(err),
+			affectedMsg: message, errcode: errorCode(err),
+			enhancedStatusCode: enhancedStatusCode(err, escSupport),
+		}
+	}
+	size := int64(buffer.Len())
+	if !binaryMIME {
+		size += int64(countBareLF(buffer.Bytes()))
+	}
+	if size > maxSize {
+		return nil, &SendError{
+			Reason: ErrMessageTooLarge, isTemp: false, affectedMsg: message,
+			errlist: []error{fmt.Errorf("message size of %d bytes exceeds the limit of %d bytes", size, maxSize)},
+		}
+	}
+	client.SetMessageSize(size)
+	return buffer.Bytes(), nil
+}
+
+// countBareLF returns the number of LF characters in the provided data, that are not preceded by
+// a CR character and therefore are converted into CRLF line endings during transmission.
+//
+// Parameters:
+//   - data: The data to count the bare LF characters in.
+//
+// Returns:
+//   - The number of bare LF characters.
+func countBareLF(data []byte) int {
+	count := 0
+	for i, char := range data {
+		if char == '\n' && (i == 0 || data[i-1] != '\r') {
+			count++
+		}
+	}
+	return count
+}
+
+// sendEnvelope sends the MAIL, RCPT and DATA commands of the mail transaction for the provided
+// Msg to the server, waiting for the response of each command before sending the next one. If
+// the server supports CHUNKING, a writer for BDAT commands is returned instead of sending DATA.
+//
+// If the sender or any of the recipients is rejected by the server, the mail transaction is
+// reset and a SendError is returned. With partial delivery enabled, the mail transaction proceeds
+// as long as at least one recipient has been accepted.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg that is being sent.
+//   - from: The envelope sender address.
+//   - rcpts: The envelope recipient addresses.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The writer for the message data if the server accepted the DATA command.
+//   - A SendError if any of the commands failed; otherwise, returns nil.
+func (c *Client) sendEnvelope(ctx context.Context, client *smtp.Client, message *Msg, from string, rcpts []string,
+	escSupport bool,
+) (io.WriteCloser, *SendError) {
+	hook, host := c.hook(), client.ServerName()
+	commandStart := time.Now()
+	err := client.Mail(from)
+	hook.OnMailFrom(ctx, SessionEvent{
+		Host: host, Message: message, Address: from, Duration: time.Since(commandStart), Err: err,
+	})
+	if err != nil {
+		retError := &SendError{
+			Reason: ErrSMTPMailFrom, errlist: []error{err}, isTemp: isTempError(err),
+			affectedMsg: message, errcode: errorCode(err),
+			enhancedStatusCode: enhancedStatusCode(err, escSupport),
+		}
+		if resetSendErr := client.Reset(); resetSendErr != nil {
+			retError.errlist = append(retError.errlist, resetSendErr)
+		}
+		return nil, retError
+	}
+	rcptResponses := make([]smtp.RcptResponse, len(rcpts))
+	rcptErrs := make([]error, len(rcpts))
+	for i, rcpt := range rcpts {
+		commandStart = time.Now()
+		rcptResponses[i], rcptErrs[i] = client.RcptWithResponse(rcpt)
+		hook.OnRcpt(ctx, SessionEvent{
+			Host: host, Message: message, Address: rcpt, Duration: time.Since(commandStart), Err: rcptErrs[i],
+		})
+	}
+	if !c.acceptPartialRcpts(message, rcptResponses, escSupport) {
+		if rcptSendErr := rcptSendError(message, rcpts, rcptErrs, escSupport); rcptSendErr != nil {
+			if resetSendErr := client.Reset(); resetSendErr != nil {
+				rcptSendErr.errlist = append(rcptSendErr.errlist, resetSendErr)
+			}
+			return nil, rcptSendErr
+		}
+	}
+	return c.openDataWriter(client, message, escSupport)
+}
+
+// useChunking returns true if the Client should transmit the message data with the BDAT command,
+// which is the case if the server supports the CHUNKING extension and the Client has not been
+// configured to skip it. CHUNKING is never used in LMTP mode.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//
+// Returns:
+//   - A boolean value indicating whether to use the BDAT command.
+func (c *Client) useChunking(client *smtp.Client) bool {
+	if c.noChunking || c.lmtp {
+		return false
+	}
+	ok, _ := client.Extension("CHUNKING")
+	return ok
+}
+
+// sendEnvelopePipelined sends the MAIL and RCPT commands of the mail transaction for the provided
+// Msg to the server in a single batch, using the PIPELINING extension, and then requests the
+// writer for the message data like sendEnvelope.
+//
+// The DATA command is deliberately not part of the batch: if the server accepted it although the
+// sender or a recipient has been rejected, the mail transaction could not be aborted without
+// delivering an empty message or dropping the connection, which would fail all further messages
+// on the same connection. If the sender or any of the recipients is rejected by the server, the
+// mail transaction is reset and a SendError is returned. With partial delivery enabled, the mail
+// transaction proceeds as long as at least one recipient has been accepted.
+//
+// Parameters:
+//   - ctx: The context.Context that is passed to the SessionHook.
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg that is being sent.
+//   - from: The envelope sender address.
+//   - rcpts: The envelope recipient addresses.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The writer for the message data if the server accepted the DATA command.
+//   - A SendError if any of the commands failed; otherwise, returns nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc2920
+func (c *Client) sendEnvelopePipelined(ctx context.Context, client *smtp.Client, message *Msg, from string,
+	rcpts []string, escSupport bool,
+) (io.WriteCloser, *SendError) {
+	hook, host := c.hook(), client.ServerName()
+	batchStart := time.Now()
+	result, err := client.MailRcptPipelined(from, rcpts)
+	mailEvent := SessionEvent{Host: host, Message: message, Address: from, Duration: time.Since(batchStart), Err: err}
+	if result != nil {
+		mailEvent.Err = result.MailErr
+	}
+	hook.OnMailFrom(ctx, mailEvent)
+	if result != nil {
+		for i, rcpt := range rcpts {
+			hook.OnRcpt(ctx, SessionEvent{Host: host, Message: message, Address: rcpt, Err: result.RcptErrs[i]})
+		}
+	}
+	if err != nil {
+		return nil, &SendError{
+			Reason: ErrSMTPMailFrom, errlist: []error{err}, isTemp: isTempError(err),
+			affectedMsg: message, errcode: errorCode(err),
+			enhancedStatusCode: enhancedStatusCode(err, escSupport),
+		}
+	}
+
+	var retError *SendError
+	switch {
+	case result.MailErr != nil:
+		retError = &SendError{
+			Reason: ErrSMTPMailFrom, errlist: []error{result.MailErr}, isTemp: isTempError(result.MailErr),
+			affectedMsg: message, errcode: errorCode(result.MailErr),
+			enhancedStatusCode: enhancedStatusCode(result.MailErr, escSupport),
+		}
+	case !c.acceptPartialRcpts(message, result.RcptResponses, escSupport):
+		retError = rcptSendError(message, rcpts, result.RcptErrs, escSupport)
+	}
+	if retError != nil {
+		if resetSendErr := client.Reset(); resetSendErr != nil {
+			retError.errlist = append(retError.errlist, resetSendErr)
+		}
+		return nil, retError
+	}
+	return c.openDataWriter(client, message, escSupport)
+}
+
+// openDataWriter requests the writer for the message data of the current mail transaction, using
+// the BDAT command if CHUNKING is used, or the DATA command otherwise.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg that is being sent.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The writer for the message data.
+//   - A SendError if the server did not accept the DATA command; otherwise, returns nil.
+func (c *Client) openDataWriter(client *smtp.Client, message *Msg, escSupport bool) (io.WriteCloser, *SendError) {
+	var writer io.WriteCloser
+	var err error
+	if c.useChunking(client) {
+		writer, err = client.Bdat()
+	} else {
+		writer, err = client.Data()
+	}
+	if err != nil {
+		return nil, &SendError{
+			Reason: ErrSMTPData, errlist: []error{err}, isTemp: isTempError(err),
+			affectedMsg: message, errcode: errorCode(err),
+			enhancedStatusCode: enhancedStatusCode(err, escSupport),
+		}
+	}
+	return writer, nil
+}
+
+// rcptSendError returns a SendError for the recipients that were rejected by the server.
+//
+// Parameters:
+//   - message: A pointer to the Msg that is being sent.
+//   - rcpts: The envelope recipient addresses.
+//   - rcptErrs: The errors returned for each of the RCPT commands, in the same order as rcpts.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - A SendError with the reason ErrSMTPRcptTo, or nil if all recipients were accepted.
+func rcptSendError(message *Msg, rcpts []string, rcptErrs []error, escSupport bool) *SendError {
+	var rcptSendErr *SendError
+	for i, err := range rcptErrs {
+		if err == nil {
+			continue
+		}
+		if rcptSendErr == nil {
+			rcptSendErr = &SendError{affectedMsg: message, errlist: make([]error, 0), rcpt: make([]string, 0)}
+		}
+		rcptSendErr.Reason = ErrSMTPRcptTo
+		rcptSendErr.errlist = append(rcptSendErr.errlist, err)
+		rcptSendErr.rcpt = append(rcptSendErr.rcpt, rcpts[i])
+		rcptSendErr.isTemp = isTempError(err)
+		rcptSendErr.errcode = errorCode(err)
+		rcptSendErr.enhancedStatusCode = enhancedStatusCode(err, escSupport)
+	}
+	return rcptSendErr
+}
+
 // checkConn ensures that a required server connection is available and extends the connection
 // deadline.
 //
@@ -1846,33 +2398,52 @@ func (c *Client) setDefaultHelo() error {
 // tls establishes a TLS connection based on the client's TLS policy and configuration.
 // Returns an error if no active connection exists or if a TLS error occurs.
 //
-// This method first checks if there is an active connection to the SMTP server. If SSL is not
-// being used and the TLS policy is not set to NoTLS, it checks for STARTTLS support. Depending
-// on the TLS policy (mandatory or opportunistic), it may initiate a TLS connection using the
+// This method first checks if there is an active connection to the SMTP server. If the connection
+// does not use implicit SSL/TLS and the TLS policy is not set to NoTLS, it checks for STARTTLS
+// support. Depending on the TLS policy (mandatory or opportunistic), it may initiate a TLS connection using the
 // StartTLS method. The method also retrieves the TLS connection state to determine if the
 // connection is encrypted and returns any errors encountered during these processes.
 //
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - policy: The effective TLSPolicy for the connection. For TLSMTASTS and TLSDANE, this is the
+//     TLSPolicy derived from the MTA-STS policy or the TLSA records of the server.
+//   - tlsConfig: The tls.Config for the STARTTLS handshake.
+//   - implicitTLS: Indicates whether the connection uses implicit SSL/TLS, in which case STARTTLS
+//     is skipped. This is not the case for the fallback connection of WithSSLPort.
+//   - isEnc: A pointer to a boolean that is set to true if the connection is encrypted.
+//
 // Returns:
 //   - An error if there is no active connection, if STARTTLS is required but not supported,
 //     or if there are issues during the TLS handshake; otherwise, returns nil.
-func (c *Client) tls(client *smtp.Client, isEnc *bool) error {
-	if !c.useSSL && c.tlspolicy != NoTLS {
+func (c *Client) tls(client *smtp.Client, policy TLSPolicy, tlsConfig *tls.Config, implicitTLS bool,
+	isEnc *bool,
+) error {
+	if !implicitTLS && policy != NoTLS {
 		hasStartTLS := false
 		extension, _ := client.Extension("STARTTLS")
-		if c.tlspolicy == TLSMandatory {
+		if policy == TLSMandatory {
 			hasStartTLS = true
 			if !extension {
+				// TLSDANE only replaces the tls.Config if usable TLSA records exist, otherwise the
+				// fallback policy is in place
+				switch {
+				case c.tlspolicy == TLSMTASTS:
+					return ErrMTASTSNoStartTLS
+				case c.tlspolicy == TLSDANE && tlsConfig != c.tlsconfig:
+					return ErrDANENoStartTLS
+				}
 				return fmt.Errorf("STARTTLS mode set to: %q, but target host does not support STARTTLS",
-					c.tlspolicy)
+					policy)
 			}
 		}
-		if c.tlspolicy == TLSOpportunistic {
+		if policy == TLSOpportunistic {
 			if extension {
 				hasStartTLS = true
 			}
 		}
 		if hasStartTLS {
-			if err := client.StartTLS(c.tlsconfig); err != nil {
+			if err := client.StartTLS(c.relayTLSConfig(tlsConfig, client.ServerName())); err != nil {
 				return err
 			}
 		}
@@ -30,6 +30,7 @@ import (
 
 	"github.com/wneessen/go-mail/log"
 	"github.com/wneessen/go-mail/smtp"
+	"github.com/wneessen/go-mail/smtptest"
 )
 
 const (
@@ -905,6 +906,8 @@ func TestClient_TLSPolicy(t *testing.T) {
 		{"TLSMandatory", TLSMandatory, "TLSMandatory"},
 		{"TLSOpportunistic", TLSOpportunistic, "TLSOpportunistic"},
 		{"NoTLS", NoTLS, "NoTLS"},
+		{"TLSMTASTS", TLSMTASTS, "TLSMTASTS"},
+		{"TLSDANE", TLSDANE, "TLSDANE"},
 		{"Invalid", -1, "UnknownPolicy"},
 	}
 	for _, tt := range tests {
@@ -1917,6 +1920,82 @@ func TestClient_DialWithContext(t *testing.T) {
 			t.Fatalf("client has no connection")
 		}
 	})
+	t.Run("SSL fallback is secured with STARTTLS according to the TLS policy", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			policy    TLSPolicy
+			starttls  bool
+			encrypted bool
+		}{
+			{"TLSMandatory with STARTTLS", TLSMandatory, true, true},
+			{"TLSOpportunistic with STARTTLS", TLSOpportunistic, true, true},
+			{"TLSOpportunistic without STARTTLS", TLSOpportunistic, false, false},
+			{"NoTLS with STARTTLS", NoTLS, true, false},
+			{"NoTLS without STARTTLS", NoTLS, false, false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var opts []smtptest.Option
+				if tt.starttls {
+					opts = append(opts, smtptest.WithSTARTTLS())
+				}
+				server, err := smtptest.NewServer(opts...)
+				if err != nil {
+					t.Fatalf("failed to start test server: %s", err)
+				}
+				t.Cleanup(func() {
+					_ = server.Close()
+				})
+				ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+				t.Cleanup(cancelDial)
+
+				client, err := NewClient(server.Host(), WithSSL(), WithTLSPolicy(tt.policy),
+					WithTLSConfig(server.ClientTLSConfig()))
+				if err != nil {
+					t.Fatalf("failed to create new client: %s", err)
+				}
+				client.port = 12345
+				client.fallbackPort = server.Port()
+
+				if err = client.DialWithContext(ctxDial); err != nil {
+					t.Fatalf("failed to connect to the test server: %s", err)
+				}
+				t.Cleanup(func() {
+					if err = client.Close(); err != nil {
+						t.Errorf("failed to close the client: %s", err)
+					}
+				})
+				if _, ok := client.smtpClient.TLSConnectionState(); ok != tt.encrypted {
+					t.Errorf("expected fallback connection encryption to be %t, got: %t", tt.encrypted, ok)
+				}
+				if err = client.Send(t.Context(), testMessage(t)); err != nil {
+					t.Errorf("failed to send message via fallback connection: %s", err)
+				}
+			})
+		}
+	})
+	t.Run("SSL fallback fails with TLSMandatory if STARTTLS is not supported", func(t *testing.T) {
+		server, err := smtptest.NewServer()
+		if err != nil {
+			t.Fatalf("failed to start test server: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = server.Close()
+		})
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(server.Host(), WithSSL(), WithTLSPolicy(TLSMandatory))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		client.port = 12345
+		client.fallbackPort = server.Port()
+
+		if err = client.DialWithContext(ctxDial); err == nil {
+			t.Error("expected unencrypted fallback connection to fail with TLSMandatory")
+		}
+	})
 	t.Run("fail on invalid host", func(t *testing.T) {
 		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
 		t.Cleanup(cancelDial)
@@ -2852,7 +2931,7 @@ func TestClient_auth(t *testing.T) {
 		if err = client.DialWithContext(ctxDial); err != nil {
 			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -2916,7 +2995,7 @@ func TestClient_auth(t *testing.T) {
 		if err = client.DialWithContext(ctxDial); err != nil {
 			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -2952,7 +3031,7 @@ func TestClient_auth(t *testing.T) {
 		if err = client.DialWithContext(ctxDial); err != nil {
 			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -2988,7 +3067,7 @@ func TestClient_auth(t *testing.T) {
 		if err = client.DialWithContext(ctxDial); err != nil {
 			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -3024,7 +3103,7 @@ func TestClient_auth(t *testing.T) {
 		if err = client.DialWithContext(ctxDial); err != nil {
 			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -3185,7 +3264,7 @@ func TestClient_Send(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.Send(message); err != nil {
+		if err = client.Send(t.Context(), message); err != nil {
 			t.Errorf("failed to send email: %s", err)
 		}
 	})
@@ -3225,16 +3304,50 @@ func TestClient_Send(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.Send(nil); err != nil {
+		if err = client.Send(t.Context(), nil); err != nil {
 			t.Errorf("failed to send email: %s", err)
 		}
 	})
+	t.Run("send with canceled context should fail", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		go func() {
+			if err := simpleSMTPServer(ctx, t, &serverProps{
+				FeatureSet: "250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8",
+				ListenPort: serverPort,
+			}); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctx); err != nil {
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		ctxSend, cancelSend := context.WithCancel(ctx)
+		cancelSend()
+		var sender Sender = client
+		if err = sender.Send(ctxSend, testMessage(t)); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected error to be %s, got: %s", context.Canceled, err)
+		}
+	})
 	t.Run("send with no connection should fail", func(t *testing.T) {
 		client, err := NewClient(DefaultHost)
 		if err != nil {
 			t.Fatalf("failed to create new client: %s", err)
 		}
-		if err = client.Send(message); err == nil {
+		if err = client.Send(t.Context(), message); err == nil {
 			t.Errorf("client should have failed to send email with no connection")
 		}
 		var sendErr *SendError
@@ -3297,7 +3410,7 @@ func TestClient_Send(t *testing.T) {
 			wg.Add(1)
 			go func(curMsg *Msg, curID int) {
 				defer wg.Done()
-				if goroutineErr := client.Send(curMsg); err != nil {
+				if goroutineErr := client.Send(t.Context(), curMsg); err != nil {
 					t.Errorf("failed to send message with ID %d: %s", curID, goroutineErr)
 				}
 			}(curMessage, id)
@@ -3482,7 +3595,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err != nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
 	})
@@ -3524,7 +3637,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 	})
@@ -3568,7 +3681,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3617,7 +3730,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3666,7 +3779,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3715,9 +3828,69 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err != nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+	})
+	t.Run("connect and send email with DSN envelope ID and original recipient", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+			SupportDSN: true,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		if err := message.SetEnvelopeID("order 4711"); err != nil {
+			t.Fatalf("failed to set envelope ID: %s", err)
+		}
+		if err := message.SetOriginalRcpt(TestRcptValid, "toni+orders@domain.tld"); err != nil {
+			t.Fatalf("failed to set original recipient: %s", err)
+		}
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS), WithDSN())
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
 			t.Errorf("failed to send message: %s", err)
 		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, " RET=FULL ENVID=order+204711\r\n") {
+			t.Errorf("expected ENVID in MAIL FROM, got: %s", echo)
+		}
+		if !strings.Contains(echo, " ORCPT=rfc822;toni+2Borders@domain.tld\r\n") {
+			t.Errorf("expected ORCPT in RCPT TO, got: %s", echo)
+		}
 	})
 	t.Run("connect and send email but fail on reset", func(t *testing.T) {
 		ctx := t.Context()
@@ -3757,7 +3930,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3807,7 +3980,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err != nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
 			t.Errorf("client should not have failed, but got: %s", err)
 		}
 	})
@@ -3850,7 +4023,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3900,7 +4073,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3949,7 +4122,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -3998,7 +4171,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Errorf("client should have failed to send message")
 		}
 		var sendErr *SendError
@@ -4047,7 +4220,7 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err == nil {
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
 			t.Error("expected mail delivery to fail")
 		}
 		var sendErr *SendError
@@ -4061,33 +4234,32 @@ func TestClient_sendSingleMsg(t *testing.T) {
 			t.Errorf("expected enhanced status code 5.5.2, got %s", sendErr.enhancedStatusCode)
 		}
 	})
-	t.Run("DKIM signed message", func(t *testing.T) {
+	t.Run("connect and send email with PIPELINING", func(t *testing.T) {
 		ctx := t.Context()
 		PortAdder.Add(1)
 		serverPort := int(TestServerPortBase + PortAdder.Load())
-		featureSet := "250-ENHANCEDSTATUSCODES\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+		featureSet := "250-8BITMIME\r\n250-PIPELINING\r\n250-DSN\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
 		go func() {
-			if err := simpleSMTPServer(ctx, t, &serverProps{
-				FeatureSet: featureSet,
-				ListenPort: serverPort,
-			}); err != nil {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
 				t.Errorf("failed to start test server: %s", err)
 				return
 			}
 		}()
 		time.Sleep(time.Millisecond * 30)
 
-		privKey, err := PrivKeyFromPEM(testKeyRSA)
-		if err != nil {
-			t.Fatalf("failed to generate DKIM key: %s", err)
-		}
-		signer := NewDKIMSigner(testDomain, testSelector, privKey)
 		message := testMessage(t)
+		message.addrHeader["Cc"] = []*mail.Address{{Address: TestRcptValid}}
 
 		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
 		t.Cleanup(cancelDial)
 
-		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS), WithAlwaysDKIMSign(signer))
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
 		if err != nil {
 			t.Fatalf("failed to create new client: %s", err)
 		}
@@ -4103,130 +4275,96 @@ func TestClient_sendSingleMsg(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.sendSingleMsg(client.smtpClient, message); err != nil {
-			t.Errorf("failed to deliver mail: %s", err)
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if rcpts := strings.Count(echo, "RCPT TO:<valid-to@domain.tld>"); rcpts != 2 {
+			t.Errorf("expected 2 RCPT TO commands, got %d", rcpts)
 		}
 	})
-}
-
-func TestNewClientNewVersionsOnly(t *testing.T) {
-	tests := []struct {
-		name       string
-		option     Option
-		expectFunc func(c *Client) error
-		shouldfail bool
-		expectErr  *error
-	}{
-		{
-			"WithLogger log.JSONlog", WithLogger(log.NewJSON(os.Stderr, log.LevelDebug)),
-			func(c *Client) error {
-				if c.logger == nil {
-					return errors.New("failed to set logger. Want logger bug got got nil")
-				}
-				loggerType := reflect.TypeOf(c.logger).String()
-				if loggerType != "*log.JSONlog" {
-					return fmt.Errorf("failed to set logger. Want logger type: %s, got: %s",
-						"*log.JSONlog", loggerType)
-				}
-				return nil
-			},
-			false, nil,
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			client, err := NewClient(DefaultHost, tt.option)
-			if !tt.shouldfail && err != nil {
-				t.Fatalf("failed to create new client: %s", err)
-			}
-			if tt.shouldfail && err == nil {
-				t.Errorf("client creation was supposed to fail, but it didn't")
-			}
-			if tt.shouldfail && tt.expectErr != nil {
-				if !errors.Is(err, *tt.expectErr) {
-					t.Errorf("error for NewClient mismatch. Expected: %s, got: %s",
-						*tt.expectErr, err)
-				}
-			}
-			if tt.expectFunc != nil {
-				if err = tt.expectFunc(client); err != nil {
-					t.Errorf("NewClient with custom option failed: %s", err)
-				}
+	t.Run("PIPELINING with mix of valid and invalid rcpts resets the transaction", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-PIPELINING\r\n250-DSN\r\n250 SMTPUTF8"
+		go func() {
+			if err := simpleSMTPServer(ctx, t, &serverProps{
+				FeatureSet: featureSet,
+				ListenPort: serverPort,
+			}); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
 			}
-		})
-	}
-}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		message.addrHeader["To"] = append(message.addrHeader["To"], &mail.Address{Address: "invalid@domain.tld"})
+		message.addrHeader["Cc"] = []*mail.Address{{Address: TestRcptValid}}
 
-func TestClient_DialWithContextNewVersionsOnly(t *testing.T) {
-	ctx := t.Context()
-	PortAdder.Add(1)
-	serverPort := int(TestServerPortBase + PortAdder.Load())
-	featureSet := "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
-	go func() {
-		if err := simpleSMTPServer(ctx, t, &serverProps{FeatureSet: featureSet, ListenPort: serverPort}); err != nil {
-			t.Errorf("failed to start test server: %s", err)
-			return
-		}
-	}()
-	time.Sleep(time.Millisecond * 30)
-	t.Run("connect with full debug logging and auth logging", func(t *testing.T) {
 		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
 		t.Cleanup(cancelDial)
 
-		logBuffer := bytes.NewBuffer(nil)
-		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS),
-			WithDebugLog(), WithLogAuthData(), WithLogger(log.NewJSON(logBuffer, log.LevelDebug)),
-			WithSMTPAuth(SMTPAuthPlain), WithUsername("test"), WithPassword("password"))
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
 		if err != nil {
 			t.Fatalf("failed to create new client: %s", err)
 		}
-
 		if err = client.DialWithContext(ctxDial); err != nil {
 			var netErr net.Error
 			if errors.As(err, &netErr) && netErr.Timeout() {
 				t.Skip("failed to connect to the test server due to timeout")
 			}
-			t.Fatalf("failed to connect to the test server: %s", err)
+			t.Fatalf("failed to connect to test server: %s", err)
 		}
-		t.Cleanup(func() {
-			if err = client.Close(); err != nil {
-				t.Errorf("failed to close the client: %s", err)
-			}
-		})
-
-		logs := parseJSONLog(t, logBuffer)
-		if len(logs.Lines) == 0 {
-			t.Errorf("failed to enable debug logging, but no logs were found")
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
+			t.Fatal("client should have failed to send message")
 		}
-		authFound := false
-		for _, logline := range logs.Lines {
-			if strings.EqualFold(logline.Message, "AUTH PLAIN AHRlc3QAcGFzc3dvcmQ=") &&
-				logline.Direction.From == "client" && logline.Direction.To == "server" {
-				authFound = true
-			}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got %s", err)
 		}
-		if !authFound {
-			t.Errorf("logAuthData not working, no authentication info found in logs")
+		if sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected ErrSMTPRcptTo, got %s", sendErr.Reason)
+		}
+		if len(sendErr.rcpt) != 1 || sendErr.rcpt[0] != "<invalid@domain.tld>" {
+			t.Errorf("expected only the invalid recipient to be affected, got %v", sendErr.rcpt)
+		}
+		if message.IsDelivered() {
+			t.Error("message should not be delivered")
+		}
+		if !client.smtpClient.HasConnection() {
+			t.Error("expected connection to be kept after a failed transaction")
+		}
+		if err = client.smtpClient.Noop(); err != nil {
+			t.Errorf("expected connection to be usable after a failed transaction: %s", err)
 		}
 	})
-}
-
-func TestClient_SendWithSMTPClient(t *testing.T) {
-	message := testMessage(t)
-
-	t.Run("sending with normal client should succeed", func(t *testing.T) {
+	t.Run("PIPELINING with failing DATA resets the transaction", func(t *testing.T) {
 		ctx := t.Context()
 		PortAdder.Add(1)
 		serverPort := int(TestServerPortBase + PortAdder.Load())
-		featureSet := "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+		featureSet := "250-8BITMIME\r\n250-PIPELINING\r\n250-DSN\r\n250 SMTPUTF8"
 		go func() {
-			if err := simpleSMTPServer(ctx, t, &serverProps{FeatureSet: featureSet, ListenPort: serverPort}); err != nil {
+			if err := simpleSMTPServer(ctx, t, &serverProps{
+				FailOnDataInit: true,
+				FeatureSet:     featureSet,
+				ListenPort:     serverPort,
+			}); err != nil {
 				t.Errorf("failed to start test server: %s", err)
 				return
 			}
 		}()
 		time.Sleep(time.Millisecond * 30)
 
+		message := testMessage(t)
+		message.addrHeader["To"] = append(message.addrHeader["To"], &mail.Address{Address: "invalid@domain.tld"})
+
 		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
 		t.Cleanup(cancelDial)
 
@@ -4246,11 +4384,963 @@ func TestClient_SendWithSMTPClient(t *testing.T) {
 				t.Errorf("failed to close client: %s", err)
 			}
 		})
-		if err = client.SendWithSMTPClient(client.smtpClient, message); err != nil {
-			t.Errorf("failed to deliver message via smtp client: %s", err)
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err == nil {
+			t.Fatal("client should have failed to send message")
 		}
-	})
-	t.Run("sending should return a send error on connection test", func(t *testing.T) {
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got %s", err)
+		}
+		if sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected ErrSMTPRcptTo, got %s", sendErr.Reason)
+		}
+		if !client.smtpClient.HasConnection() {
+			t.Error("expected connection to be kept after DATA was rejected")
+		}
+	})
+	t.Run("connect and send email with CHUNKING", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-CHUNKING\r\n250-DSN\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if !strings.EqualFold(message.ServerResponse(), "2.0.0 Ok: queued as 1234567890") {
+			t.Errorf("unexpected server response: %s", message.ServerResponse())
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, "BDAT ") || !strings.Contains(echo, " LAST\r\n") {
+			t.Errorf("expected message to be transmitted with BDAT, got: %s", echo)
+		}
+		if strings.Contains(echo, "\r\nDATA\r\n") {
+			t.Errorf("expected no DATA command, got: %s", echo)
+		}
+	})
+	t.Run("connect and send email with CHUNKING and PIPELINING", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-PIPELINING\r\n250-CHUNKING\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if !strings.EqualFold(message.ServerResponse(), "2.0.0 Ok: queued as 1234567890") {
+			t.Errorf("unexpected server response: %s", message.ServerResponse())
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, "BDAT ") || !strings.Contains(echo, " LAST\r\n") {
+			t.Errorf("expected message to be transmitted with BDAT, got: %s", echo)
+		}
+		if strings.Contains(echo, "\r\nDATA\r\n") {
+			t.Errorf("expected no DATA command, got: %s", echo)
+		}
+	})
+	t.Run("CHUNKING is skipped with WithoutChunking", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-CHUNKING\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS), WithoutChunking())
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if !strings.EqualFold(message.ServerResponse(), "2.0.0 Ok: queued as 1234567890") {
+			t.Errorf("unexpected server response: %s", message.ServerResponse())
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if strings.Contains(echo, "BDAT ") {
+			t.Errorf("expected no BDAT command, got: %s", echo)
+		}
+		if !strings.Contains(echo, "\r\nDATA\r\n") {
+			t.Errorf("expected DATA command, got: %s", echo)
+		}
+	})
+	t.Run("bare LF line endings are converted to CRLF with CHUNKING", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-CHUNKING\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		message.SetEncoding(NoEncoding)
+		message.SetBodyString(TypeTextPlain, "line one\nline two\r\nline three\n")
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		_, payload, found := strings.Cut(echo, " LAST\r\n")
+		if !found {
+			t.Fatalf("expected message to be transmitted with BDAT, got: %s", echo)
+		}
+		if strings.Count(payload, "\n") != strings.Count(payload, "\r\n") {
+			t.Errorf("expected only CRLF line endings in BDAT payload, got: %q", payload)
+		}
+		if !strings.Contains(payload, "line one\r\nline two\r\nline three\r\n") {
+			t.Errorf("expected message body with CRLF line endings, got: %q", payload)
+		}
+	})
+	t.Run("unencoded binary attachment with BINARYMIME", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-CHUNKING\r\n250 BINARYMIME"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		binaryData := []byte("binary\x00data\nwith bare LF\r\n.\r\n")
+		message := testMessage(t)
+		message.SetEncoding(NoEncoding)
+		if err := message.AttachReader("binary.bin", bytes.NewReader(binaryData), WithFileEncoding(NoEncoding)); err != nil {
+			t.Fatalf("failed to attach binary data: %s", err)
+		}
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if !strings.EqualFold(message.ServerResponse(), "2.0.0 Ok: queued as 1234567890") {
+			t.Errorf("unexpected server response: %s", message.ServerResponse())
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, "BODY=BINARYMIME") {
+			t.Errorf("expected BODY=BINARYMIME in MAIL FROM, got: %s", echo)
+		}
+		if !strings.Contains(echo, string(binaryData)) {
+			t.Errorf("expected binary data to be transmitted unaltered, got: %s", echo)
+		}
+	})
+	t.Run("message size is announced with SIZE", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-SIZE 10485760\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		size, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, fmt.Sprintf(" SIZE=%d\r\n", size)) {
+			t.Errorf("expected SIZE=%d in MAIL FROM, got: %s", size, echo)
+		}
+	})
+	t.Run("message is rendered once and counted with CRLF line endings with SIZE", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-SIZE 10485760\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		message.SetEncoding(NoEncoding)
+		message.SetBodyString(TypeTextPlain, "line one\nline two\n")
+		size, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+		renders := &atomic.Int32{}
+		message.middlewares = append(message.middlewares, countingMiddleware{count: renders})
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if count := renders.Load(); count != 1 {
+			t.Errorf("expected message to be rendered once, got: %d", count)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, fmt.Sprintf(" SIZE=%d\r\n", size+2)) {
+			t.Errorf("expected SIZE=%d in MAIL FROM, got: %s", size+2, echo)
+		}
+	})
+	t.Run("SIZE without a limit does not render the message in advance", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-SIZE\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		renders := &atomic.Int32{}
+		message.middlewares = append(message.middlewares, countingMiddleware{count: renders})
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		if count := renders.Load(); count != 1 {
+			t.Errorf("expected message to be rendered once, got: %d", count)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if strings.Contains(echo, " SIZE=") {
+			t.Errorf("expected no SIZE parameter in MAIL FROM, got: %s", echo)
+		}
+	})
+	t.Run("message exceeding the SIZE limit fails", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-SIZE 100\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer: echoBuffer,
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t)
+		size, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		err = client.sendSingleMsg(t.Context(), client.smtpClient, message)
+		if err == nil {
+			t.Fatal("expected message exceeding the size limit to fail")
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrMessageTooLarge {
+			t.Errorf("expected ErrMessageTooLarge, got: %s", sendErr.Reason)
+		}
+		if sendErr.IsTemp() {
+			t.Error("expected permanent error")
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("%d bytes", size)) {
+			t.Errorf("expected message size in error, got: %s", err)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if strings.Contains(echo, "MAIL FROM") {
+			t.Errorf("expected no MAIL FROM command to be sent, got: %s", echo)
+		}
+	})
+	t.Run("message with REQUIRETLS over TLS", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-REQUIRETLS\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer:  echoBuffer,
+			FeatureSet:  featureSet,
+			ListenPort:  serverPort,
+			SSLListener: true,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t, WithRequireTLS())
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithSSL(), WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to send message: %s", err)
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if !strings.Contains(echo, " REQUIRETLS\r\n") {
+			t.Errorf("expected REQUIRETLS in MAIL FROM, got: %s", echo)
+		}
+	})
+	t.Run("message with REQUIRETLS fails without TLS", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250-REQUIRETLS\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer:  echoBuffer,
+			FeatureSet:  featureSet,
+			ListenPort:  serverPort,
+			SSLListener: false,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t, WithRequireTLS())
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		err = client.sendSingleMsg(t.Context(), client.smtpClient, message)
+		if err == nil {
+			t.Fatal("expected message requiring TLS to fail")
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrNoRequireTLS {
+			t.Errorf("expected ErrNoRequireTLS, got: %s", sendErr.Reason)
+		}
+		if sendErr.IsTemp() {
+			t.Error("expected permanent error")
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if strings.Contains(echo, "MAIL FROM") {
+			t.Errorf("expected no MAIL FROM command to be sent, got: %s", echo)
+		}
+	})
+	t.Run("message with REQUIRETLS fails if not supported by the server", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-8BITMIME\r\n250 SMTPUTF8"
+		echoBuffer := bytes.NewBuffer(nil)
+		props := &serverProps{
+			EchoBuffer:  echoBuffer,
+			FeatureSet:  featureSet,
+			ListenPort:  serverPort,
+			SSLListener: true,
+		}
+		go func() {
+			if err := simpleSMTPServer(ctx, t, props); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		message := testMessage(t, WithRequireTLS())
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithSSL(), WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		err = client.sendSingleMsg(t.Context(), client.smtpClient, message)
+		if err == nil {
+			t.Fatal("expected message requiring TLS to fail")
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrNoRequireTLS {
+			t.Errorf("expected ErrNoRequireTLS, got: %s", sendErr.Reason)
+		}
+		if sendErr.IsTemp() {
+			t.Error("expected permanent error")
+		}
+		props.BufferMutex.RLock()
+		echo := echoBuffer.String()
+		props.BufferMutex.RUnlock()
+		if strings.Contains(echo, "MAIL FROM") {
+			t.Errorf("expected no MAIL FROM command to be sent, got: %s", echo)
+		}
+	})
+	t.Run("DKIM signed message", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-ENHANCEDSTATUSCODES\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+		go func() {
+			if err := simpleSMTPServer(ctx, t, &serverProps{
+				FeatureSet: featureSet,
+				ListenPort: serverPort,
+			}); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		privKey, err := PrivKeyFromPEM(testKeyRSA)
+		if err != nil {
+			t.Fatalf("failed to generate DKIM key: %s", err)
+		}
+		signer := NewDKIMSigner(testDomain, testSelector, privKey)
+		message := testMessage(t)
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS), WithAlwaysDKIMSign(signer))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.sendSingleMsg(t.Context(), client.smtpClient, message); err != nil {
+			t.Errorf("failed to deliver mail: %s", err)
+		}
+	})
+}
+
+func TestClient_DialAndSendPipelined(t *testing.T) {
+	t.Run("rejected recipient does not affect the following messages", func(t *testing.T) {
+		server, err := smtptest.NewServer(smtptest.WithPipelining())
+		if err != nil {
+			t.Fatalf("failed to start test server: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = server.Close()
+		})
+		server.SetReplyFunc("RCPT", func(arg string) string {
+			if arg == "TO:<invalid@domain.tld>" {
+				return "550 5.1.1 User unknown"
+			}
+			return ""
+		})
+		client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		first := testMessage(t)
+		if err = first.AddTo("invalid@domain.tld"); err != nil {
+			t.Fatalf("failed to add recipient: %s", err)
+		}
+		second := testMessage(t)
+		if err = client.DialAndSendWithContext(t.Context(), first, second); err == nil {
+			t.Fatal("expected delivery of the first message to fail")
+		}
+		var sendErr *SendError
+		if !errors.As(first.SendError(), &sendErr) || sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected ErrSMTPRcptTo for the first message, got: %s", first.SendError())
+		}
+		if first.IsDelivered() {
+			t.Error("expected first message not to be delivered")
+		}
+		if !second.IsDelivered() {
+			t.Errorf("expected second message to be delivered, got: %s", second.SendError())
+		}
+		if received := server.Messages(); len(received) != 1 {
+			t.Errorf("expected 1 message on the test server, got: %d", len(received))
+		}
+		var dataCommands int
+		for _, command := range server.Commands() {
+			if strings.EqualFold(command, "DATA") {
+				dataCommands++
+			}
+		}
+		if dataCommands != 1 {
+			t.Errorf("expected DATA only for the second message, got %d DATA commands", dataCommands)
+		}
+	})
+}
+
+func TestNewClientNewVersionsOnly(t *testing.T) {
+	tests := []struct {
+		name       string
+		option     Option
+		expectFunc func(c *Client) error
+		shouldfail bool
+		expectErr  *error
+	}{
+		{
+			"WithLogger log.JSONlog", WithLogger(log.NewJSON(os.Stderr, log.LevelDebug)),
+			func(c *Client) error {
+				if c.logger == nil {
+					return errors.New("failed to set logger. Want logger bug got got nil")
+				}
+				loggerType := reflect.TypeOf(c.logger).String()
+				if loggerType != "*log.JSONlog" {
+					return fmt.Errorf("failed to set logger. Want logger type: %s, got: %s",
+						"*log.JSONlog", loggerType)
+				}
+				return nil
+			},
+			false, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(DefaultHost, tt.option)
+			if !tt.shouldfail && err != nil {
+				t.Fatalf("failed to create new client: %s", err)
+			}
+			if tt.shouldfail && err == nil {
+				t.Errorf("client creation was supposed to fail, but it didn't")
+			}
+			if tt.shouldfail && tt.expectErr != nil {
+				if !errors.Is(err, *tt.expectErr) {
+					t.Errorf("error for NewClient mismatch. Expected: %s, got: %s",
+						*tt.expectErr, err)
+				}
+			}
+			if tt.expectFunc != nil {
+				if err = tt.expectFunc(client); err != nil {
+					t.Errorf("NewClient with custom option failed: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_DialWithContextNewVersionsOnly(t *testing.T) {
+	ctx := t.Context()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	featureSet := "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+	go func() {
+		if err := simpleSMTPServer(ctx, t, &serverProps{FeatureSet: featureSet, ListenPort: serverPort}); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+	t.Run("connect with full debug logging and auth logging", func(t *testing.T) {
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		logBuffer := bytes.NewBuffer(nil)
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS),
+			WithDebugLog(), WithLogAuthData(), WithLogger(log.NewJSON(logBuffer, log.LevelDebug)),
+			WithSMTPAuth(SMTPAuthPlain), WithUsername("test"), WithPassword("password"))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to the test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err = client.Close(); err != nil {
+				t.Errorf("failed to close the client: %s", err)
+			}
+		})
+
+		logs := parseJSONLog(t, logBuffer)
+		if len(logs.Lines) == 0 {
+			t.Errorf("failed to enable debug logging, but no logs were found")
+		}
+		authFound := false
+		for _, logline := range logs.Lines {
+			if strings.EqualFold(logline.Message, "AUTH PLAIN AHRlc3QAcGFzc3dvcmQ=") &&
+				logline.Direction.From == "client" && logline.Direction.To == "server" {
+				authFound = true
+			}
+		}
+		if !authFound {
+			t.Errorf("logAuthData not working, no authentication info found in logs")
+		}
+	})
+}
+
+func TestClient_SendWithSMTPClient(t *testing.T) {
+	message := testMessage(t)
+
+	t.Run("sending with normal client should succeed", func(t *testing.T) {
+		ctx := t.Context()
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		featureSet := "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+		go func() {
+			if err := simpleSMTPServer(ctx, t, &serverProps{FeatureSet: featureSet, ListenPort: serverPort}); err != nil {
+				t.Errorf("failed to start test server: %s", err)
+				return
+			}
+		}()
+		time.Sleep(time.Millisecond * 30)
+
+		ctxDial, cancelDial := context.WithTimeout(ctx, time.Millisecond*500)
+		t.Cleanup(cancelDial)
+
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(ctxDial); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				t.Skip("failed to connect to the test server due to timeout")
+			}
+			t.Fatalf("failed to connect to test server: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if err = client.SendWithSMTPClient(client.smtpClient, message); err != nil {
+			t.Errorf("failed to deliver message via smtp client: %s", err)
+		}
+	})
+	t.Run("sending should return a send error on connection test", func(t *testing.T) {
 		ctx := t.Context()
 		PortAdder.Add(1)
 		serverPort := int(TestServerPortBase + PortAdder.Load())
@@ -4741,12 +5831,27 @@ func testMessage(t *testing.T, opts ...MsgOption) *Msg {
 	return message
 }
 
+// stripESMTPParam removes the ESMTP parameter with the given keyword from the given command
+// arguments.
+func stripESMTPParam(args, keyword string) string {
+	idx := strings.Index(args, " "+keyword+"=")
+	if idx < 0 {
+		return args
+	}
+	end := strings.IndexByte(args[idx+1:], ' ')
+	if end < 0 {
+		return args[:idx]
+	}
+	return args[:idx] + args[idx+1+end:]
+}
+
 // testingKey replaces the substring "TESTING KEY" with "PRIVATE KEY" in the given string s.
 func testingKey(s string) string { return strings.ReplaceAll(s, "TESTING KEY", "PRIVATE KEY") }
 
 // serverProps represents the configuration properties for the SMTP server.
 type serverProps struct {
 	BufferMutex     sync.RWMutex
+	Concurrent      bool
 	EchoBuffer      io.Writer
 	FailOnAuth      bool
 	FailOnDataInit  bool
@@ -4758,6 +5863,7 @@ type serverProps struct {
 	FailOnReset     bool
 	FailOnSTARTTLS  bool
 	FailTemp        bool
+	FailTempCount   atomic.Int32
 	FeatureSet      string
 	ListenPort      int
 	SSLListener     bool
@@ -4821,6 +5927,10 @@ func simpleSMTPServer(ctx context.Context, t *testing.T, props *serverProps) err
 				}
 				return fmt.Errorf("unable to accept connection: %w", err)
 			}
+			if props.Concurrent {
+				go handleTestServerConnection(connection, t, props)
+				continue
+			}
 			handleTestServerConnection(connection, t, props)
 		}
 	}
@@ -4895,9 +6005,13 @@ func handleTestServerConnection(connection net.Conn, t *testing.T, props *server
 			}
 			from := strings.TrimPrefix(data, "MAIL FROM:")
 			from = strings.ReplaceAll(from, "BODY=8BITMIME", "")
+			from = strings.ReplaceAll(from, "BODY=BINARYMIME", "")
 			from = strings.ReplaceAll(from, "SMTPUTF8", "")
+			from = strings.ReplaceAll(from, "REQUIRETLS", "")
+			from = stripESMTPParam(from, "SIZE")
 			if props.SupportDSN {
 				from = strings.ReplaceAll(from, "RET=FULL", "")
+				from = stripESMTPParam(from, "ENVID")
 			}
 			from = strings.TrimSpace(from)
 			if !strings.EqualFold(from, "<valid-from@domain.tld>") {
@@ -4909,6 +6023,7 @@ func handleTestServerConnection(connection net.Conn, t *testing.T, props *server
 			to := strings.TrimPrefix(data, "RCPT TO:")
 			if props.SupportDSN {
 				to = strings.ReplaceAll(to, "NOTIFY=FAILURE,SUCCESS", "")
+				to = stripESMTPParam(to, "ORCPT")
 			}
 			to = strings.TrimSpace(to)
 			if !strings.EqualFold(to, "<valid-to@domain.tld>") {
@@ -4947,7 +6062,7 @@ func handleTestServerConnection(connection net.Conn, t *testing.T, props *server
 						writeLine("500 5.0.0 Error during DATA transmission")
 						break
 					}
-					if props.FailTemp {
+					if props.FailTemp || props.FailTempCount.Add(-1) >= 0 {
 						writeLine("451 4.3.0 Error: fail on DATA close")
 						break
 					}
@@ -4956,6 +6071,43 @@ func handleTestServerConnection(connection net.Conn, t *testing.T, props *server
 				}
 				datastring += ddata + "\n"
 			}
+		case strings.HasPrefix(data, "BDAT"):
+			fields := strings.Fields(data)
+			if len(fields) < 2 {
+				writeLine("501 5.5.4 Syntax: BDAT size [LAST]")
+				break
+			}
+			size, serr := strconv.Atoi(fields[1])
+			if serr != nil {
+				writeLine("501 5.5.4 Syntax: BDAT size [LAST]")
+				break
+			}
+			last := len(fields) > 2 && strings.EqualFold(fields[2], "LAST")
+			chunk := make([]byte, size)
+			if _, rerr := io.ReadFull(reader, chunk); rerr != nil {
+				t.Logf("failed to read chunk from connection: %s", rerr)
+				break
+			}
+			if props.EchoBuffer != nil {
+				props.BufferMutex.Lock()
+				if _, berr := props.EchoBuffer.Write(chunk); berr != nil {
+					t.Errorf("failed write to echo buffer: %s", berr)
+				}
+				props.BufferMutex.Unlock()
+			}
+			if !last {
+				writeOK()
+				break
+			}
+			if props.FailOnDataClose {
+				writeLine("500 5.0.0 Error during BDAT transmission")
+				break
+			}
+			if props.FailTemp || props.FailTempCount.Add(-1) >= 0 {
+				writeLine("451 4.3.0 Error: fail on BDAT LAST")
+				break
+			}
+			writeLine("250 2.0.0 Ok: queued as 1234567890")
 		case strings.EqualFold(data, "noop"):
 			if props.FailOnNoop {
 				writeLine("500 5.0.0 Error: fail on NOOP")
@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// List of TLSA certificate usages, selectors and matching types that are supported for DANE.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc6698#section-2.1
+//   - https://datatracker.ietf.org/doc/html/rfc7672#section-3.1
+const (
+	// TLSAUsageDANETA indicates that the TLSA record matches a trust anchor of the certificate chain
+	// of the server.
+	TLSAUsageDANETA uint8 = 2
+
+	// TLSAUsageDANEEE indicates that the TLSA record matches the end-entity certificate of the server.
+	TLSAUsageDANEEE uint8 = 3
+
+	// TLSASelectorCert indicates that the TLSA record matches the full certificate.
+	TLSASelectorCert uint8 = 0
+
+	// TLSASelectorSPKI indicates that the TLSA record matches the SubjectPublicKeyInfo of the certificate.
+	TLSASelectorSPKI uint8 = 1
+
+	// TLSAMatchingFull indicates that the TLSA record holds the exact data of the selected content.
+	TLSAMatchingFull uint8 = 0
+
+	// TLSAMatchingSHA256 indicates that the TLSA record holds the SHA-256 hash of the selected content.
+	TLSAMatchingSHA256 uint8 = 1
+
+	// TLSAMatchingSHA512 indicates that the TLSA record holds the SHA-512 hash of the selected content.
+	TLSAMatchingSHA512 uint8 = 2
+)
+
+var (
+	// ErrDANEResolverIsNil is returned if a nil TLSAResolver is provided to the Client.
+	ErrDANEResolverIsNil = errors.New("DANE TLSA resolver must not be nil")
+
+	// ErrInvalidDANEFallback is returned if an invalid fallback TLSPolicy is provided to WithDANE.
+	ErrInvalidDANEFallback = errors.New("DANE fallback policy can only be TLSMandatory, " +
+		"TLSOpportunistic or NoTLS")
+
+	// ErrDANENoStartTLS is returned if usable TLSA records have been published for the server, but
+	// the server does not support STARTTLS.
+	ErrDANENoStartTLS = errors.New("DANE TLSA records are published, but mail server does not support STARTTLS")
+
+	// ErrDANEVerificationFailed is returned if the certificate chain of the server does not match any
+	// of the usable TLSA records.
+	ErrDANEVerificationFailed = errors.New("server certificate does not match any DANE TLSA record")
+)
+
+// TLSARecord represents a TLSA resource record as used for DANE.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc6698#section-2.1
+type TLSARecord struct {
+	// Usage is the certificate usage of the record. Only TLSAUsageDANETA and TLSAUsageDANEEE are
+	// usable for SMTP.
+	Usage uint8
+
+	// Selector specifies which part of the certificate is matched.
+	Selector uint8
+
+	// MatchingType specifies how the certificate data is matched.
+	MatchingType uint8
+
+	// Data is the certificate association data of the record.
+	Data []byte
+}
+
+// TLSAResolver is an interface for types that look up the TLSA records of a mail server.
+//
+// As DANE relies on the authenticity of the TLSA records, implementations must only return
+// records that have been validated with DNSSEC. If the mail server does not publish any TLSA
+// records, an empty slice and a nil error must be returned. A returned error aborts the
+// connection.
+type TLSAResolver interface {
+	// LookupTLSA looks up the DNSSEC validated TLSA records for the given host and port, i. e. the
+	// "_<port>._tcp.<host>" TLSA resource records.
+	LookupTLSA(ctx context.Context, host string, port int) ([]TLSARecord, error)
+}
+
+// WithDANE enables the TLSDANE policy for the Client, using the provided TLSAResolver.
+//
+// When dialing, the Client looks up the TLSA records of the server. If usable DANE-EE or DANE-TA
+// records exist, STARTTLS is mandatory and the certificate of the server is verified against the
+// TLSA records instead of the WebPKI. If no usable records exist, the Client falls back to the
+// provided TLSPolicy.
+//
+// Parameters:
+//   - resolver: The TLSAResolver that looks up the DNSSEC validated TLSA records.
+//   - fallback: The TLSPolicy to use if no usable TLSA records exist. Must be either TLSMandatory,
+//     TLSOpportunistic or NoTLS.
+//
+// Returns:
+//   - An Option function that enables the TLSDANE policy for the Client.
+//   - An error if the resolver is nil or the fallback TLSPolicy is invalid.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc7672
+func WithDANE(resolver TLSAResolver, fallback TLSPolicy) Option {
+	return func(c *Client) error {
+		if resolver == nil {
+			return ErrDANEResolverIsNil
+		}
+		switch fallback {
+		case TLSMandatory, TLSOpportunistic, NoTLS:
+		default:
+			return ErrInvalidDANEFallback
+		}
+		c.daneResolver = resolver
+		c.daneFallback = fallback
+		c.tlspolicy = TLSDANE
+		return nil
+	}
+}
+
+// usable reports whether the TLSARecord can be used for DANE verification of an SMTP server.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc7672#section-3.1.3
+func (r TLSARecord) usable() bool {
+	if r.Usage != TLSAUsageDANETA && r.Usage != TLSAUsageDANEEE {
+		return false
+	}
+	if r.Selector != TLSASelectorCert && r.Selector != TLSASelectorSPKI {
+		return false
+	}
+	switch r.MatchingType {
+	case TLSAMatchingFull, TLSAMatchingSHA256, TLSAMatchingSHA512:
+		return len(r.Data) > 0
+	}
+	return false
+}
+
+// matches reports whether the TLSARecord matches the given certificate.
+func (r TLSARecord) matches(cert *x509.Certificate) bool {
+	var data []byte
+	switch r.Selector {
+	case TLSASelectorCert:
+		data = cert.Raw
+	case TLSASelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return false
+	}
+	switch r.MatchingType {
+	case TLSAMatchingFull:
+	case TLSAMatchingSHA256:
+		sum := sha256.Sum256(data)
+		data = sum[:]
+	case TLSAMatchingSHA512:
+		sum := sha512.Sum512(data)
+		data = sum[:]
+	default:
+		return false
+	}
+	return bytes.Equal(data, r.Data)
+}
+
+// danePolicy looks up the TLSA records of the given server and returns the effective TLSPolicy and
+// tls.Config for the connection.
+//
+// If usable TLSA records exist, TLSMandatory is returned alongside a copy of the Client's tls.Config
+// that verifies the certificate of the server against the TLSA records. Otherwise, the fallback
+// TLSPolicy and the Client's tls.Config are returned.
+//
+// Parameters:
+//   - ctx: The context.Context for the TLSA lookup.
+//   - host: The hostname of the server the Client connects to.
+//   - port: The port of the server the Client connects to.
+//
+// Returns:
+//   - The effective TLSPolicy for the connection.
+//   - The tls.Config for the connection.
+//   - An error if the TLSA lookup failed.
+func (c *Client) danePolicy(ctx context.Context, host string, port int) (TLSPolicy, *tls.Config, error) {
+	if c.daneResolver == nil {
+		return c.tlspolicy, c.tlsconfig, ErrDANEResolverIsNil
+	}
+	records, err := c.daneResolver.LookupTLSA(ctx, host, port)
+	if err != nil {
+		return c.tlspolicy, c.tlsconfig, fmt.Errorf("failed to look up TLSA records: %w", err)
+	}
+	usable := make([]TLSARecord, 0, len(records))
+	for _, record := range records {
+		if record.usable() {
+			usable = append(usable, record)
+		}
+	}
+	if len(usable) == 0 {
+		return c.daneFallback, c.tlsconfig, nil
+	}
+
+	config := &tls.Config{MinVersion: DefaultTLSMinVersion}
+	if c.tlsconfig != nil {
+		config = c.relayTLSConfig(c.tlsconfig, host).Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	// The WebPKI verification is replaced by the DANE verification in VerifyConnection
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = daneVerifier(usable, config.ServerName)
+	return TLSMandatory, config, nil
+}
+
+// daneVerifier returns a function for the VerifyConnection field of a tls.Config, that verifies
+// the certificate chain of the server against the given TLSA records.
+//
+// A DANE-EE record must match the end-entity certificate of the server. In this case, neither the
+// hostname nor the validity period of the certificate are checked. A DANE-TA record must match a
+// certificate of the chain presented by the server, or hold a full trust anchor certificate. The
+// end-entity certificate must then chain up to the trust anchor and be valid for the hostname.
+//
+// Parameters:
+//   - records: The usable TLSA records of the server.
+//   - hostname: The hostname of the server.
+//
+// Returns:
+//   - A function that verifies the tls.ConnectionState of the connection.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc7672#section-3.1
+//   - https://datatracker.ietf.org/doc/html/rfc7672#section-3.2
+func daneVerifier(records []TLSARecord, hostname string) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrDANEVerificationFailed
+		}
+		leaf := state.PeerCertificates[0]
+		for _, record := range records {
+			if record.Usage == TLSAUsageDANEEE && record.matches(leaf) {
+				return nil
+			}
+		}
+
+		roots := x509.NewCertPool()
+		hasAnchor := false
+		for _, record := range records {
+			if record.Usage != TLSAUsageDANETA {
+				continue
+			}
+			for _, cert := range state.PeerCertificates {
+				if record.matches(cert) {
+					roots.AddCert(cert)
+					hasAnchor = true
+				}
+			}
+			if record.Selector == TLSASelectorCert && record.MatchingType == TLSAMatchingFull {
+				if cert, err := x509.ParseCertificate(record.Data); err == nil {
+					roots.AddCert(cert)
+					hasAnchor = true
+				}
+			}
+		}
+		if !hasAnchor {
+			return ErrDANEVerificationFailed
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := leaf.Verify(x509.VerifyOptions{
+			DNSName:       hostname,
+			Intermediates: intermediates,
+			Roots:         roots,
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDANEVerificationFailed, err)
+		}
+		return nil
+	}
+}
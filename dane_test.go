@@ -0,0 +1,322 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDANE(t *testing.T) {
+	t.Run("DANE is enabled", func(t *testing.T) {
+		resolver := &testTLSAResolver{}
+		client, err := NewClient(DefaultHost, WithDANE(resolver, TLSOpportunistic))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.tlspolicy != TLSDANE {
+			t.Errorf("expected TLS policy to be %s, got: %s", TLSDANE, client.tlspolicy)
+		}
+		if client.daneFallback != TLSOpportunistic {
+			t.Errorf("expected DANE fallback to be %s, got: %s", TLSOpportunistic, client.daneFallback)
+		}
+		if client.daneResolver != resolver {
+			t.Error("expected DANE resolver to be the provided resolver")
+		}
+	})
+	t.Run("nil resolver fails", func(t *testing.T) {
+		_, err := NewClient(DefaultHost, WithDANE(nil, TLSMandatory))
+		if !errors.Is(err, ErrDANEResolverIsNil) {
+			t.Errorf("expected error to be %s, got: %s", ErrDANEResolverIsNil, err)
+		}
+	})
+	t.Run("invalid fallback fails", func(t *testing.T) {
+		for _, fallback := range []TLSPolicy{TLSMTASTS, TLSDANE, -1} {
+			_, err := NewClient(DefaultHost, WithDANE(&testTLSAResolver{}, fallback))
+			if !errors.Is(err, ErrInvalidDANEFallback) {
+				t.Errorf("expected error to be %s, got: %s", ErrInvalidDANEFallback, err)
+			}
+		}
+	})
+}
+
+func TestTLSARecord_usable(t *testing.T) {
+	tests := []struct {
+		name   string
+		record TLSARecord
+		want   bool
+	}{
+		{"DANE-EE SPKI SHA-256", TLSARecord{3, 1, 1, []byte{1}}, true},
+		{"DANE-TA cert SHA-512", TLSARecord{2, 0, 2, []byte{1}}, true},
+		{"DANE-TA full cert", TLSARecord{2, 0, 0, []byte{1}}, true},
+		{"PKIX-TA is unusable", TLSARecord{0, 1, 1, []byte{1}}, false},
+		{"PKIX-EE is unusable", TLSARecord{1, 1, 1, []byte{1}}, false},
+		{"unknown selector", TLSARecord{3, 2, 1, []byte{1}}, false},
+		{"unknown matching type", TLSARecord{3, 1, 3, []byte{1}}, false},
+		{"empty data", TLSARecord{3, 1, 1, nil}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.usable(); got != tt.want {
+				t.Errorf("expected usable to be %t, got: %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTLSARecord_matches(t *testing.T) {
+	_, _, leaf := testDANEChain(t, "mail.example.com")
+	other, _, _ := testDANEChain(t, "mail.example.com")
+	certSHA256 := sha256.Sum256(leaf.Raw)
+	certSHA512 := sha512.Sum512(leaf.Raw)
+	spkiSHA256 := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	spkiSHA512 := sha512.Sum512(leaf.RawSubjectPublicKeyInfo)
+	tests := []struct {
+		name   string
+		record TLSARecord
+		want   bool
+	}{
+		{"full cert", TLSARecord{3, 0, 0, leaf.Raw}, true},
+		{"cert SHA-256", TLSARecord{3, 0, 1, certSHA256[:]}, true},
+		{"cert SHA-512", TLSARecord{3, 0, 2, certSHA512[:]}, true},
+		{"full SPKI", TLSARecord{3, 1, 0, leaf.RawSubjectPublicKeyInfo}, true},
+		{"SPKI SHA-256", TLSARecord{3, 1, 1, spkiSHA256[:]}, true},
+		{"SPKI SHA-512", TLSARecord{3, 1, 2, spkiSHA512[:]}, true},
+		{"other cert", TLSARecord{3, 0, 0, other.Raw}, false},
+		{"selector mismatch", TLSARecord{3, 1, 1, certSHA256[:]}, false},
+		{"unknown selector", TLSARecord{3, 2, 0, leaf.Raw}, false},
+		{"unknown matching type", TLSARecord{3, 0, 3, leaf.Raw}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.record.matches(leaf); got != tt.want {
+				t.Errorf("expected matches to be %t, got: %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDaneVerifier(t *testing.T) {
+	root, intermediate, leaf := testDANEChain(t, "mail.example.com")
+	_, _, otherLeaf := testDANEChain(t, "mail.example.com")
+	chain := tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, intermediate}}
+	leafSPKI := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	intermediateCert := sha512.Sum512(intermediate.Raw)
+	tests := []struct {
+		name     string
+		records  []TLSARecord
+		hostname string
+		state    tls.ConnectionState
+		wantErr  bool
+	}{
+		{
+			"DANE-EE matches the end-entity certificate", []TLSARecord{{3, 1, 1, leafSPKI[:]}},
+			"mail.example.com", chain, false,
+		},
+		{
+			"DANE-EE ignores the hostname", []TLSARecord{{3, 1, 1, leafSPKI[:]}},
+			"other.example.com", chain, false,
+		},
+		{
+			"DANE-EE does not match another certificate", []TLSARecord{{3, 1, 1, leafSPKI[:]}},
+			"mail.example.com",
+			tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherLeaf, intermediate}}, true,
+		},
+		{
+			"DANE-TA matches an intermediate of the chain", []TLSARecord{{2, 0, 2, intermediateCert[:]}},
+			"mail.example.com", chain, false,
+		},
+		{
+			"DANE-TA requires a matching hostname", []TLSARecord{{2, 0, 2, intermediateCert[:]}},
+			"other.example.com", chain, true,
+		},
+		{
+			"DANE-TA with full trust anchor not in the chain", []TLSARecord{{2, 0, 0, root.Raw}},
+			"mail.example.com", chain, false,
+		},
+		{
+			"DANE-TA does not match a foreign chain", []TLSARecord{{2, 0, 2, intermediateCert[:]}},
+			"mail.example.com",
+			tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherLeaf}}, true,
+		},
+		{
+			"no peer certificates", []TLSARecord{{3, 1, 1, leafSPKI[:]}},
+			"mail.example.com", tls.ConnectionState{}, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := daneVerifier(tt.records, tt.hostname)(tt.state)
+			if tt.wantErr && !errors.Is(err, ErrDANEVerificationFailed) {
+				t.Errorf("expected error to be %s, got: %s", ErrDANEVerificationFailed, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("expected verification to succeed, got: %s", err)
+			}
+		})
+	}
+}
+
+func TestClient_DialWithContext_DANE(t *testing.T) {
+	block, _ := pem.Decode(localhostCert)
+	if block == nil {
+		t.Fatal("failed to decode test server certificate")
+	}
+	serverCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse test server certificate: %s", err)
+	}
+	serverSPKI := sha256.Sum256(serverCert.RawSubjectPublicKeyInfo)
+	matching := []TLSARecord{{TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256, serverSPKI[:]}}
+	mismatching := []TLSARecord{{TLSAUsageDANEEE, TLSASelectorSPKI, TLSAMatchingSHA256, make([]byte, 32)}}
+	startTLSFeatures := "250-8BITMIME\r\n250-STARTTLS\r\n250 SMTPUTF8"
+	plainFeatures := "250-8BITMIME\r\n250 SMTPUTF8"
+
+	t.Run("matching TLSA record succeeds", func(t *testing.T) {
+		resolver := &testTLSAResolver{records: matching}
+		client := testDANEClient(t, startTLSFeatures, resolver, NoTLS)
+		if err := client.DialWithContext(t.Context()); err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if _, err := client.smtpClient.GetTLSConnectionState(); err != nil {
+			t.Errorf("expected TLS protected connection, got: %s", err)
+		}
+		if resolver.host != DefaultHost {
+			t.Errorf("expected TLSA lookup for %s, got: %s", DefaultHost, resolver.host)
+		}
+	})
+	t.Run("mismatching TLSA record fails", func(t *testing.T) {
+		client := testDANEClient(t, startTLSFeatures, &testTLSAResolver{records: mismatching}, NoTLS)
+		if err := client.DialWithContext(t.Context()); !errors.Is(err, ErrDANEVerificationFailed) {
+			t.Errorf("expected error to be %s, got: %s", ErrDANEVerificationFailed, err)
+		}
+	})
+	t.Run("usable TLSA records without STARTTLS fail", func(t *testing.T) {
+		client := testDANEClient(t, plainFeatures, &testTLSAResolver{records: matching}, NoTLS)
+		if err := client.DialWithContext(t.Context()); !errors.Is(err, ErrDANENoStartTLS) {
+			t.Errorf("expected error to be %s, got: %s", ErrDANENoStartTLS, err)
+		}
+	})
+	t.Run("no usable TLSA records fall back to the fallback policy", func(t *testing.T) {
+		unusable := []TLSARecord{{1, TLSASelectorSPKI, TLSAMatchingSHA256, serverSPKI[:]}}
+		client := testDANEClient(t, plainFeatures, &testTLSAResolver{records: unusable}, NoTLS)
+		if err := client.DialWithContext(t.Context()); err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("failed to close client: %s", err)
+		}
+	})
+	t.Run("mandatory fallback policy requires STARTTLS", func(t *testing.T) {
+		client := testDANEClient(t, plainFeatures, &testTLSAResolver{}, TLSMandatory)
+		err := client.DialWithContext(t.Context())
+		if err == nil || errors.Is(err, ErrDANENoStartTLS) {
+			t.Errorf("expected STARTTLS error of the fallback policy, got: %s", err)
+		}
+	})
+	t.Run("TLSA lookup failure fails the dial", func(t *testing.T) {
+		client := testDANEClient(t, startTLSFeatures, &testTLSAResolver{err: errors.New("bogus DNSSEC")}, NoTLS)
+		if err := client.DialWithContext(t.Context()); err == nil || !strings.Contains(err.Error(), "bogus DNSSEC") {
+			t.Errorf("expected TLSA lookup failure, got: %s", err)
+		}
+	})
+}
+
+// testTLSAResolver is an in-memory TLSAResolver that returns static TLSA records.
+type testTLSAResolver struct {
+	err     error
+	host    string
+	records []TLSARecord
+}
+
+func (r *testTLSAResolver) LookupTLSA(_ context.Context, host string, _ int) ([]TLSARecord, error) {
+	r.host = host
+	return r.records, r.err
+}
+
+// testDANEChain creates a certificate chain of a root CA, an intermediate CA and an end-entity
+// certificate for the given hostname.
+func testDANEChain(t *testing.T, hostname string) (root, intermediate, leaf *x509.Certificate) {
+	t.Helper()
+	serial := int64(0)
+	create := func(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+	) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %s", err)
+		}
+		serial++
+		template.SerialNumber = big.NewInt(serial)
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %s", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %s", err)
+		}
+		return cert, key
+	}
+	caTemplate := func(name string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject: pkix.Name{CommonName: name}, IsCA: true, BasicConstraintsValid: true,
+			KeyUsage: x509.KeyUsageCertSign,
+		}
+	}
+	root, rootKey := create(caTemplate("go-mail test root"), nil, nil)
+	intermediate, intermediateKey := create(caTemplate("go-mail test intermediate"), root, rootKey)
+	leaf, _ = create(&x509.Certificate{
+		Subject: pkix.Name{CommonName: hostname}, DNSNames: []string{hostname},
+		KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, intermediate, intermediateKey)
+	return root, intermediate, leaf
+}
+
+// testDANEClient starts a test server with the given feature set and returns a Client that
+// connects to it with the TLSDANE policy.
+func testDANEClient(t *testing.T, featureSet string, resolver TLSAResolver, fallback TLSPolicy) *Client {
+	t.Helper()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	go func() {
+		if err := simpleSMTPServer(t.Context(), t, &serverProps{
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+
+	client, err := NewClient(DefaultHost, WithPort(serverPort), WithDANE(resolver, fallback))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}
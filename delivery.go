@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"regexp"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+	"weak"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+// queueIDPatterns are the patterns used to extract the queue ID from the final reply of a server
+// after the message data, with the queue ID as the first submatch.
+var queueIDPatterns = []*regexp.Regexp{
+	// Postfix and most other MTAs: "2.0.0 Ok: queued as 4ABC1234"
+	regexp.MustCompile(`(?i)\bqueued as\s+([^\s;,]+)`),
+	// Exim: "OK id=1abcDE-0001xx-AB"
+	regexp.MustCompile(`(?i)\bid=([^\s;,]+)`),
+	// Sendmail: "2.0.0 x9ABCDEF012345 Message accepted for delivery"
+	regexp.MustCompile(`(?i)^(?:\d\.\d{1,3}\.\d{1,3}\s+)?(\S+)\s+Message accepted for delivery`),
+	// Gmail: "2.0.0 OK  1700000000 abc123si.45 - gsmtp"
+	regexp.MustCompile(`(?i)\bOK\s+\d+\s+(\S+)\s+-\s+gsmtp`),
+}
+
+// DeliveryResult represents the structured result of the delivery of a Msg.
+//
+// It is attached to the Msg once the server accepted the message data and can be retrieved via
+// Msg.DeliveryResult.
+type DeliveryResult struct {
+	// ServerHost is the hostname of the server that accepted the Msg.
+	ServerHost string
+
+	// ServerResponse is the final reply of the server after the message data.
+	ServerResponse string
+
+	// QueueID is the queue ID that has been assigned to the Msg by the server, as parsed from the
+	// ServerResponse. It is empty if the ServerResponse has an unknown format.
+	QueueID string
+
+	// TLSVersion is the TLS version of the connection, e.g. tls.VersionTLS13, or zero if the
+	// connection is not TLS protected. Use tls.VersionName to get its name.
+	TLSVersion uint16
+
+	// TLSCipherSuite is the TLS cipher suite of the connection, or zero if the connection is not
+	// TLS protected. Use tls.CipherSuiteName to get its name.
+	TLSCipherSuite uint16
+
+	// AuthMechanism is the SASL mechanism used for the SMTP authentication, or an empty string if
+	// the Client did not authenticate.
+	AuthMechanism string
+
+	// Rcpts holds the outcome of the delivery for each recipient, as returned by Msg.RcptResults.
+	Rcpts []RcptResult
+
+	// Timings holds the durations of the phases of the delivery.
+	Timings DeliveryTimings
+}
+
+// DeliveryTimings holds the durations of the phases of the delivery of a Msg.
+//
+// The durations of the Dial, TLS and Auth phases relate to the setup of the connection, which might
+// be shared by several messages.
+type DeliveryTimings struct {
+	// Dial is the duration of the establishment of the connection. For connections with implicit
+	// SSL/TLS, it includes the TLS handshake.
+	Dial time.Duration
+
+	// TLS is the duration of the STARTTLS negotiation, or zero if STARTTLS has not been used.
+	TLS time.Duration
+
+	// Auth is the duration of the SMTP authentication, or zero if the Client did not authenticate.
+	Auth time.Duration
+
+	// Data is the duration of the transmission of the message data, including the final reply of
+	// the server.
+	Data time.Duration
+}
+
+// newDeliveryResult returns the DeliveryResult for the provided Msg, which has just been accepted
+// by the server.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - message: A pointer to the Msg that has been sent.
+//   - data: The duration of the transmission of the message data.
+//
+// Returns:
+//   - A pointer to the DeliveryResult of the Msg.
+func (c *Client) newDeliveryResult(client *smtp.Client, message *Msg, data time.Duration) *DeliveryResult {
+	result := &DeliveryResult{
+		ServerHost:     message.serverHost,
+		ServerResponse: message.serverResponse,
+		QueueID:        parseQueueID(message.serverResponse),
+		AuthMechanism:  client.AuthMechanism(),
+		Rcpts:          slices.Clone(message.rcptResults),
+	}
+	if state, ok := client.TLSConnectionState(); ok {
+		result.TLSVersion = state.Version
+		result.TLSCipherSuite = state.CipherSuite
+	}
+	result.Timings = loadSetupTimings(client)
+	result.Timings.Data = data
+	return result
+}
+
+// setupTimings holds the durations of the setup phases of the connections established by the
+// Clients, keyed by a weak pointer to the smtp.Client of the connection. An entry is removed once
+// its smtp.Client has been garbage collected, so it does not outlive the connection.
+var setupTimings sync.Map
+
+// storeSetupTimings stores the durations of the setup phases of the connection of the provided
+// smtp.Client, so that they can be added to the DeliveryResult of every Msg sent via the connection.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//   - timings: The DeliveryTimings holding the durations of the Dial, TLS and Auth phases.
+func storeSetupTimings(client *smtp.Client, timings DeliveryTimings) {
+	key := weak.Make(client)
+	setupTimings.Store(key, timings)
+	runtime.AddCleanup(client, func(key weak.Pointer[smtp.Client]) {
+		setupTimings.Delete(key)
+	}, key)
+}
+
+// loadSetupTimings returns the durations of the setup phases of the connection of the provided
+// smtp.Client, as stored by storeSetupTimings.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that holds the connection to the SMTP server.
+//
+// Returns:
+//   - The DeliveryTimings of the setup phases, or zero durations if the connection has not been
+//     established by a Client.
+func loadSetupTimings(client *smtp.Client) DeliveryTimings {
+	timings, ok := setupTimings.Load(weak.Make(client))
+	if !ok {
+		return DeliveryTimings{}
+	}
+	return timings.(DeliveryTimings)
+}
+
+// parseQueueID extracts the queue ID from the final reply of a server after the message data.
+//
+// Parameters:
+//   - response: The final reply of the server.
+//
+// Returns:
+//   - The queue ID, or an empty string if the reply has an unknown format.
+func parseQueueID(response string) string {
+	for _, pattern := range queueIDPatterns {
+		if match := pattern.FindStringSubmatch(response); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"crypto/tls"
+	"runtime"
+	"testing"
+	"time"
+	"weak"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+func TestClient_Send_DeliveryResult(t *testing.T) {
+	t.Run("delivery result with STARTTLS and SMTP AUTH", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{
+			FeatureSet: "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-STARTTLS\r\n250-ENHANCEDSTATUSCODES\r\n250 SMTPUTF8",
+		})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(TLSMandatory),
+			WithTLSConfig(&tls.Config{InsecureSkipVerify: true}), WithSMTPAuth(SMTPAuthPlain),
+			WithUsername("toni"), WithPassword("tester"))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if message.DeliveryResult() != nil {
+			t.Error("expected no delivery result before sending")
+		}
+		if err = client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		result := message.DeliveryResult()
+		if result == nil {
+			t.Fatal("expected delivery result after sending")
+		}
+		if result.ServerHost != DefaultHost {
+			t.Errorf("expected server host %s, got: %s", DefaultHost, result.ServerHost)
+		}
+		if result.ServerResponse != message.ServerResponse() {
+			t.Errorf("expected server response %q, got: %q", message.ServerResponse(), result.ServerResponse)
+		}
+		if result.QueueID != "1234567890" {
+			t.Errorf("expected queue ID 1234567890, got: %s", result.QueueID)
+		}
+		if result.TLSVersion == 0 || result.TLSCipherSuite == 0 {
+			t.Errorf("expected TLS version and cipher suite, got: %d, %d", result.TLSVersion, result.TLSCipherSuite)
+		}
+		if result.AuthMechanism != "PLAIN" {
+			t.Errorf("expected auth mechanism PLAIN, got: %s", result.AuthMechanism)
+		}
+		if len(result.Rcpts) != 1 || result.Rcpts[0].Code != 250 || result.Rcpts[0].EnhancedStatusCode != "2.0.0" {
+			t.Errorf("unexpected recipient results: %+v", result.Rcpts)
+		}
+		if result.Timings.Dial <= 0 || result.Timings.TLS <= 0 || result.Timings.Auth <= 0 || result.Timings.Data <= 0 {
+			t.Errorf("expected timings for all phases, got: %+v", result.Timings)
+		}
+	})
+	t.Run("delivery result without TLS and SMTP AUTH", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		result := message.DeliveryResult()
+		if result == nil {
+			t.Fatal("expected delivery result after sending")
+		}
+		if result.TLSVersion != 0 || result.TLSCipherSuite != 0 || result.AuthMechanism != "" {
+			t.Errorf("expected no TLS and auth details, got: %+v", result)
+		}
+		if result.Timings.TLS != 0 || result.Timings.Auth != 0 {
+			t.Errorf("expected no TLS and auth timings, got: %+v", result.Timings)
+		}
+	})
+	t.Run("no delivery result if the message data is rejected", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{FailOnDataClose: true})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = client.DialAndSend(message); err == nil {
+			t.Fatal("expected sending to fail")
+		}
+		if message.DeliveryResult() != nil {
+			t.Errorf("expected no delivery result, got: %+v", message.DeliveryResult())
+		}
+	})
+}
+
+func TestSetupTimings(t *testing.T) {
+	t.Run("setup timings are stored per connection", func(t *testing.T) {
+		client, other := &smtp.Client{}, &smtp.Client{}
+		want := DeliveryTimings{Dial: time.Millisecond, TLS: 2 * time.Millisecond, Auth: 3 * time.Millisecond}
+		storeSetupTimings(client, want)
+		if timings := loadSetupTimings(client); timings != want {
+			t.Errorf("expected setup timings to be %+v, got: %+v", want, timings)
+		}
+		if timings := loadSetupTimings(other); timings != (DeliveryTimings{}) {
+			t.Errorf("expected no setup timings for other connection, got: %+v", timings)
+		}
+	})
+	t.Run("setup timings are removed with the connection", func(t *testing.T) {
+		client := &smtp.Client{}
+		key := weak.Make(client)
+		storeSetupTimings(client, DeliveryTimings{Dial: time.Millisecond})
+		client = nil
+		for range 50 {
+			runtime.GC()
+			if _, ok := setupTimings.Load(key); !ok {
+				return
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		t.Error("expected setup timings to be removed after the connection has been garbage collected")
+	})
+}
+
+func TestParseQueueID(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"Postfix", "2.0.0 Ok: queued as 4Vx1Qd2Kz3z9abc", "4Vx1Qd2Kz3z9abc"},
+		{"Exim", "OK id=1tXyZa-000AbC-De", "1tXyZa-000AbC-De"},
+		{"Sendmail", "2.0.0 4BGAbCdE012345 Message accepted for delivery", "4BGAbCdE012345"},
+		{"Gmail", "2.0.0 OK  1729080000 d9443c01a7336-20cbc4a3a5dsi123456.123 - gsmtp", "d9443c01a7336-20cbc4a3a5dsi123456.123"},
+		{"unknown format", "2.0.0 Ok", ""},
+		{"empty response", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQueueID(tt.response); got != tt.want {
+				t.Errorf("expected queue ID %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
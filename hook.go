@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+// SessionHook is the interface for hooks that observe the SMTP sessions of a Client.
+//
+// A SessionHook is invoked synchronously for each phase of a SMTP session, with the
+// context.Context of the operation and the SessionEvent describing the phase. It can be used to
+// record traces or metrics, e.g. with OpenTelemetry or Prometheus, without depending on the debug
+// log. Implementations must be safe for concurrent use and should return quickly, since they
+// block the SMTP session. Embed NopSessionHook to implement only some of the methods.
+type SessionHook interface {
+	// OnDial is called after the connection to a server has been established or failed. For
+	// connections with implicit SSL/TLS, the TLS handshake is part of the dial phase.
+	OnDial(ctx context.Context, event SessionEvent)
+
+	// OnTLSHandshake is called after the STARTTLS negotiation with the server has completed or
+	// failed. The TLSState of the SessionEvent is set if the negotiation succeeded.
+	OnTLSHandshake(ctx context.Context, event SessionEvent)
+
+	// OnAuth is called after the SMTP authentication has completed or failed. The AuthMechanism
+	// of the SessionEvent is set if the authentication succeeded.
+	OnAuth(ctx context.Context, event SessionEvent)
+
+	// OnMailFrom is called after the server replied to the "MAIL FROM" command for a Msg. With
+	// PIPELINING, the commands of the mail transaction are sent in a single batch and the
+	// Duration of the SessionEvent covers the whole batch.
+	OnMailFrom(ctx context.Context, event SessionEvent)
+
+	// OnRcpt is called after the server replied to the "RCPT TO" command for a recipient. With
+	// PIPELINING, the Duration of the SessionEvent is zero, as it is covered by OnMailFrom.
+	OnRcpt(ctx context.Context, event SessionEvent)
+
+	// OnData is called after the message data of a Msg has been transmitted and the server
+	// replied to it, or the transmission failed.
+	OnData(ctx context.Context, event SessionEvent)
+
+	// OnQuit is called after the connection to the server has been closed with the "QUIT"
+	// command.
+	OnQuit(ctx context.Context, event SessionEvent)
+
+	// OnError is called when a connection to the server could not be established or a Msg could
+	// not be sent. The Err of the SessionEvent holds the resulting error, i.e. a SendError for
+	// a Msg. The phase hooks are called with the error of the phase beforehand.
+	OnError(ctx context.Context, event SessionEvent)
+}
+
+// SessionEvent describes a phase of a SMTP session, as passed to the methods of a SessionHook.
+type SessionEvent struct {
+	// Host is the hostname of the server.
+	Host string
+
+	// Message is the Msg that is being sent, or nil for phases of the connection setup.
+	Message *Msg
+
+	// Address is the envelope sender address for OnMailFrom and the recipient address for OnRcpt.
+	Address string
+
+	// AuthMechanism is the SASL mechanism of the successful SMTP authentication for OnAuth.
+	AuthMechanism string
+
+	// TLSState is the state of the TLS connection for OnTLSHandshake, or for OnDial if implicit
+	// SSL/TLS is used.
+	TLSState *tls.ConnectionState
+
+	// Duration is the duration of the phase.
+	Duration time.Duration
+
+	// Err is the error of the phase, or nil if the phase succeeded.
+	Err error
+}
+
+// NopSessionHook is a SessionHook that does nothing. It can be embedded into a custom SessionHook
+// to implement only some of the methods.
+type NopSessionHook struct{}
+
+// OnDial satisfies the SessionHook interface.
+func (NopSessionHook) OnDial(context.Context, SessionEvent) {}
+
+// OnTLSHandshake satisfies the SessionHook interface.
+func (NopSessionHook) OnTLSHandshake(context.Context, SessionEvent) {}
+
+// OnAuth satisfies the SessionHook interface.
+func (NopSessionHook) OnAuth(context.Context, SessionEvent) {}
+
+// OnMailFrom satisfies the SessionHook interface.
+func (NopSessionHook) OnMailFrom(context.Context, SessionEvent) {}
+
+// OnRcpt satisfies the SessionHook interface.
+func (NopSessionHook) OnRcpt(context.Context, SessionEvent) {}
+
+// OnData satisfies the SessionHook interface.
+func (NopSessionHook) OnData(context.Context, SessionEvent) {}
+
+// OnQuit satisfies the SessionHook interface.
+func (NopSessionHook) OnQuit(context.Context, SessionEvent) {}
+
+// OnError satisfies the SessionHook interface.
+func (NopSessionHook) OnError(context.Context, SessionEvent) {}
+
+// WithSessionHook sets the SessionHook that observes the SMTP sessions of the Client.
+//
+// Parameters:
+//   - hook: The SessionHook to invoke for the phases of the SMTP sessions.
+//
+// Returns:
+//   - An Option function that sets the SessionHook for the Client.
+func WithSessionHook(hook SessionHook) Option {
+	return func(c *Client) error {
+		c.sessionHook = hook
+		return nil
+	}
+}
+
+// hook returns the SessionHook of the Client, or a NopSessionHook if none is set.
+func (c *Client) hook() SessionHook {
+	if c.sessionHook == nil {
+		return NopSessionHook{}
+	}
+	return c.sessionHook
+}
@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testHookContextKey struct{}
+
+// testSessionHook is a SessionHook that records the invoked methods and their events.
+type testSessionHook struct {
+	NopSessionHook
+	mutex  sync.Mutex
+	calls  []string
+	events map[string][]SessionEvent
+	ctxOK  bool
+}
+
+func (h *testSessionHook) record(ctx context.Context, name string, event SessionEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.events == nil {
+		h.events = make(map[string][]SessionEvent)
+		h.ctxOK = true
+	}
+	h.calls = append(h.calls, name)
+	h.events[name] = append(h.events[name], event)
+	if ctx.Value(testHookContextKey{}) == nil {
+		h.ctxOK = false
+	}
+}
+
+func (h *testSessionHook) OnDial(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "dial", event)
+}
+
+func (h *testSessionHook) OnTLSHandshake(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "tls", event)
+}
+
+func (h *testSessionHook) OnAuth(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "auth", event)
+}
+
+func (h *testSessionHook) OnMailFrom(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "mail", event)
+}
+
+func (h *testSessionHook) OnRcpt(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "rcpt", event)
+}
+
+func (h *testSessionHook) OnData(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "data", event)
+}
+
+func (h *testSessionHook) OnQuit(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "quit", event)
+}
+
+func (h *testSessionHook) OnError(ctx context.Context, event SessionEvent) {
+	h.record(ctx, "error", event)
+}
+
+func TestWithSessionHook(t *testing.T) {
+	t.Run("hook is set", func(t *testing.T) {
+		hook := &testSessionHook{}
+		client, err := NewClient(DefaultHost, WithSessionHook(hook))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.hook() != hook {
+			t.Error("expected session hook to be set")
+		}
+	})
+	t.Run("NopSessionHook is used by default", func(t *testing.T) {
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if _, ok := client.hook().(NopSessionHook); !ok {
+			t.Errorf("expected NopSessionHook, got: %T", client.hook())
+		}
+	})
+}
+
+func TestClient_SessionHook(t *testing.T) {
+	ctx := context.WithValue(t.Context(), testHookContextKey{}, true)
+	t.Run("hooks are invoked for all phases of the session", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{
+			FeatureSet: "250-AUTH PLAIN\r\n250-8BITMIME\r\n250-STARTTLS\r\n250 SMTPUTF8",
+		})
+		hook := &testSessionHook{}
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(TLSMandatory),
+			WithTLSConfig(&tls.Config{InsecureSkipVerify: true}), WithSMTPAuth(SMTPAuthPlain),
+			WithUsername("toni"), WithPassword("tester"), WithSessionHook(hook))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = client.DialAndSendWithContext(ctx, message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if calls := strings.Join(hook.calls, ","); calls != "dial,tls,auth,mail,rcpt,data,quit" {
+			t.Errorf("unexpected hook calls: %s", calls)
+		}
+		if !hook.ctxOK {
+			t.Error("expected the context to be passed to all hooks")
+		}
+		if tlsEvent := hook.events["tls"][0]; tlsEvent.TLSState == nil || tlsEvent.Err != nil {
+			t.Errorf("unexpected TLS event: %+v", tlsEvent)
+		}
+		if authEvent := hook.events["auth"][0]; authEvent.AuthMechanism != "PLAIN" || authEvent.Duration <= 0 {
+			t.Errorf("unexpected auth event: %+v", authEvent)
+		}
+		if mailEvent := hook.events["mail"][0]; mailEvent.Address != "<"+TestSenderValid+">" ||
+			mailEvent.Message != message || mailEvent.Host != DefaultHost {
+			t.Errorf("unexpected MAIL FROM event: %+v", mailEvent)
+		}
+		if rcptEvent := hook.events["rcpt"][0]; rcptEvent.Address != "<"+TestRcptValid+">" || rcptEvent.Err != nil {
+			t.Errorf("unexpected RCPT TO event: %+v", rcptEvent)
+		}
+		if dataEvent := hook.events["data"][0]; dataEvent.Duration <= 0 || dataEvent.Err != nil {
+			t.Errorf("unexpected DATA event: %+v", dataEvent)
+		}
+	})
+	t.Run("hooks are invoked for pipelined mail transactions", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{FeatureSet: "250-8BITMIME\r\n250-PIPELINING\r\n250 SMTPUTF8"})
+		hook := &testSessionHook{}
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS), WithSessionHook(hook))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = message.AddTo("invalid-to@domain.tld"); err != nil {
+			t.Fatalf("failed to add recipient: %s", err)
+		}
+		if err = client.DialAndSendWithContext(ctx, message); err == nil {
+			t.Fatal("expected sending to fail")
+		}
+		// DATA is not part of the batch, so the transaction is reset and the connection closed as usual
+		if calls := strings.Join(hook.calls, ","); calls != "dial,mail,rcpt,rcpt,error,quit" {
+			t.Errorf("unexpected hook calls: %s", calls)
+		}
+		if rcptEvent := hook.events["rcpt"][1]; rcptEvent.Err == nil {
+			t.Errorf("expected error for rejected recipient, got: %+v", rcptEvent)
+		}
+		var sendErr *SendError
+		if errorEvent := hook.events["error"][0]; !errors.As(errorEvent.Err, &sendErr) || errorEvent.Message != message {
+			t.Errorf("expected SendError for the message, got: %+v", errorEvent)
+		}
+	})
+	t.Run("hooks are invoked for a failed dial", func(t *testing.T) {
+		hook := &testSessionHook{}
+		client, err := NewClient(DefaultHost, WithPort(1), WithTLSPolicy(NoTLS), WithSessionHook(hook))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialAndSendWithContext(ctx, testMessage(t)); err == nil {
+			t.Fatal("expected dial to fail")
+		}
+		if calls := strings.Join(hook.calls, ","); calls != "dial,error" {
+			t.Errorf("unexpected hook calls: %s", calls)
+		}
+		if dialEvent := hook.events["dial"][0]; dialEvent.Err == nil || dialEvent.Host != DefaultHost {
+			t.Errorf("unexpected dial event: %+v", dialEvent)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"github.com/wneessen/go-mail/smtp"
+)
+
+// RcptResult represents the outcome of the delivery of a Msg to a single recipient.
+type RcptResult struct {
+	// Rcpt is the envelope recipient address.
+	Rcpt string
+
+	// Code is the reply code of the server for the recipient.
+	Code int
+
+	// EnhancedStatusCode is the enhanced status code of the server reply for the recipient, if the
+	// server supports the ENHANCEDSTATUSCODES extension.
+	EnhancedStatusCode string
+
+	// Response is the reply text of the server for the recipient.
+	Response string
+
+	// Err is the error returned by the server for the recipient, or nil if the recipient has
+	// been accepted.
+	Err error
+}
+
+// WithLMTP enables the LMTP mode of the Client.
+//
+// LMTP is used to deliver mail into a local mail store like Dovecot or Cyrus, usually via a UNIX
+// domain socket (see NewClient) or port 24. In LMTP mode, the Client greets the server with LHLO
+// instead of EHLO. After the message data has been transmitted, the server returns a reply for
+// each accepted recipient, which are available via Msg.RcptResults. If the delivery failed for
+// some of the recipients only, the SendError of the Msg has the ErrPartialDelivery reason and
+// lists the failed recipients. CHUNKING is not used in LMTP mode.
+//
+// Returns:
+//   - An Option function that enables the LMTP mode for the Client.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc2033
+func WithLMTP() Option {
+	return func(c *Client) error {
+		c.lmtp = true
+		return nil
+	}
+}
+
+// lmtpSendError evaluates the per-recipient replies of an LMTP server after the message data has
+// been transmitted and stores them as RcptResults in the provided Msg. They replace the RcptResults
+// of the RCPT commands, if any have been stored for a partial delivery.
+//
+// Parameters:
+//   - message: A pointer to the Msg that has been sent.
+//   - responses: The per-recipient replies of the LMTP server.
+//   - closeErr: The error returned when closing the data writer.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - A SendError if the delivery failed for any of the recipients; otherwise, returns nil. If
+//     the delivery succeeded for some of the recipients, the SendError has the ErrPartialDelivery
+//     reason and is never temporary, as a retry would deliver the Msg to the successful recipients
+//     again.
+func lmtpSendError(message *Msg, responses []smtp.RcptResponse, closeErr error, escSupport bool) *SendError {
+	var errs []error
+	var failedRcpts []string
+	isTemp := true
+	for _, response := range responses {
+		message.setRcptResult(newRcptResult(response, escSupport))
+		if response.Err != nil {
+			errs = append(errs, response.Err)
+			failedRcpts = append(failedRcpts, response.Rcpt)
+			isTemp = isTemp && isTempError(response.Err)
+		}
+	}
+
+	switch {
+	case closeErr == nil:
+		return nil
+	case len(errs) == 0:
+		// Reading the replies failed before any recipient was rejected
+		return &SendError{
+			Reason: ErrSMTPDataClose, errlist: []error{closeErr}, isTemp: isTempError(closeErr),
+			affectedMsg: message, errcode: errorCode(closeErr),
+			enhancedStatusCode: enhancedStatusCode(closeErr, escSupport),
+		}
+	case len(errs) < len(responses):
+		return &SendError{
+			Reason: ErrPartialDelivery, errlist: errs, rcpt: failedRcpts, isTemp: false,
+			affectedMsg: message, errcode: errorCode(errs[0]),
+			enhancedStatusCode: enhancedStatusCode(errs[0], escSupport),
+		}
+	}
+	return &SendError{
+		Reason: ErrSMTPDataClose, errlist: errs, rcpt: failedRcpts, isTemp: isTemp,
+		affectedMsg: message, errcode: errorCode(errs[0]),
+		enhancedStatusCode: enhancedStatusCode(errs[0], escSupport),
+	}
+}
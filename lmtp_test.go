@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithLMTP(t *testing.T) {
+	client, err := NewClient(DefaultHost, WithLMTP())
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	if !client.lmtp {
+		t.Error("expected LMTP mode to be enabled")
+	}
+}
+
+func TestClient_Send_LMTP(t *testing.T) {
+	rcpts := []string{"toni.tester@domain.tld", "tina.tester@domain.tld"}
+	t.Run("message is delivered to all recipients", func(t *testing.T) {
+		server := testLMTPServer(t, nil)
+		client := testLMTPClient(t, server)
+		message := testMessage(t)
+		if err := message.To(rcpts...); err != nil {
+			t.Fatalf("failed to set recipients: %s", err)
+		}
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+		results := message.RcptResults()
+		if len(results) != 2 {
+			t.Fatalf("expected 2 recipient results, got: %d", len(results))
+		}
+		for i, result := range results {
+			if result.Rcpt != "<"+rcpts[i]+">" {
+				t.Errorf("expected recipient %s, got: %s", rcpts[i], result.Rcpt)
+			}
+			if result.Err != nil || result.Code != 250 || result.EnhancedStatusCode != "2.0.0" {
+				t.Errorf("unexpected recipient result: %+v", result)
+			}
+		}
+		if !strings.HasPrefix(server.commands(), "LHLO ") {
+			t.Errorf("expected LHLO greeting, got: %q", server.commands())
+		}
+	})
+	t.Run("partial delivery is reported", func(t *testing.T) {
+		server := testLMTPServer(t, map[string]string{
+			"<tina.tester@domain.tld>": "452 4.2.2 <tina.tester@domain.tld> mailbox full",
+		})
+		client := testLMTPClient(t, server)
+		message := testMessage(t)
+		if err := message.To(rcpts...); err != nil {
+			t.Fatalf("failed to set recipients: %s", err)
+		}
+		err := client.DialAndSend(message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrPartialDelivery {
+			t.Errorf("expected ErrPartialDelivery, got: %s", sendErr.Reason)
+		}
+		if sendErr.IsTemp() {
+			t.Error("expected partial delivery not to be temporary")
+		}
+		if len(sendErr.rcpt) != 1 || sendErr.rcpt[0] != "<tina.tester@domain.tld>" {
+			t.Errorf("expected failed recipient to be reported, got: %v", sendErr.rcpt)
+		}
+		if sendErr.ErrorCode() != 452 || sendErr.EnhancedStatusCode() != "4.2.2" {
+			t.Errorf("expected 452 4.2.2, got: %d %s", sendErr.ErrorCode(), sendErr.EnhancedStatusCode())
+		}
+		results := message.RcptResults()
+		if len(results) != 2 {
+			t.Fatalf("expected 2 recipient results, got: %d", len(results))
+		}
+		if results[0].Err != nil {
+			t.Errorf("expected first recipient to be accepted, got: %s", results[0].Err)
+		}
+		if results[1].Err == nil || results[1].Code != 452 || results[1].EnhancedStatusCode != "4.2.2" {
+			t.Errorf("unexpected result for second recipient: %+v", results[1])
+		}
+	})
+	t.Run("failed delivery to all recipients is reported", func(t *testing.T) {
+		server := testLMTPServer(t, map[string]string{
+			"<toni.tester@domain.tld>": "452 4.2.2 mailbox full",
+			"<tina.tester@domain.tld>": "451 4.3.0 temporary failure",
+		})
+		client := testLMTPClient(t, server)
+		message := testMessage(t)
+		if err := message.To(rcpts...); err != nil {
+			t.Fatalf("failed to set recipients: %s", err)
+		}
+		err := client.DialAndSend(message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrSMTPDataClose {
+			t.Errorf("expected ErrSMTPDataClose, got: %s", sendErr.Reason)
+		}
+		if !sendErr.IsTemp() {
+			t.Error("expected failed delivery to be temporary")
+		}
+		if len(sendErr.rcpt) != 2 {
+			t.Errorf("expected 2 failed recipients, got: %v", sendErr.rcpt)
+		}
+	})
+}
+
+// testLMTP is a minimal LMTP server for the tests, that listens on a UNIX domain socket, accepts
+// all recipients and replies with the configured reply for each recipient after the message data.
+type testLMTP struct {
+	mutex   sync.Mutex
+	path    string
+	log     strings.Builder
+	replies map[string]string
+}
+
+// commands returns the commands received by the server.
+func (s *testLMTP) commands() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.log.String()
+}
+
+// testLMTPServer starts a testLMTP server with the given per-recipient replies. Recipients without
+// a configured reply are accepted.
+func testLMTPServer(t *testing.T, replies map[string]string) *testLMTP {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "go-mail-lmtp-*")
+	if err != nil {
+		t.Fatalf("failed to create temp directory: %s", err)
+	}
+	server := &testLMTP{path: filepath.Join(dir, "lmtp.sock"), replies: replies}
+	listener, err := net.Listen("unix", server.path)
+	if err != nil {
+		t.Fatalf("failed to listen on UNIX domain socket: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		_ = os.RemoveAll(dir)
+	})
+	go func() {
+		for {
+			conn, aerr := listener.Accept()
+			if aerr != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+// handle serves a single LMTP connection.
+func (s *testLMTP) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	writeLine := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	writeLine("220 go-mail test LMTP server ready")
+	var rcpts []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		s.mutex.Lock()
+		s.log.WriteString(line + "\n")
+		s.mutex.Unlock()
+		switch {
+		case strings.HasPrefix(line, "LHLO"):
+			writeLine("250-localhost.localdomain\r\n250-8BITMIME\r\n250-PIPELINING\r\n250 ENHANCEDSTATUSCODES")
+		case strings.HasPrefix(line, "MAIL FROM:"):
+			rcpts = nil
+			writeLine("250 2.1.0 Ok")
+		case strings.HasPrefix(line, "RCPT TO:"):
+			rcpts = append(rcpts, strings.TrimSpace(strings.TrimPrefix(line, "RCPT TO:")))
+			writeLine("250 2.1.5 Ok")
+		case line == "DATA":
+			writeLine("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				data, derr := reader.ReadString('\n')
+				if derr != nil {
+					return
+				}
+				if strings.TrimSpace(data) == "." {
+					break
+				}
+			}
+			for _, rcpt := range rcpts {
+				reply, ok := s.replies[rcpt]
+				if !ok {
+					reply = "250 2.0.0 " + rcpt + " Saved"
+				}
+				writeLine(reply)
+			}
+		case line == "NOOP":
+			writeLine("250 2.0.0 Ok")
+		case line == "RSET":
+			rcpts = nil
+			writeLine("250 2.0.0 Ok")
+		case line == "QUIT":
+			writeLine("221 2.0.0 Bye")
+			return
+		default:
+			writeLine("500 5.5.2 Error: bad syntax")
+		}
+	}
+}
+
+// testLMTPClient returns a Client in LMTP mode that connects to the given testLMTP server.
+func testLMTPClient(t *testing.T, server *testLMTP) *Client {
+	t.Helper()
+	client, err := NewClient("unix://"+server.path, WithLMTP(), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}
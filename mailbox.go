@@ -0,0 +1,509 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaildirFlagDraft marks a message in a Maildir as a draft.
+	MaildirFlagDraft = "D"
+
+	// MaildirFlagFlagged marks a message in a Maildir as flagged.
+	MaildirFlagFlagged = "F"
+
+	// MaildirFlagPassed marks a message in a Maildir as passed, i.e. forwarded or bounced.
+	MaildirFlagPassed = "P"
+
+	// MaildirFlagReplied marks a message in a Maildir as replied.
+	MaildirFlagReplied = "R"
+
+	// MaildirFlagSeen marks a message in a Maildir as seen.
+	MaildirFlagSeen = "S"
+
+	// MaildirFlagTrashed marks a message in a Maildir as trashed.
+	MaildirFlagTrashed = "T"
+
+	// mboxDefaultSender is the sender used in the separator line of an mbox entry if the Msg
+	// has no sender address.
+	mboxDefaultSender = "MAILER-DAEMON"
+)
+
+var (
+	// ErrInvalidMaildirFlags is returned when the provided Maildir flags contain characters other
+	// than ASCII letters.
+	ErrInvalidMaildirFlags = errors.New("maildir flags must only consist of ASCII letters")
+
+	// maildirCounter is the delivery counter that is part of the unique names of Maildir files.
+	maildirCounter atomic.Uint64
+)
+
+// WriteToMaildir delivers the Msg into the Maildir at the given directory.
+//
+// The Msg is written into a file with a unique name in the "tmp" subdirectory first, flushed to
+// the disk and then moved into the "new" subdirectory, so that readers of the Maildir never see
+// partially written messages. If flags are given, the Msg is moved into the "cur" subdirectory
+// instead, with the flags as the info part of its file name, as it is done by a mail client that
+// has already processed the message, e.g. for an archived copy of a sent message that should be
+// marked as seen. The subdirectories of the Maildir are created if they do not exist.
+//
+// The Msg is written with LF line endings, as expected by the mail clients reading a Maildir.
+//
+// Parameters:
+//   - dir: The path of the Maildir.
+//   - flags: The Maildir flags, e.g. MaildirFlagSeen. The flags are sorted and deduplicated.
+//
+// Returns:
+//   - The path of the delivered file.
+//   - An error if the flags are invalid, or if the Msg cannot be rendered or written.
+//
+// References:
+//   - https://cr.yp.to/proto/maildir.html
+//   - https://www.courier-mta.org/maildir.html
+func (m *Msg) WriteToMaildir(dir, flags string) (string, error) {
+	flags, err := maildirFlags(flags)
+	if err != nil {
+		return "", err
+	}
+	for _, subdir := range []string{"tmp", "new", "cur"} {
+		if err = os.MkdirAll(filepath.Join(dir, subdir), 0o700); err != nil {
+			return "", fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+	name, err := maildirUniqueName()
+	if err != nil {
+		return "", err
+	}
+
+	tmpPath := filepath.Join(dir, "tmp", name)
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create maildir file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	converter := &lfWriter{writer: writer}
+	_, err = m.WriteTo(converter)
+	if err == nil {
+		err = converter.flush()
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to write maildir file: %w", err)
+	}
+
+	path := filepath.Join(dir, "new", name)
+	if flags != "" {
+		path = filepath.Join(dir, "cur", name+":2,"+flags)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to deliver maildir file: %w", err)
+	}
+	return path, nil
+}
+
+// WriteToMbox writes the Msg as an entry of an mbox file to the given io.Writer.
+//
+// The entry starts with a "From " separator line holding the envelope sender of the Msg, as
+// returned by GetSender, and the current time. Lines of the Msg that start with "From ", optionally
+// preceded by any number of ">" characters, are quoted with an additional ">", following the mboxrd
+// format, so that the quoting can be reversed unambiguously. The Msg is written with LF line
+// endings and followed by an empty line that separates it from the next entry.
+//
+// Parameters:
+//   - writer: The io.Writer to which the mbox entry is written.
+//
+// Returns:
+//   - The total number of bytes written.
+//   - An error if the Msg cannot be rendered or written.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc4155
+//   - https://www.loc.gov/preservation/digital/formats/fdd/fdd000385.shtml
+func (m *Msg) WriteToMbox(writer io.Writer) (int64, error) {
+	sender := mboxDefaultSender
+	if from, err := m.GetSender(false); err == nil {
+		sender = strings.Trim(from, "<>")
+	}
+	buffered := bufio.NewWriter(writer)
+	n, err := fmt.Fprintf(buffered, "From %s %s\n", sender, time.Now().UTC().Format(time.ANSIC))
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	quoter := &mboxrdWriter{writer: buffered, lineStart: true}
+	converter := &lfWriter{writer: quoter}
+	_, err = m.WriteTo(converter)
+	if err == nil {
+		err = converter.flush()
+	}
+	if err == nil {
+		err = quoter.flush()
+	}
+	written += quoter.written
+	if err != nil {
+		return written, err
+	}
+	trailer := "\n"
+	if !quoter.lineStart {
+		trailer = "\n\n"
+	}
+	n, err = buffered.WriteString(trailer)
+	written += int64(n)
+	if err != nil {
+		return written, err
+	}
+	return written, buffered.Flush()
+}
+
+// AppendToMbox appends the Msg as an entry to the mbox file with the given name, using
+// WriteToMbox. The file is created if it does not exist.
+//
+// Parameters:
+//   - name: The name of the mbox file.
+//
+// Returns:
+//   - An error if the file cannot be opened or if writing the entry fails, otherwise nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc4155
+func (m *Msg) AppendToMbox(name string) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mbox file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if _, err = m.WriteToMbox(file); err != nil {
+		return fmt.Errorf("failed to write to mbox file: %w", err)
+	}
+	return file.Close()
+}
+
+// MboxToMsgs returns an iterator over the messages of the mbox read from the given io.Reader.
+//
+// The mbox is split into messages at each line that starts with "From ". Lines that start with
+// "From ", preceded by one or more ">" characters, are unquoted by removing one ">", which
+// reverses the quoting of both the mboxrd and the mboxo format. The empty line that separates a
+// message from the next one is removed. Each message is then parsed via EMLToMsgFromReader.
+//
+// For each message, the iterator yields either the parsed Msg or an error. An error that only
+// affects a single message, e.g. a message that cannot be parsed, does not stop the iteration,
+// so that the remaining messages can still be imported. An error reading from the io.Reader
+// ends the iteration, discarding the partially read message.
+//
+// Parameters:
+//   - reader: An io.Reader containing the mbox.
+//
+// Returns:
+//   - An iterator over the parsed Msg objects and the per-message errors.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc4155
+//   - https://www.loc.gov/preservation/digital/formats/fdd/fdd000385.shtml
+func MboxToMsgs(reader io.Reader) iter.Seq2[*Msg, error] {
+	return func(yield func(*Msg, error) bool) {
+		buffered := bufio.NewReader(reader)
+		var message bytes.Buffer
+		index, started := 0, false
+		emit := func() bool {
+			if !started {
+				if len(bytes.TrimSpace(message.Bytes())) == 0 {
+					return true
+				}
+				return yield(nil, errors.New("failed to parse mbox: content before first From line"))
+			}
+			index++
+			data := message.Bytes()
+			if bytes.HasSuffix(data, []byte("\n\n")) || bytes.HasSuffix(data, []byte("\r\n\r\n")) {
+				data = data[:len(data)-1]
+				data = bytes.TrimSuffix(data, []byte("\r"))
+			}
+			msg, err := EMLToMsgFromReader(bytes.NewReader(data))
+			if err != nil {
+				return yield(nil, fmt.Errorf("failed to parse message %d of mbox: %w", index, err))
+			}
+			return yield(msg, nil)
+		}
+
+		for {
+			line, err := buffered.ReadBytes('\n')
+			if len(line) > 0 {
+				switch {
+				case bytes.HasPrefix(line, []byte("From ")):
+					if !emit() {
+						return
+					}
+					message.Reset()
+					started = true
+				case bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")):
+					message.Write(line[1:])
+				default:
+					message.Write(line)
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to read mbox: %w", err))
+				return
+			}
+		}
+		emit()
+	}
+}
+
+// MboxFileToMsgs opens the mbox file at the provided file path and returns an iterator over its
+// messages, using MboxToMsgs. The file is closed when the iteration ends.
+//
+// Parameters:
+//   - filePath: The path to the mbox file.
+//
+// Returns:
+//   - An iterator over the parsed Msg objects and the per-message errors. If the file cannot be
+//     opened, the iterator yields a single error.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc4155
+func MboxFileToMsgs(filePath string) iter.Seq2[*Msg, error] {
+	return func(yield func(*Msg, error) bool) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to open mbox file: %w", err))
+			return
+		}
+		defer func() { _ = file.Close() }()
+		MboxToMsgs(file)(yield)
+	}
+}
+
+// MaildirToMsgs returns an iterator over the messages of the Maildir at the given directory.
+//
+// The messages in the "new" and "cur" subdirectories of the Maildir are parsed via
+// EMLToMsgFromFile, in the order of their file names, which start with the time of their
+// delivery. Messages that are still being delivered into the "tmp" subdirectory, as well as
+// hidden files, are skipped.
+//
+// For each message, the iterator yields either the parsed Msg or an error. An error that only
+// affects a single message, e.g. a message that cannot be parsed, does not stop the iteration,
+// so that the remaining messages can still be imported.
+//
+// Parameters:
+//   - dir: The path of the Maildir.
+//
+// Returns:
+//   - An iterator over the parsed Msg objects and the per-message errors.
+//
+// References:
+//   - https://cr.yp.to/proto/maildir.html
+func MaildirToMsgs(dir string) iter.Seq2[*Msg, error] {
+	return func(yield func(*Msg, error) bool) {
+		for _, subdir := range []string{"new", "cur"} {
+			entries, err := os.ReadDir(filepath.Join(dir, subdir))
+			if err != nil {
+				if !yield(nil, fmt.Errorf("failed to read maildir: %w", err)) {
+					return
+				}
+				continue
+			}
+			for _, entry := range entries {
+				if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+					continue
+				}
+				path := filepath.Join(dir, subdir, entry.Name())
+				msg, err := EMLToMsgFromFile(path)
+				if err != nil {
+					err = fmt.Errorf("failed to parse maildir message %s: %w", path, err)
+					msg = nil
+				}
+				if !yield(msg, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// maildirFlags validates the given Maildir flags and returns them sorted and deduplicated.
+//
+// Parameters:
+//   - flags: The Maildir flags.
+//
+// Returns:
+//   - The sorted and deduplicated flags.
+//   - An error if the flags contain characters other than ASCII letters.
+func maildirFlags(flags string) (string, error) {
+	chars := []byte(flags)
+	for _, char := range chars {
+		if (char < 'A' || char > 'Z') && (char < 'a' || char > 'z') {
+			return "", ErrInvalidMaildirFlags
+		}
+	}
+	slices.Sort(chars)
+	return string(slices.Compact(chars)), nil
+}
+
+// maildirUniqueName returns a unique name for a file in a Maildir, consisting of the current
+// time, the process ID, a delivery counter, random bytes and the hostname.
+//
+// Returns:
+//   - The unique name.
+//   - An error if the random bytes cannot be generated.
+func maildirUniqueName() (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate maildir file name: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+	now := time.Now()
+	return fmt.Sprintf("%d.M%dP%dQ%dR%s.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(),
+		maildirCounter.Add(1), hex.EncodeToString(random), hostname), nil
+}
+
+// lfWriter is an io.Writer that converts CRLF line endings into LF line endings.
+type lfWriter struct {
+	writer io.Writer
+	cr     bool
+}
+
+// Write writes the given data to the underlying io.Writer, omitting each CR that is followed by
+// an LF. A CR at the end of the data is held back until the next call of Write.
+//
+// Parameters:
+//   - data: The data to be written.
+//
+// Returns:
+//   - The number of bytes consumed from the data.
+//   - An error if writing to the underlying io.Writer fails.
+func (w *lfWriter) Write(data []byte) (int, error) {
+	output := make([]byte, 0, len(data)+1)
+	if w.cr {
+		if len(data) == 0 || data[0] != '\n' {
+			output = append(output, '\r')
+		}
+		w.cr = false
+	}
+	for i, char := range data {
+		if char == '\r' {
+			if i == len(data)-1 {
+				w.cr = true
+				continue
+			}
+			if data[i+1] == '\n' {
+				continue
+			}
+		}
+		output = append(output, char)
+	}
+	if _, err := w.writer.Write(output); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// flush writes a held back CR at the end of the data to the underlying io.Writer.
+//
+// Returns:
+//   - An error if writing to the underlying io.Writer fails.
+func (w *lfWriter) flush() error {
+	if !w.cr {
+		return nil
+	}
+	w.cr = false
+	_, err := w.writer.Write([]byte{'\r'})
+	return err
+}
+
+// mboxrdWriter is an io.Writer that quotes lines starting with "From ", optionally preceded by
+// any number of ">" characters, with an additional ">", following the mboxrd format.
+type mboxrdWriter struct {
+	writer    io.Writer
+	line      []byte
+	lineStart bool
+	written   int64
+}
+
+// Write writes the given data to the underlying io.Writer, quoting lines as required. The start
+// of a line is held back until it can be decided whether it needs to be quoted.
+//
+// Parameters:
+//   - data: The data to be written.
+//
+// Returns:
+//   - The number of bytes consumed from the data.
+//   - An error if writing to the underlying io.Writer fails.
+func (w *mboxrdWriter) Write(data []byte) (int, error) {
+	var output bytes.Buffer
+	for _, char := range data {
+		if w.line != nil {
+			w.line = append(w.line, char)
+			if bytes.HasPrefix(bytes.TrimLeft(w.line, ">"), []byte("From ")) {
+				output.WriteByte('>')
+				output.Write(w.line)
+				w.line = nil
+			} else if !bytes.HasPrefix([]byte("From "), bytes.TrimLeft(w.line, ">")) {
+				output.Write(w.line)
+				w.line = nil
+			}
+			w.lineStart = char == '\n'
+			continue
+		}
+		if w.lineStart && (char == '>' || char == 'F') {
+			w.line = []byte{char}
+			w.lineStart = false
+			continue
+		}
+		output.WriteByte(char)
+		w.lineStart = char == '\n'
+	}
+	n, err := w.writer.Write(output.Bytes())
+	w.written += int64(n)
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// flush writes the held back start of the last line to the underlying io.Writer.
+//
+// Returns:
+//   - An error if writing to the underlying io.Writer fails.
+func (w *mboxrdWriter) flush() error {
+	if w.line == nil {
+		return nil
+	}
+	n, err := w.writer.Write(w.line)
+	w.written += int64(n)
+	w.line = nil
+	return err
+}
@@ -0,0 +1,372 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsg_WriteToMaildir(t *testing.T) {
+	t.Run("message without flags is delivered to new", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "Maildir")
+		path, err := testMessage(t).WriteToMaildir(dir, "")
+		if err != nil {
+			t.Fatalf("failed to write message to maildir: %s", err)
+		}
+		if filepath.Dir(path) != filepath.Join(dir, "new") {
+			t.Errorf("expected message in new, got: %s", path)
+		}
+		if strings.Contains(filepath.Base(path), ":") {
+			t.Errorf("expected file name without info, got: %s", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read maildir file: %s", err)
+		}
+		if bytes.Contains(data, []byte("\r\n")) {
+			t.Error("expected LF line endings")
+		}
+		if !bytes.Contains(data, []byte("Subject: Testmail\n")) {
+			t.Errorf("expected message in maildir file, got: %s", data)
+		}
+		tmp, err := os.ReadDir(filepath.Join(dir, "tmp"))
+		if err != nil {
+			t.Fatalf("failed to read tmp directory: %s", err)
+		}
+		if len(tmp) != 0 {
+			t.Errorf("expected empty tmp directory, got: %d files", len(tmp))
+		}
+	})
+	t.Run("message with flags is delivered to cur", func(t *testing.T) {
+		dir := t.TempDir()
+		path, err := testMessage(t).WriteToMaildir(dir, MaildirFlagSeen+MaildirFlagFlagged+MaildirFlagSeen)
+		if err != nil {
+			t.Fatalf("failed to write message to maildir: %s", err)
+		}
+		if filepath.Dir(path) != filepath.Join(dir, "cur") {
+			t.Errorf("expected message in cur, got: %s", path)
+		}
+		if !strings.HasSuffix(path, ":2,FS") {
+			t.Errorf("expected sorted and deduplicated flags, got: %s", path)
+		}
+	})
+	t.Run("file names are unique", func(t *testing.T) {
+		dir := t.TempDir()
+		names := make(map[string]struct{})
+		for range 10 {
+			path, err := testMessage(t).WriteToMaildir(dir, "")
+			if err != nil {
+				t.Fatalf("failed to write message to maildir: %s", err)
+			}
+			names[path] = struct{}{}
+		}
+		if len(names) != 10 {
+			t.Errorf("expected 10 unique file names, got: %d", len(names))
+		}
+	})
+	t.Run("invalid flags fail", func(t *testing.T) {
+		if _, err := testMessage(t).WriteToMaildir(t.TempDir(), "S,T"); !errors.Is(err, ErrInvalidMaildirFlags) {
+			t.Errorf("expected ErrInvalidMaildirFlags, got: %s", err)
+		}
+	})
+	t.Run("maildir on file path fails", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatalf("failed to create file: %s", err)
+		}
+		if _, err := testMessage(t).WriteToMaildir(file, ""); err == nil {
+			t.Error("expected WriteToMaildir to fail on file path")
+		}
+	})
+}
+
+func TestMsg_WriteToMbox(t *testing.T) {
+	t.Run("mbox entry with separator and quoting", func(t *testing.T) {
+		message := testMessage(t)
+		if err := message.EnvelopeFrom("bounces@example.com"); err != nil {
+			t.Fatalf("failed to set envelope from: %s", err)
+		}
+		message.SetBodyString(TypeTextPlain, "From here\r\n>From there\r\nFromage\r\n>>From everywhere")
+		var buffer bytes.Buffer
+		n, err := message.WriteToMbox(&buffer)
+		if err != nil {
+			t.Fatalf("failed to write message to mbox: %s", err)
+		}
+		if n != int64(buffer.Len()) {
+			t.Errorf("expected %d bytes written, got: %d", buffer.Len(), n)
+		}
+		output := buffer.String()
+		separator, _, _ := strings.Cut(output, "\n")
+		fields := strings.SplitN(separator, " ", 3)
+		if len(fields) != 3 || fields[0] != "From" || fields[1] != "bounces@example.com" {
+			t.Fatalf("unexpected separator line: %s", separator)
+		}
+		if _, err = time.Parse(time.ANSIC, fields[2]); err != nil {
+			t.Errorf("failed to parse separator date: %s", err)
+		}
+		for _, want := range []string{"\n>From here\n", "\n>>From there\n", "\nFromage\n", "\n>>>From everywhere\n"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got: %s", want, output)
+			}
+		}
+		if strings.Contains(output, "\r\n") {
+			t.Error("expected LF line endings")
+		}
+		if !strings.HasSuffix(output, "\n\n") {
+			t.Error("expected entry to end with an empty line")
+		}
+	})
+	t.Run("mbox entry without sender", func(t *testing.T) {
+		message := NewMsg()
+		message.SetBodyString(TypeTextPlain, "Testmail")
+		var buffer bytes.Buffer
+		if _, err := message.WriteToMbox(&buffer); err != nil {
+			t.Fatalf("failed to write message to mbox: %s", err)
+		}
+		if !strings.HasPrefix(buffer.String(), "From MAILER-DAEMON ") {
+			t.Errorf("expected default sender in separator line, got: %s", buffer.String())
+		}
+	})
+	t.Run("mbox entry to failing writer", func(t *testing.T) {
+		if _, err := testMessage(t).WriteToMbox(failReadWriteSeekCloser{}); err == nil {
+			t.Error("expected WriteToMbox to fail on failing writer")
+		}
+	})
+}
+
+func TestMsg_AppendToMbox(t *testing.T) {
+	t.Run("messages are appended", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "sent.mbox")
+		for range 2 {
+			if err := testMessage(t).AppendToMbox(name); err != nil {
+				t.Fatalf("failed to append message to mbox: %s", err)
+			}
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("failed to read mbox file: %s", err)
+		}
+		if count := bytes.Count(data, []byte("\nFrom "+TestSenderValid+" ")); count != 1 {
+			t.Errorf("expected 1 separator after the first entry, got: %d", count)
+		}
+		if !bytes.HasPrefix(data, []byte("From "+TestSenderValid+" ")) {
+			t.Errorf("expected mbox file to start with a separator line, got: %s", data)
+		}
+	})
+	t.Run("append to directory fails", func(t *testing.T) {
+		if err := testMessage(t).AppendToMbox(t.TempDir()); err == nil {
+			t.Error("expected AppendToMbox to fail on directory")
+		}
+	})
+}
+
+func TestMboxrdWriter(t *testing.T) {
+	input := "From a\n>From b\nFro\nF\n>\n>>From c"
+	want := ">From a\n>>From b\nFro\nF\n>\n>>>From c"
+	var buffer bytes.Buffer
+	writer := &mboxrdWriter{writer: &buffer, lineStart: true}
+	for i := range len(input) {
+		if _, err := writer.Write([]byte{input[i]}); err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+	if err := writer.flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err)
+	}
+	if buffer.String() != want {
+		t.Errorf("expected %q, got: %q", want, buffer.String())
+	}
+	if writer.written != int64(len(want)) {
+		t.Errorf("expected %d bytes written, got: %d", len(want), writer.written)
+	}
+}
+
+func TestLfWriter(t *testing.T) {
+	var buffer bytes.Buffer
+	writer := &lfWriter{writer: &buffer}
+	for _, chunk := range []string{"a\r", "\nb\r", "c\r\n", "d\r"} {
+		if _, err := writer.Write([]byte(chunk)); err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+	if err := writer.flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err)
+	}
+	if want := "a\nb\rc\nd\r"; buffer.String() != want {
+		t.Errorf("expected %q, got: %q", want, buffer.String())
+	}
+}
+
+func TestMboxToMsgs(t *testing.T) {
+	t.Run("messages written by WriteToMbox are read back", func(t *testing.T) {
+		var buffer bytes.Buffer
+		for _, subject := range []string{"first", "second", "third"} {
+			message := testMessage(t)
+			message.Subject(subject)
+			message.SetBodyString(TypeTextPlain, "From here\r\n>From there")
+			if _, err := message.WriteToMbox(&buffer); err != nil {
+				t.Fatalf("failed to write message to mbox: %s", err)
+			}
+		}
+		var subjects []string
+		for message, err := range MboxToMsgs(&buffer) {
+			if err != nil {
+				t.Fatalf("failed to read message from mbox: %s", err)
+			}
+			subjects = append(subjects, strings.Join(message.GetGenHeader(HeaderSubject), ""))
+			var output bytes.Buffer
+			if _, err = message.WriteTo(&output); err != nil {
+				t.Fatalf("failed to write message: %s", err)
+			}
+			if !strings.Contains(output.String(), "\r\nFrom here\r\n>From there") {
+				t.Errorf("expected unquoted body, got: %s", output.String())
+			}
+		}
+		if strings.Join(subjects, ",") != "first,second,third" {
+			t.Errorf("unexpected subjects: %v", subjects)
+		}
+	})
+	t.Run("broken message does not abort the import", func(t *testing.T) {
+		mbox := "From a@example.com Thu Jan  1 00:00:00 2026\nSubject: first\n\nBody\n\n" +
+			"From b@example.com Thu Jan  1 00:00:00 2026\nnot a header\n\n" +
+			"From c@example.com Thu Jan  1 00:00:00 2026\nSubject: third\n\nBody\n"
+		var subjects []string
+		var errs []error
+		for message, err := range MboxToMsgs(strings.NewReader(mbox)) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			subjects = append(subjects, strings.Join(message.GetGenHeader(HeaderSubject), ""))
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "message 2") {
+			t.Errorf("expected a single error for message 2, got: %v", errs)
+		}
+		if strings.Join(subjects, ",") != "first,third" {
+			t.Errorf("unexpected subjects: %v", subjects)
+		}
+	})
+	t.Run("content before first From line fails", func(t *testing.T) {
+		mbox := "Subject: orphan\n\nBody\nFrom a@example.com Thu Jan  1 00:00:00 2026\nSubject: first\n\nBody\n"
+		var count, errCount int
+		for _, err := range MboxToMsgs(strings.NewReader(mbox)) {
+			if err != nil {
+				errCount++
+				continue
+			}
+			count++
+		}
+		if count != 1 || errCount != 1 {
+			t.Errorf("expected 1 message and 1 error, got: %d messages, %d errors", count, errCount)
+		}
+	})
+	t.Run("empty mbox yields nothing", func(t *testing.T) {
+		for range MboxToMsgs(strings.NewReader("")) {
+			t.Error("expected no messages")
+		}
+	})
+	t.Run("iteration stops on break", func(t *testing.T) {
+		mbox := "From a@example.com Thu Jan  1 00:00:00 2026\nSubject: first\n\nBody\n\n" +
+			"From b@example.com Thu Jan  1 00:00:00 2026\nSubject: second\n\nBody\n"
+		count := 0
+		for range MboxToMsgs(strings.NewReader(mbox)) {
+			count++
+			break
+		}
+		if count != 1 {
+			t.Errorf("expected 1 iteration, got: %d", count)
+		}
+	})
+	t.Run("read error ends the iteration", func(t *testing.T) {
+		var last error
+		for _, err := range MboxToMsgs(failReadWriteSeekCloser{}) {
+			last = err
+		}
+		if last == nil {
+			t.Error("expected read error")
+		}
+	})
+}
+
+func TestMboxFileToMsgs(t *testing.T) {
+	t.Run("messages are read from file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "sent.mbox")
+		for range 2 {
+			if err := testMessage(t).AppendToMbox(name); err != nil {
+				t.Fatalf("failed to append message to mbox: %s", err)
+			}
+		}
+		count := 0
+		for _, err := range MboxFileToMsgs(name) {
+			if err != nil {
+				t.Fatalf("failed to read message from mbox: %s", err)
+			}
+			count++
+		}
+		if count != 2 {
+			t.Errorf("expected 2 messages, got: %d", count)
+		}
+	})
+	t.Run("non-existing file fails", func(t *testing.T) {
+		var errs []error
+		for _, err := range MboxFileToMsgs(filepath.Join(t.TempDir(), "missing.mbox")) {
+			errs = append(errs, err)
+		}
+		if len(errs) != 1 || errs[0] == nil {
+			t.Errorf("expected a single error, got: %v", errs)
+		}
+	})
+}
+
+func TestMaildirToMsgs(t *testing.T) {
+	t.Run("messages written by WriteToMaildir are read back", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := testMessage(t).WriteToMaildir(dir, ""); err != nil {
+			t.Fatalf("failed to write message to maildir: %s", err)
+		}
+		if _, err := testMessage(t).WriteToMaildir(dir, MaildirFlagSeen); err != nil {
+			t.Fatalf("failed to write message to maildir: %s", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "tmp", "incomplete"), []byte("Subject: tmp"), 0o600); err != nil {
+			t.Fatalf("failed to write tmp file: %s", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cur", "broken"), []byte("not a header\n\n"), 0o600); err != nil {
+			t.Fatalf("failed to write broken file: %s", err)
+		}
+		var count int
+		var errs []error
+		for message, err := range MaildirToMsgs(dir) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if subject := strings.Join(message.GetGenHeader(HeaderSubject), ""); subject != "Testmail" {
+				t.Errorf("expected subject Testmail, got: %s", subject)
+			}
+			count++
+		}
+		if count != 2 {
+			t.Errorf("expected 2 messages, got: %d", count)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+			t.Errorf("expected a single error for the broken file, got: %v", errs)
+		}
+	})
+	t.Run("missing maildir fails", func(t *testing.T) {
+		var errs []error
+		for _, err := range MaildirToMsgs(filepath.Join(t.TempDir(), "missing")) {
+			errs = append(errs, err)
+		}
+		if len(errs) != 2 {
+			t.Errorf("expected an error per subdirectory, got: %v", errs)
+		}
+	})
+}
@@ -0,0 +1,455 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	ht "html/template"
+	"io"
+	"iter"
+	"maps"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"sync"
+	tt "text/template"
+)
+
+// DefaultMailMergeConcurrency is the default number of messages a MailMerge sends concurrently.
+const DefaultMailMergeConcurrency = 4
+
+var (
+	// ErrNoMailMergeBase is returned when a MailMerge is created without a base Msg.
+	ErrNoMailMergeBase = errors.New("no base message set for mail merge")
+
+	// ErrNoMailMergeTemplate is returned when a MailMerge is created without a text and an HTML
+	// template.
+	ErrNoMailMergeTemplate = errors.New("mail merge requires a text or an HTML template")
+
+	// ErrInvalidMailMergeConcurrency is returned when the provided concurrency for a MailMerge is
+	// zero or negative.
+	ErrInvalidMailMergeConcurrency = errors.New("mail merge concurrency must be greater than zero")
+)
+
+type (
+	// MailMergeOption is a function type that modifies the configuration or behavior of a MailMerge
+	// instance.
+	MailMergeOption func(*MailMerge) error
+
+	// MailMerge renders and sends personalized copies of a base Msg to a list of recipients, e.g.
+	// for newsletters.
+	//
+	// For each MailMergeRecipient, MailMerge copies the base Msg, addresses the copy to the
+	// recipient and renders its subject and body from the templates of the MailMerge. The templates
+	// are executed with the MailMergeRecipient as data, so that they can access the recipient's
+	// address and name, as well as the custom data of the recipient via the Data field, e.g.
+	// "{{.Data.FirstName}}". The base Msg is never modified and can hold everything that is shared
+	// by all copies, like the sender, attachments, embeds or a DKIM or S/MIME configuration.
+	MailMerge struct {
+		// base is the Msg that is copied for each recipient.
+		base *Msg
+
+		// concurrency is the number of messages that are sent concurrently.
+		concurrency int
+
+		// html is the template for the HTML body of the copies.
+		html *ht.Template
+
+		// subject is the template for the subject of the copies.
+		subject *tt.Template
+
+		// text is the template for the plain text body of the copies.
+		text *tt.Template
+	}
+
+	// MailMergeRecipient represents a single recipient of a MailMerge.
+	MailMergeRecipient struct {
+		// Address is the mail address of the recipient.
+		Address string
+
+		// Name is the optional display name of the recipient.
+		Name string
+
+		// Data holds the custom data of the recipient, that is available to the templates via the
+		// Data field.
+		Data any
+
+		// Headers holds additional header fields that are set for the recipient, e.g. a tracking ID.
+		Headers map[Header]string
+
+		// UnsubscribeURL is the optional URL via which the recipient can unsubscribe. An "https"
+		// URL is set as RFC 8058 one-click unsubscribe URL via Msg.SetListUnsubscribeOneClick,
+		// any other URI, e.g. a "mailto" URI, via Msg.SetListUnsubscribe.
+		UnsubscribeURL string
+	}
+
+	// MailMergeResult represents the result of a MailMerge for a single recipient.
+	MailMergeResult struct {
+		// Recipient is the recipient the result belongs to.
+		Recipient MailMergeRecipient
+
+		// Msg is the personalized copy of the base Msg, or nil if the rendering failed.
+		Msg *Msg
+
+		// Err is the error that occurred while rendering or sending the Msg, or nil if the Msg
+		// has been delivered.
+		Err error
+	}
+)
+
+// NewMailMerge returns a new MailMerge for the provided base Msg and templates.
+//
+// If both a text and an HTML template are provided, the copies are sent as multipart/alternative
+// messages with the plain text body and the HTML alternative. Either of the templates may be nil,
+// but not both.
+//
+// Parameters:
+//   - base: A pointer to the Msg that is copied for each recipient.
+//   - text: A pointer to the text/template.Template for the plain text body, or nil.
+//   - html: A pointer to the html/template.Template for the HTML body, or nil.
+//   - opts: Optional parameters for customizing the MailMerge.
+//
+// Returns:
+//   - A pointer to the new MailMerge.
+//   - An error if the base Msg or both templates are missing, or if any option fails.
+func NewMailMerge(base *Msg, text *tt.Template, html *ht.Template, opts ...MailMergeOption) (*MailMerge, error) {
+	if base == nil {
+		return nil, ErrNoMailMergeBase
+	}
+	if text == nil && html == nil {
+		return nil, ErrNoMailMergeTemplate
+	}
+	merge := &MailMerge{
+		base:        base,
+		concurrency: DefaultMailMergeConcurrency,
+		html:        html,
+		text:        text,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(merge); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	return merge, nil
+}
+
+// WithMailMergeSubject sets the template for the subject of the personalized copies. Without a
+// subject template, the copies keep the subject of the base Msg.
+//
+// Parameters:
+//   - subject: A pointer to the text/template.Template for the subject.
+//
+// Returns:
+//   - A MailMergeOption function that sets the subject template for the MailMerge.
+//   - An error if the template is nil.
+func WithMailMergeSubject(subject *tt.Template) MailMergeOption {
+	return func(m *MailMerge) error {
+		if subject == nil {
+			return errors.New(errTplPointerNil)
+		}
+		m.subject = subject
+		return nil
+	}
+}
+
+// WithMailMergeConcurrency sets the number of messages that are sent concurrently by
+// MailMerge.Send. Each concurrent delivery uses its own SMTP session.
+//
+// Parameters:
+//   - concurrency: The number of concurrent deliveries. Must be greater than zero.
+//
+// Returns:
+//   - A MailMergeOption function that sets the concurrency for the MailMerge.
+//   - An error if the concurrency is zero or negative.
+func WithMailMergeConcurrency(concurrency int) MailMergeOption {
+	return func(m *MailMerge) error {
+		if concurrency <= 0 {
+			return ErrInvalidMailMergeConcurrency
+		}
+		m.concurrency = concurrency
+		return nil
+	}
+}
+
+// Render returns the personalized copy of the base Msg for the given recipient, without sending
+// it, e.g. for a preview.
+//
+// The copy is addressed to the recipient, replacing any "To", "Cc" and "Bcc" addresses of the base
+// Msg. If the base Msg has a Message-ID, the copy receives a new one, so that each copy has its
+// own Message-ID.
+//
+// Parameters:
+//   - recipient: The MailMergeRecipient the copy is rendered for.
+//
+// Returns:
+//   - A pointer to the personalized Msg.
+//   - An error if the recipient address is invalid or if any of the templates fails to execute.
+func (m *MailMerge) Render(recipient MailMergeRecipient) (*Msg, error) {
+	return m.render(m.base, recipient)
+}
+
+// render returns the personalized copy of the provided base Msg for the given recipient.
+//
+// Parameters:
+//   - base: A pointer to the Msg that is copied for the recipient.
+//   - recipient: The MailMergeRecipient the copy is rendered for.
+//
+// Returns:
+//   - A pointer to the personalized Msg.
+//   - An error if the recipient address is invalid or if any of the templates fails to execute.
+func (m *MailMerge) render(base *Msg, recipient MailMergeRecipient) (*Msg, error) {
+	message := base.clone()
+	delete(message.addrHeader, HeaderCc)
+	delete(message.addrHeader, HeaderBcc)
+	to := recipient.Address
+	if recipient.Name != "" {
+		to = (&mail.Address{Name: recipient.Name, Address: recipient.Address}).String()
+	}
+	if err := message.To(to); err != nil {
+		return nil, err
+	}
+	if _, ok := message.genHeader[HeaderMessageID]; ok {
+		message.SetMessageID()
+	}
+
+	if m.subject != nil {
+		buffer := bytes.NewBuffer(nil)
+		if err := m.subject.Execute(buffer, recipient); err != nil {
+			return nil, fmt.Errorf(errTplExecuteFailed, err)
+		}
+		message.Subject(buffer.String())
+	}
+	switch {
+	case m.text != nil && m.html != nil:
+		if err := message.SetBodyTextTemplate(m.text, recipient); err != nil {
+			return nil, err
+		}
+		if err := message.AddAlternativeHTMLTemplate(m.html, recipient); err != nil {
+			return nil, err
+		}
+	case m.text != nil:
+		if err := message.SetBodyTextTemplate(m.text, recipient); err != nil {
+			return nil, err
+		}
+	default:
+		if err := message.SetBodyHTMLTemplate(m.html, recipient); err != nil {
+			return nil, err
+		}
+	}
+
+	for header, value := range recipient.Headers {
+		message.SetGenHeader(header, value)
+	}
+	switch {
+	case recipient.UnsubscribeURL == "":
+	case strings.HasPrefix(strings.ToLower(recipient.UnsubscribeURL), "https:"):
+		if err := message.SetListUnsubscribeOneClick(recipient.UnsubscribeURL); err != nil {
+			return nil, err
+		}
+	default:
+		message.SetListUnsubscribe(recipient.UnsubscribeURL)
+	}
+	return message, nil
+}
+
+// Send renders a personalized copy of the base Msg for each of the given recipients and sends
+// the copies via the provided Client, using up to the configured number of concurrent SMTP
+// sessions of a ClientPool.
+//
+// Send returns an iterator over the results, that yields a MailMergeResult for each recipient as
+// soon as the copy has been sent or has failed, so the results are not necessarily in the order
+// of the recipients. The recipients are only consumed and the messages only sent while iterating.
+// Stopping the iteration early stops sending further copies. If the context is canceled, the
+// remaining recipients are skipped.
+//
+// The attachments and embeds of the base Msg are read once and buffered in memory before the
+// copies are rendered, so that files from an io.Reader or io.ReadSeeker can be shared by the
+// concurrently rendered copies. If a file cannot be read, a single MailMergeResult with the error
+// and without a Recipient is yielded.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeouts and cancellation.
+//   - client: A pointer to the Client used for the delivery.
+//   - recipients: An iterator over the MailMergeRecipient the copies are sent to.
+//
+// Returns:
+//   - An iterator over the MailMergeResult of each recipient.
+func (m *MailMerge) Send(ctx context.Context, client *Client, recipients iter.Seq[MailMergeRecipient]) iter.Seq[MailMergeResult] {
+	return func(yield func(MailMergeResult) bool) {
+		base := m.base.clone()
+		if err := base.bufferFiles(); err != nil {
+			yield(MailMergeResult{Err: err})
+			return
+		}
+		pool, err := NewClientPool(client, WithPoolSize(m.concurrency))
+		if err != nil {
+			yield(MailMergeResult{Err: err})
+			return
+		}
+		defer func() { _ = pool.Close() }()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := make(chan struct{})
+		jobs := make(chan MailMergeRecipient)
+		results := make(chan MailMergeResult)
+
+		go func() {
+			defer close(jobs)
+			for recipient := range recipients {
+				select {
+				case jobs <- recipient:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		wg.Add(m.concurrency)
+		for range m.concurrency {
+			go func() {
+				defer wg.Done()
+				for recipient := range jobs {
+					result := m.send(ctx, pool, base, recipient)
+					select {
+					case results <- result:
+					case <-stop:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for result := range results {
+			if !yield(result) {
+				close(stop)
+				cancel()
+				break
+			}
+		}
+		for range results {
+			// Wait for the workers to finish before the ClientPool is closed
+		}
+	}
+}
+
+// send renders the personalized copy of the provided base Msg for the given recipient and sends it
+// via the provided ClientPool.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - pool: A pointer to the ClientPool used for the delivery.
+//   - base: A pointer to the Msg that is copied for the recipient.
+//   - recipient: The MailMergeRecipient the copy is sent to.
+//
+// Returns:
+//   - The MailMergeResult of the recipient.
+func (m *MailMerge) send(ctx context.Context, pool *ClientPool, base *Msg, recipient MailMergeRecipient) MailMergeResult {
+	result := MailMergeResult{Recipient: recipient}
+	if err := ctx.Err(); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Msg, result.Err = m.render(base, recipient)
+	if result.Err != nil {
+		result.Msg = nil
+		return result
+	}
+	result.Err = pool.Send(ctx, result.Msg)
+	return result
+}
+
+// clone returns a copy of the Msg that can be modified without affecting the original Msg. The
+// header fields, the attachments and embeds including their MIME headers, and the lists of parts
+// and middlewares are copied, while the parts, the middlewares and the Writer functions of the
+// files are shared. The delivery state of the copy is reset.
+//
+// Returns:
+//   - A pointer to the copy of the Msg.
+func (m *Msg) clone() *Msg {
+	message := *m
+	message.addrHeader = make(map[AddrHeader][]*mail.Address, len(m.addrHeader))
+	for header, addresses := range m.addrHeader {
+		message.addrHeader[header] = slices.Clone(addresses)
+	}
+	message.genHeader = make(map[Header][]string, len(m.genHeader))
+	for header, values := range m.genHeader {
+		message.genHeader[header] = slices.Clone(values)
+	}
+	message.preformHeader = make(map[Header]string, len(m.preformHeader))
+	maps.Copy(message.preformHeader, m.preformHeader)
+	message.multiPartBoundary = make(map[MIMEType]string, len(m.multiPartBoundary))
+	maps.Copy(message.multiPartBoundary, m.multiPartBoundary)
+	message.originalRcpts = maps.Clone(m.originalRcpts)
+	message.attachments = cloneFiles(m.attachments)
+	message.embeds = cloneFiles(m.embeds)
+	message.parts = slices.Clone(m.parts)
+	message.middlewares = slices.Clone(m.middlewares)
+
+	message.isDelivered = false
+	message.sendError = nil
+	message.rcptResults = nil
+	message.serverHost = ""
+	message.serverResponse = ""
+	message.deliveryResult = nil
+	return &message
+}
+
+// bufferFiles reads the attachments and embeds of the Msg once and replaces their Writer functions
+// with ones that write the buffered data, so that the files can be written concurrently and
+// repeatedly, e.g. for files from an io.ReadSeeker, whose Writer is not safe for concurrent use.
+//
+// Returns:
+//   - An error if any of the files cannot be read.
+func (m *Msg) bufferFiles() error {
+	for _, file := range slices.Concat(m.attachments, m.embeds) {
+		buffer := bytes.NewBuffer(nil)
+		if _, err := file.Writer(buffer); err != nil {
+			return fmt.Errorf("failed to read file %q: %w", file.Name, err)
+		}
+		data := buffer.Bytes()
+		file.Writer = func(writer io.Writer) (int64, error) {
+			numBytes, err := writer.Write(data)
+			return int64(numBytes), err
+		}
+	}
+	return nil
+}
+
+// cloneFiles returns a copy of the provided files, in which each File and its MIME header are
+// copied, since the MIME header is completed while the Msg is written.
+//
+// Parameters:
+//   - files: The list of files to copy.
+//
+// Returns:
+//   - The list of copied files.
+func cloneFiles(files []*File) []*File {
+	if files == nil {
+		return nil
+	}
+	clones := make([]*File, len(files))
+	for i, file := range files {
+		clone := *file
+		clone.Header = make(textproto.MIMEHeader, len(file.Header))
+		for key, values := range file.Header {
+			clone.Header[key] = slices.Clone(values)
+		}
+		clones[i] = &clone
+	}
+	return clones
+}
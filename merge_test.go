@@ -0,0 +1,349 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	ht "html/template"
+	"slices"
+	"strings"
+	"testing"
+	tt "text/template"
+
+	"github.com/wneessen/go-mail/smtptest"
+)
+
+func TestNewMailMerge(t *testing.T) {
+	text := tt.Must(tt.New("text").Parse("Hello {{.Name}}"))
+	t.Run("new mail merge with defaults", func(t *testing.T) {
+		merge, err := NewMailMerge(testMessage(t), text, nil)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		if merge.concurrency != DefaultMailMergeConcurrency {
+			t.Errorf("expected concurrency %d, got: %d", DefaultMailMergeConcurrency, merge.concurrency)
+		}
+	})
+	t.Run("new mail merge with options", func(t *testing.T) {
+		subject := tt.Must(tt.New("subject").Parse("Hi"))
+		merge, err := NewMailMerge(testMessage(t), text, nil, nil, WithMailMergeSubject(subject),
+			WithMailMergeConcurrency(8))
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		if merge.concurrency != 8 {
+			t.Errorf("expected concurrency 8, got: %d", merge.concurrency)
+		}
+		if merge.subject != subject {
+			t.Error("expected subject template to be set")
+		}
+	})
+	t.Run("new mail merge without base fails", func(t *testing.T) {
+		if _, err := NewMailMerge(nil, text, nil); !errors.Is(err, ErrNoMailMergeBase) {
+			t.Errorf("expected ErrNoMailMergeBase, got: %s", err)
+		}
+	})
+	t.Run("new mail merge without templates fails", func(t *testing.T) {
+		if _, err := NewMailMerge(testMessage(t), nil, nil); !errors.Is(err, ErrNoMailMergeTemplate) {
+			t.Errorf("expected ErrNoMailMergeTemplate, got: %s", err)
+		}
+	})
+	t.Run("new mail merge with invalid concurrency fails", func(t *testing.T) {
+		_, err := NewMailMerge(testMessage(t), text, nil, WithMailMergeConcurrency(0))
+		if !errors.Is(err, ErrInvalidMailMergeConcurrency) {
+			t.Errorf("expected ErrInvalidMailMergeConcurrency, got: %s", err)
+		}
+	})
+	t.Run("new mail merge with nil subject fails", func(t *testing.T) {
+		if _, err := NewMailMerge(testMessage(t), text, nil, WithMailMergeSubject(nil)); err == nil {
+			t.Error("expected nil subject template to fail")
+		}
+	})
+}
+
+func TestMailMerge_Render(t *testing.T) {
+	text := tt.Must(tt.New("text").Parse("Hello {{.Name}}, your code is {{.Data.Code}}"))
+	html := ht.Must(ht.New("html").Parse("<p>Hello {{.Name}}, your code is {{.Data.Code}}</p>"))
+	subject := tt.Must(tt.New("subject").Parse("News for {{.Name}}"))
+	recipient := MailMergeRecipient{
+		Address:        "toni.tester@example.com",
+		Name:           "Toni Tester",
+		Data:           map[string]string{"Code": "<1234>"},
+		Headers:        map[Header]string{"X-Campaign-Rcpt": "42"},
+		UnsubscribeURL: "https://example.com/unsubscribe?id=42",
+	}
+	t.Run("copy is personalized", func(t *testing.T) {
+		base := testMessage(t)
+		if err := base.Cc("tina.tester@example.com"); err != nil {
+			t.Fatalf("failed to set cc address: %s", err)
+		}
+		base.SetMessageID()
+		merge, err := NewMailMerge(base, text, html, WithMailMergeSubject(subject))
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		message, err := merge.Render(recipient)
+		if err != nil {
+			t.Fatalf("failed to render message: %s", err)
+		}
+		if to := message.GetToString(); len(to) != 1 || to[0] != `"Toni Tester" <toni.tester@example.com>` {
+			t.Errorf("unexpected to addresses: %v", to)
+		}
+		if cc := message.GetCcString(); len(cc) != 0 {
+			t.Errorf("expected no cc addresses, got: %v", cc)
+		}
+		if got := message.GetGenHeader(HeaderMessageID); slices.Equal(got, base.GetGenHeader(HeaderMessageID)) {
+			t.Error("expected copy to have its own Message-ID")
+		}
+		var buffer bytes.Buffer
+		if _, err = message.WriteTo(&buffer); err != nil {
+			t.Fatalf("failed to write message: %s", err)
+		}
+		output := buffer.String()
+		for _, want := range []string{
+			"Subject: News for Toni Tester", "X-Campaign-Rcpt: 42",
+			"List-Unsubscribe: <https://example.com/unsubscribe?id=42>",
+			"List-Unsubscribe-Post: List-Unsubscribe=One-Click",
+			"Hello Toni Tester, your code is <1234>",
+			"Hello Toni Tester, your code is &lt;1234&gt;",
+			"multipart/alternative",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got: %s", want, output)
+			}
+		}
+
+		if to := base.GetToString(); len(to) != 1 || to[0] != "<"+TestRcptValid+">" {
+			t.Errorf("expected base message to be unchanged, got to addresses: %v", to)
+		}
+		if len(base.GetCcString()) != 1 {
+			t.Error("expected base message to keep its cc addresses")
+		}
+		if subject := base.GetGenHeader(HeaderSubject); len(subject) != 1 || subject[0] != "Testmail" {
+			t.Errorf("expected base message to keep its subject, got: %v", subject)
+		}
+	})
+	t.Run("html only copy with mailto unsubscribe", func(t *testing.T) {
+		merge, err := NewMailMerge(testMessage(t), nil, html)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		mailto := recipient
+		mailto.UnsubscribeURL = "mailto:unsubscribe@example.com"
+		message, err := merge.Render(mailto)
+		if err != nil {
+			t.Fatalf("failed to render message: %s", err)
+		}
+		var buffer bytes.Buffer
+		if _, err = message.WriteTo(&buffer); err != nil {
+			t.Fatalf("failed to write message: %s", err)
+		}
+		output := buffer.String()
+		if !strings.Contains(output, "List-Unsubscribe: <mailto:unsubscribe@example.com>") {
+			t.Errorf("expected mailto unsubscribe header, got: %s", output)
+		}
+		if strings.Contains(output, "List-Unsubscribe-Post") || strings.Contains(output, "multipart/alternative") {
+			t.Errorf("unexpected output: %s", output)
+		}
+		if !strings.Contains(output, "Subject: Testmail") {
+			t.Errorf("expected subject of base message, got: %s", output)
+		}
+	})
+	t.Run("invalid address fails", func(t *testing.T) {
+		merge, err := NewMailMerge(testMessage(t), text, nil)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		if _, err = merge.Render(MailMergeRecipient{Address: "invalid"}); err == nil {
+			t.Error("expected invalid address to fail")
+		}
+	})
+	t.Run("failing template fails", func(t *testing.T) {
+		failing := tt.Must(tt.New("failing").Parse("{{.Data.Missing.Field}}"))
+		for name, merge := range map[string]*MailMerge{
+			"subject": {base: testMessage(t), text: text, subject: failing},
+			"text":    {base: testMessage(t), text: failing},
+		} {
+			if _, err := merge.Render(MailMergeRecipient{Address: TestRcptValid, Data: 1}); err == nil {
+				t.Errorf("expected failing %s template to fail", name)
+			}
+		}
+	})
+}
+
+func TestMailMerge_Send(t *testing.T) {
+	text := tt.Must(tt.New("text").Parse("Hello {{.Data}}"))
+	recipients := func(count int) func(func(MailMergeRecipient) bool) {
+		return func(yield func(MailMergeRecipient) bool) {
+			for i := range count {
+				address := fmt.Sprintf("rcpt%d@example.com", i)
+				if !yield(MailMergeRecipient{Address: address, Data: i}) {
+					return
+				}
+			}
+		}
+	}
+	t.Run("copies are sent to all recipients", func(t *testing.T) {
+		server, client := testMailMergeServer(t)
+		server.SetReplyFunc("RCPT", func(arg string) string {
+			if arg == "TO:<rcpt3@example.com>" {
+				return "550 5.1.1 User unknown"
+			}
+			return ""
+		})
+		merge, err := NewMailMerge(testMessage(t), text, nil, WithMailMergeConcurrency(3))
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		var delivered, failed []string
+		for result := range merge.Send(t.Context(), client, recipients(10)) {
+			if result.Err != nil {
+				failed = append(failed, result.Recipient.Address)
+				continue
+			}
+			if !result.Msg.IsDelivered() {
+				t.Errorf("expected message to %s to be delivered", result.Recipient.Address)
+			}
+			delivered = append(delivered, result.Recipient.Address)
+		}
+		if len(delivered) != 9 {
+			t.Errorf("expected 9 delivered messages, got: %d", len(delivered))
+		}
+		if len(failed) != 1 || failed[0] != "rcpt3@example.com" {
+			t.Errorf("expected delivery to rcpt3@example.com to fail, got: %v", failed)
+		}
+		if len(server.Messages()) != 9 {
+			t.Errorf("expected 9 messages on the test server, got: %d", len(server.Messages()))
+		}
+		for _, received := range server.Messages() {
+			rcpts := received.RcptAddresses()
+			if len(rcpts) != 1 {
+				t.Fatalf("expected a single recipient per message, got: %v", rcpts)
+			}
+			var index int
+			if _, err = fmt.Sscanf(rcpts[0], "rcpt%d@example.com", &index); err != nil {
+				t.Fatalf("unexpected recipient: %s", rcpts[0])
+			}
+			if !bytes.Contains(received.Data, []byte(fmt.Sprintf("Hello %d", index))) {
+				t.Errorf("expected personalized body for %s, got: %s", rcpts[0], received.Data)
+			}
+		}
+	})
+	t.Run("render error is reported per recipient", func(t *testing.T) {
+		server, client := testMailMergeServer(t)
+		merge, err := NewMailMerge(testMessage(t), text, nil)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		invalid := func(yield func(MailMergeRecipient) bool) {
+			_ = yield(MailMergeRecipient{Address: "invalid"}) && yield(MailMergeRecipient{Address: TestRcptValid})
+		}
+		var errs int
+		for result := range merge.Send(t.Context(), client, invalid) {
+			if result.Err != nil {
+				errs++
+				if result.Msg != nil {
+					t.Error("expected no Msg for failed rendering")
+				}
+			}
+		}
+		if errs != 1 || len(server.Messages()) != 1 {
+			t.Errorf("expected 1 error and 1 message, got: %d errors, %d messages", errs, len(server.Messages()))
+		}
+	})
+	t.Run("stopping the iteration stops sending", func(t *testing.T) {
+		server, client := testMailMergeServer(t)
+		merge, err := NewMailMerge(testMessage(t), text, nil, WithMailMergeConcurrency(1))
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		for range merge.Send(t.Context(), client, recipients(100)) {
+			break
+		}
+		if count := len(server.Messages()); count > 3 {
+			t.Errorf("expected sending to stop, got: %d messages", count)
+		}
+	})
+	t.Run("files from a ReadSeeker are shared by concurrent copies", func(t *testing.T) {
+		server, client := testMailMergeServer(t)
+		base := testMessage(t)
+		base.AttachReadSeeker("attachment.txt", strings.NewReader("This is an attachment"))
+		base.EmbedReadSeeker("embed.txt", strings.NewReader("This is an embed"))
+		merge, err := NewMailMerge(base, text, nil, WithMailMergeConcurrency(4))
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		for result := range merge.Send(t.Context(), client, recipients(20)) {
+			if result.Err != nil {
+				t.Errorf("failed to send message to %s: %s", result.Recipient.Address, result.Err)
+			}
+		}
+		if len(server.Messages()) != 20 {
+			t.Fatalf("expected 20 messages on the test server, got: %d", len(server.Messages()))
+		}
+		attachment := base64.StdEncoding.EncodeToString([]byte("This is an attachment"))
+		embed := base64.StdEncoding.EncodeToString([]byte("This is an embed"))
+		for _, received := range server.Messages() {
+			if !bytes.Contains(received.Data, []byte(attachment)) || !bytes.Contains(received.Data, []byte(embed)) {
+				t.Errorf("expected attachment and embed in message, got: %s", received.Data)
+			}
+		}
+		if _, ok := base.attachments[0].getHeader(HeaderContentType); ok {
+			t.Error("expected MIME header of the base attachment to be unchanged")
+		}
+	})
+	t.Run("unreadable file fails", func(t *testing.T) {
+		server, client := testMailMergeServer(t)
+		base := testMessage(t)
+		base.AttachReadSeeker("attachment.txt", failReadWriteSeekCloser{})
+		merge, err := NewMailMerge(base, text, nil)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		var results []MailMergeResult
+		for result := range merge.Send(t.Context(), client, recipients(2)) {
+			results = append(results, result)
+		}
+		if len(results) != 1 || results[0].Err == nil {
+			t.Errorf("expected a single error result, got: %v", results)
+		}
+		if len(server.Messages()) != 0 {
+			t.Errorf("expected no messages on the test server, got: %d", len(server.Messages()))
+		}
+	})
+	t.Run("nil client fails", func(t *testing.T) {
+		merge, err := NewMailMerge(testMessage(t), text, nil)
+		if err != nil {
+			t.Fatalf("failed to create mail merge: %s", err)
+		}
+		var results []MailMergeResult
+		for result := range merge.Send(t.Context(), nil, recipients(2)) {
+			results = append(results, result)
+		}
+		if len(results) != 1 || results[0].Err == nil {
+			t.Errorf("expected a single error result, got: %v", results)
+		}
+	})
+}
+
+// testMailMergeServer starts a test server and returns it together with a Client for it.
+func testMailMergeServer(t *testing.T) (*smtptest.Server, *Client) {
+	t.Helper()
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return server, client
+}
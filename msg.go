@@ -41,6 +41,11 @@ var (
 	// without a usable HTTPS URL, which RFC 8058 requires.
 	ErrNoHTTPSUnsubURL = errors.New("one-click unsubscribe requires at least one HTTPS " +
 		"URI in List-Unsubscribe")
+
+	// ErrInvalidEnvelopeID is returned when a DSN envelope identifier contains characters other
+	// than printable US-ASCII characters or exceeds 100 characters.
+	ErrInvalidEnvelopeID = errors.New("DSN envelope ID must consist of at most 100 printable " +
+		"US-ASCII characters")
 )
 
 const (
@@ -159,6 +164,30 @@ type Msg struct {
 	// different Content-Type settings in the msgWriter.
 	pgptype PGPType
 
+	// requireTLS indicates that the Msg must only be transmitted over TLS protected connections, as
+	// described in RFC 8689.
+	requireTLS bool
+
+	// envelopeID holds the DSN envelope identifier of the Msg, as described in RFC 3461.
+	envelopeID string
+
+	// originalRcpts maps the envelope recipients of the Msg to their DSN original recipients, as
+	// described in RFC 3461.
+	originalRcpts map[string]string
+
+	// outboxMessage holds the already rendered message of a Msg restored from an Outbox. If set,
+	// the Client transmits it as is, instead of rendering the Msg.
+	outboxMessage *outboxMessage
+
+	// rcptResults holds the outcome of the delivery for each recipient, if provided by the server
+	rcptResults []RcptResult
+
+	// serverHost holds the hostname of the server that accepted the mail
+	serverHost string
+
+	// deliveryResult holds the DeliveryResult of the last delivery of the mail
+	deliveryResult *DeliveryResult
+
 	// serverResponse holds the response from the sending server after the mail has been
 	// successfully queued
 	serverResponse string
@@ -390,6 +419,26 @@ func WithNoDefaultUserAgent() MsgOption {
 	}
 }
 
+// WithRequireTLS marks the Msg as requiring TLS for its delivery during its creation or
+// initialization.
+//
+// This MsgOption function instructs the Client to only send the Msg if the connection to the
+// SMTP server is TLS protected and the server supports the REQUIRETLS extension. The REQUIRETLS
+// parameter is then added to the "MAIL FROM" command, which requests all subsequent mail servers
+// to relay the message over TLS protected connections only. If these requirements are not met,
+// the delivery fails with a SendError of reason ErrNoRequireTLS.
+//
+// Returns:
+//   - A MsgOption function that can be used to customize the Msg instance.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8689
+func WithRequireTLS() MsgOption {
+	return func(m *Msg) {
+		m.requireTLS = true
+	}
+}
+
 // SetCharset sets or overrides the currently set encoding charset of the Msg.
 //
 // This method allows you to specify a character set for the email message. The charset is
@@ -1487,6 +1536,131 @@ func (m *Msg) IsDelivered() bool {
 	return m.isDelivered
 }
 
+// RequiresTLS indicates whether the Msg requires TLS for its delivery.
+//
+// This method returns true if the Msg has been marked as requiring TLS with the WithRequireTLS
+// MsgOption or the SetRequireTLS method. Such a Msg is only sent over TLS protected connections
+// to servers that support the REQUIRETLS extension.
+//
+// Returns:
+//   - A boolean value indicating whether the Msg requires TLS for its delivery.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8689
+func (m *Msg) RequiresTLS() bool {
+	return m.requireTLS
+}
+
+// SetRequireTLS sets or unsets the REQUIRETLS requirement of the Msg.
+//
+// This method marks the Msg as requiring TLS for its delivery, as described for the WithRequireTLS
+// MsgOption. Passing false removes the requirement again.
+//
+// Parameters:
+//   - requireTLS: A boolean value indicating whether the Msg requires TLS for its delivery.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8689
+func (m *Msg) SetRequireTLS(requireTLS bool) {
+	m.requireTLS = requireTLS
+}
+
+// EnvelopeID returns the DSN envelope identifier of the Msg.
+//
+// Returns:
+//   - The envelope identifier set with SetEnvelopeID, or an empty string if none is set.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3461#section-4.4
+func (m *Msg) EnvelopeID() string {
+	return m.envelopeID
+}
+
+// SetEnvelopeID sets the DSN envelope identifier of the Msg.
+//
+// If the SMTP server supports the DSN extension, the envelope identifier is sent xtext encoded in
+// the ENVID parameter of the "MAIL FROM" command. The server includes it in any Delivery Status
+// Notification it issues for the Msg, which allows to correlate bounces with the original Msg.
+// Passing an empty string removes the envelope identifier again.
+//
+// Parameters:
+//   - id: The envelope identifier, consisting of at most 100 printable US-ASCII characters.
+//
+// Returns:
+//   - ErrInvalidEnvelopeID if the envelope identifier is invalid; otherwise, returns nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3461#section-4.4
+func (m *Msg) SetEnvelopeID(id string) error {
+	if len(id) > 100 {
+		return ErrInvalidEnvelopeID
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return ErrInvalidEnvelopeID
+		}
+	}
+	m.envelopeID = id
+	return nil
+}
+
+// OriginalRcpt returns the DSN original recipient that has been set for the given recipient.
+//
+// Parameters:
+//   - rcpt: The recipient address.
+//
+// Returns:
+//   - The original recipient address, or an empty string if none is set or the recipient address
+//     cannot be parsed.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3461#section-4.2
+func (m *Msg) OriginalRcpt(rcpt string) string {
+	address, err := mail.ParseAddress(rcpt)
+	if err != nil {
+		return ""
+	}
+	return m.originalRcpts[mailAddressStringWithoutName(*address)]
+}
+
+// SetOriginalRcpt sets the DSN original recipient for a recipient of the Msg.
+//
+// If the SMTP server supports the DSN extension, the original recipient is sent xtext encoded in
+// the ORCPT parameter of the "RCPT TO" command for the recipient, using the "rfc822" address type.
+// The server includes it in any Delivery Status Notification it issues for the recipient, which
+// is useful if the recipient address has been rewritten, for example by an alias expansion.
+// Passing an empty original recipient removes it again.
+//
+// Parameters:
+//   - rcpt: The recipient address, as set in the "To", "Cc" or "Bcc" header.
+//   - original: The original recipient address.
+//
+// Returns:
+//   - An error if any of the addresses cannot be parsed; otherwise, returns nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3461#section-4.2
+func (m *Msg) SetOriginalRcpt(rcpt, original string) error {
+	address, err := mail.ParseAddress(rcpt)
+	if err != nil {
+		return fmt.Errorf(errParseMailAddr, rcpt, err)
+	}
+	key := mailAddressStringWithoutName(*address)
+	if original == "" {
+		delete(m.originalRcpts, key)
+		return nil
+	}
+	originalAddress, err := mail.ParseAddress(original)
+	if err != nil {
+		return fmt.Errorf(errParseMailAddr, original, err)
+	}
+	if m.originalRcpts == nil {
+		m.originalRcpts = make(map[string]string)
+	}
+	m.originalRcpts[key] = originalAddress.Address
+	return nil
+}
+
 // RequestMDNTo adds the "Disposition-Notification-To" header to the Msg to request a Message Disposition
 // Notification (MDN) from the receiving end, as specified in RFC 8098.
 //
@@ -1865,6 +2039,56 @@ func (m *Msg) GetBoundary() string {
 	return m.boundary
 }
 
+// RcptResults returns the outcome of the delivery for each recipient of the mail.
+//
+// The results hold the replies of the server for the RCPT commands of the recipients. In LMTP mode
+// (see WithLMTP), the replies of the server after the message data replace them for the accepted
+// recipients.
+//
+// Returns:
+//   - A slice of RcptResult, one for each recipient, or nil if the Msg has not been sent.
+func (m *Msg) RcptResults() []RcptResult {
+	return m.rcptResults
+}
+
+// setRcptResult stores the RcptResult for a recipient, replacing an existing RcptResult for the
+// same recipient.
+//
+// Parameters:
+//   - result: The RcptResult to store.
+func (m *Msg) setRcptResult(result RcptResult) {
+	for i := range m.rcptResults {
+		if m.rcptResults[i].Rcpt == result.Rcpt {
+			m.rcptResults[i] = result
+			return
+		}
+	}
+	m.rcptResults = append(m.rcptResults, result)
+}
+
+// DeliveryResult returns the structured result of the delivery of the mail.
+//
+// The DeliveryResult is available once the server accepted the message data, even if the delivery
+// failed for some of the recipients. It can be stored as evidence of the delivery.
+//
+// Returns:
+//   - A pointer to the DeliveryResult, or nil if the mail has not been delivered.
+func (m *Msg) DeliveryResult() *DeliveryResult {
+	return m.deliveryResult
+}
+
+// ServerHost returns the hostname of the server that accepted the mail.
+//
+// If the Client is configured with fallback hosts via WithFallbackHosts, this function can be used
+// to determine which of the relay hosts finally accepted the message.
+//
+// Returns:
+//   - The hostname of the server that accepted the mail, or an empty string if the mail has not
+//     been delivered.
+func (m *Msg) ServerHost() string {
+	return m.serverHost
+}
+
 // ServerResponse returns the server's response after queuing the mail.
 //
 // This function retrieves the value of m.serverResponse, which typically contains information
@@ -2347,6 +2571,28 @@ func (m *Msg) WriteTo(w io.Writer) (int64, error) {
 	return total + int64(n2), err
 }
 
+// Size returns the size of the formatted Msg in bytes.
+//
+// This method renders the Msg, including all middlewares, as well as DKIM and S/MIME signing if
+// configured, into an io.Discard writer and returns the number of bytes written. It allows to check
+// the size of the Msg against the maximum message size of a mail server, without sending it.
+//
+// Since the complete Msg is rendered, calling Size is as expensive as writing the Msg, including
+// the encoding of all attachments and the signing. The returned size is the size of the rendered
+// Msg, before bare LF line endings are converted into CRLF during transmission. The Client does
+// not use this method for the SIZE extension, but determines the size while rendering the Msg for
+// the transmission.
+//
+// Returns:
+//   - The size of the formatted Msg in bytes.
+//   - An error if the Msg could not be rendered; otherwise, returns nil.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc1870
+func (m *Msg) Size() (int64, error) {
+	return m.WriteTo(io.Discard)
+}
+
 // WriteToSkipMiddleware writes the formatted Msg into the given io.Writer, but skips the specified
 // middleware type.
 //
@@ -2820,6 +3066,31 @@ func (m *Msg) hasRelated() bool {
 	return m.pgptype == 0 && ((len(m.parts) > 0 && len(m.embeds) > 0) || len(m.embeds) > 1)
 }
 
+// hasUnencodedFiles returns true if any of the attachments or embeds of the Msg is configured to
+// be transmitted without any encoding.
+//
+// Files with NoEncoding may contain binary data, which can only be transmitted unaltered if the
+// server supports the BINARYMIME extension.
+//
+// Returns:
+//   - A boolean value indicating whether the message has unencoded files.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3030#section-3
+func (m *Msg) hasUnencodedFiles() bool {
+	if m.outboxMessage != nil {
+		return m.outboxMessage.unencodedFiles
+	}
+	for _, files := range [][]*File{m.attachments, m.embeds} {
+		for _, file := range files {
+			if file != nil && file.Enc == NoEncoding {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // hasPGPType returns true if the Msg should be treated as a PGP-encoded message.
 //
 // This method checks whether the message is configured to be treated as a PGP-encoded message by examining
@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	ttpl "text/template"
 	"time"
@@ -299,6 +300,16 @@ func TestNewMsg(t *testing.T) {
 				message.noDefaultUserAgent)
 		}
 	})
+	t.Run("new message with REQUIRETLS", func(t *testing.T) {
+		message := NewMsg(WithRequireTLS())
+		if message == nil {
+			t.Fatal("message is nil")
+		}
+		if !message.requireTLS {
+			t.Errorf("NewMsg(WithRequireTLS()) failed. Expected requireTLS to be true, got: %t",
+				message.requireTLS)
+		}
+	})
 	t.Run("new message with DKIM enabled", func(t *testing.T) {
 		privKey, err := PrivKeyFromPEM(testKeyEd25519)
 		if err != nil {
@@ -2291,6 +2302,94 @@ func TestMsg_SetUserAgent(t *testing.T) {
 	})
 }
 
+func TestMsg_SetEnvelopeID(t *testing.T) {
+	t.Run("SetEnvelopeID with valid ID", func(t *testing.T) {
+		message := testMessage(t)
+		if err := message.SetEnvelopeID("QQ314159"); err != nil {
+			t.Fatalf("failed to set envelope ID: %s", err)
+		}
+		if message.EnvelopeID() != "QQ314159" {
+			t.Errorf("expected envelope ID to be %q, got: %q", "QQ314159", message.EnvelopeID())
+		}
+		if err := message.SetEnvelopeID(""); err != nil {
+			t.Fatalf("failed to remove envelope ID: %s", err)
+		}
+		if message.EnvelopeID() != "" {
+			t.Errorf("expected envelope ID to be removed, got: %q", message.EnvelopeID())
+		}
+	})
+	t.Run("SetEnvelopeID with invalid IDs", func(t *testing.T) {
+		for _, id := range []string{"t\u00e4st", "line\r\nbreak", strings.Repeat("a", 101)} {
+			message := testMessage(t)
+			if err := message.SetEnvelopeID(id); !errors.Is(err, ErrInvalidEnvelopeID) {
+				t.Errorf("expected ErrInvalidEnvelopeID for %q, got: %s", id, err)
+			}
+			if message.EnvelopeID() != "" {
+				t.Errorf("expected no envelope ID, got: %q", message.EnvelopeID())
+			}
+		}
+	})
+}
+
+func TestMsg_SetOriginalRcpt(t *testing.T) {
+	t.Run("SetOriginalRcpt with valid addresses", func(t *testing.T) {
+		message := testMessage(t)
+		if err := message.SetOriginalRcpt("Toni Tester <toni.tester@example.com>", "toni@example.org"); err != nil {
+			t.Fatalf("failed to set original recipient: %s", err)
+		}
+		if orcpt := message.OriginalRcpt("toni.tester@example.com"); orcpt != "toni@example.org" {
+			t.Errorf("expected original recipient to be %q, got: %q", "toni@example.org", orcpt)
+		}
+		if orcpt := message.originalRcpts["<toni.tester@example.com>"]; orcpt != "toni@example.org" {
+			t.Errorf("expected original recipient for envelope recipient, got: %q", orcpt)
+		}
+		if err := message.SetOriginalRcpt("toni.tester@example.com", ""); err != nil {
+			t.Fatalf("failed to remove original recipient: %s", err)
+		}
+		if orcpt := message.OriginalRcpt("toni.tester@example.com"); orcpt != "" {
+			t.Errorf("expected original recipient to be removed, got: %q", orcpt)
+		}
+	})
+	t.Run("SetOriginalRcpt with invalid addresses", func(t *testing.T) {
+		message := testMessage(t)
+		if err := message.SetOriginalRcpt("invalid", "toni@example.org"); err == nil {
+			t.Error("expected error for invalid recipient address")
+		}
+		if err := message.SetOriginalRcpt("toni.tester@example.com", "invalid"); err == nil {
+			t.Error("expected error for invalid original recipient address")
+		}
+		if orcpt := message.OriginalRcpt("invalid"); orcpt != "" {
+			t.Errorf("expected no original recipient, got: %q", orcpt)
+		}
+	})
+}
+
+func TestMsg_RequiresTLS(t *testing.T) {
+	t.Run("RequiresTLS on default message", func(t *testing.T) {
+		message := testMessage(t)
+		if message.RequiresTLS() {
+			t.Error("RequiresTLS on default message should return false")
+		}
+	})
+	t.Run("RequiresTLS with WithRequireTLS", func(t *testing.T) {
+		message := testMessage(t, WithRequireTLS())
+		if !message.RequiresTLS() {
+			t.Error("RequiresTLS on message with WithRequireTLS should return true")
+		}
+	})
+	t.Run("SetRequireTLS sets and unsets the requirement", func(t *testing.T) {
+		message := testMessage(t)
+		message.SetRequireTLS(true)
+		if !message.RequiresTLS() {
+			t.Error("RequiresTLS after SetRequireTLS(true) should return true")
+		}
+		message.SetRequireTLS(false)
+		if message.RequiresTLS() {
+			t.Error("RequiresTLS after SetRequireTLS(false) should return false")
+		}
+	})
+}
+
 func TestMsg_IsDelivered(t *testing.T) {
 	t.Run("IsDelivered on unsent message", func(t *testing.T) {
 		message := testMessage(t)
@@ -8170,6 +8269,45 @@ func TestMsg_Write(t *testing.T) {
 	t.Log("Write() is just an alias to WriteTo(), which has already been tested.")
 }
 
+func TestMsg_Size(t *testing.T) {
+	t.Run("Size matches the length of the formatted message", func(t *testing.T) {
+		message := testMessage(t)
+		size, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+		buffer := bytes.NewBuffer(nil)
+		if _, err = message.WriteTo(buffer); err != nil {
+			t.Fatalf("failed to write message to buffer: %s", err)
+		}
+		if size != int64(buffer.Len()) {
+			t.Errorf("expected message size to be %d, got: %d", buffer.Len(), size)
+		}
+	})
+	t.Run("Size includes attachments", func(t *testing.T) {
+		message := testMessage(t)
+		before, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+		message.AttachFile("testdata/attachment.txt")
+		after, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+		if after <= before {
+			t.Errorf("expected message size with attachment to be larger than %d, got: %d", before, after)
+		}
+		again, err := message.Size()
+		if err != nil {
+			t.Fatalf("failed to get message size: %s", err)
+		}
+		if again != after {
+			t.Errorf("expected message size to be stable, got: %d and %d", after, again)
+		}
+	})
+}
+
 func TestMsg_WriteToSkipMiddleware(t *testing.T) {
 	t.Run("WriteToSkipMiddleware with two middlewares, skipping uppercase", func(t *testing.T) {
 		message := NewMsg(WithMiddleware(encodeMiddleware{}), WithMiddleware(uppercaseMiddleware{}))
@@ -8842,6 +8980,36 @@ func TestMsg_hasRelated(t *testing.T) {
 	})
 }
 
+func TestMsg_hasUnencodedFiles(t *testing.T) {
+	t.Run("message has no files", func(t *testing.T) {
+		message := testMessage(t)
+		if message.hasUnencodedFiles() {
+			t.Error("message has no files, but hasUnencodedFiles returned true")
+		}
+	})
+	t.Run("message with encoded attachment", func(t *testing.T) {
+		message := testMessage(t)
+		message.AttachFile("testdata/attachment.txt")
+		if message.hasUnencodedFiles() {
+			t.Error("message has encoded attachment, but hasUnencodedFiles returned true")
+		}
+	})
+	t.Run("message with unencoded attachment", func(t *testing.T) {
+		message := testMessage(t)
+		message.AttachFile("testdata/attachment.txt", WithFileEncoding(NoEncoding))
+		if !message.hasUnencodedFiles() {
+			t.Error("message has unencoded attachment, but hasUnencodedFiles returned false")
+		}
+	})
+	t.Run("message with unencoded embed", func(t *testing.T) {
+		message := testMessage(t)
+		message.EmbedFile("testdata/embed.txt", WithFileEncoding(NoEncoding))
+		if !message.hasUnencodedFiles() {
+			t.Error("message has unencoded embed, but hasUnencodedFiles returned false")
+		}
+	})
+}
+
 func TestMsg_hasPGPType(t *testing.T) {
 	t.Run("message has no pgpType", func(t *testing.T) {
 		message := testMessage(t)
@@ -9282,6 +9450,22 @@ func (mw encodeMiddleware) Type() MiddlewareType {
 	return "encode"
 }
 
+// countingMiddleware is a middleware type that counts how often the Msg has been rendered.
+type countingMiddleware struct {
+	count *atomic.Int32
+}
+
+// Handle satisfies the Middleware interface for the countingMiddleware
+func (mw countingMiddleware) Handle(m *Msg) *Msg {
+	mw.count.Add(1)
+	return m
+}
+
+// Type satisfies the Middleware interface for the countingMiddleware
+func (mw countingMiddleware) Type() MiddlewareType {
+	return "counting"
+}
+
 // failReadWriteSeekCloser is a type that always returns an error. It satisfies the io.Reader, io.Writer
 // io.Closer, io.Seeker, io.WriteSeeker, io.ReadSeeker, io.ReadCloser and io.WriteCloser interfaces
 type failReadWriteSeekCloser struct{}
@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MTASTSMode is a type wrapper for a string and represents the mode of an MTA-STS policy.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-5
+type MTASTSMode string
+
+const (
+	// MTASTSModeEnforce requires that the connection to the mail server is TLS protected and that
+	// the mail server hostname matches one of the MX patterns of the policy.
+	MTASTSModeEnforce MTASTSMode = "enforce"
+
+	// MTASTSModeTesting indicates that the policy is only tested. Policy failures do not prevent
+	// the delivery.
+	MTASTSModeTesting MTASTSMode = "testing"
+
+	// MTASTSModeNone indicates that the domain does not have an active MTA-STS policy.
+	MTASTSModeNone MTASTSMode = "none"
+)
+
+const (
+	// mtastsMaxPolicySize is the maximum size of an MTA-STS policy file we are willing to read.
+	mtastsMaxPolicySize = 64 * 1024
+
+	// mtastsMaxAge is the maximum max_age of an MTA-STS policy as defined in RFC 8461.
+	mtastsMaxAge = 31557600 * time.Second
+)
+
+var (
+	// ErrNoMTASTSPolicy is returned by an MTASTSResolver if the domain does not publish an MTA-STS policy.
+	ErrNoMTASTSPolicy = errors.New("domain does not publish an MTA-STS policy")
+
+	// ErrInvalidMTASTSPolicy is returned if an MTA-STS policy could not be parsed or is invalid.
+	ErrInvalidMTASTSPolicy = errors.New("invalid MTA-STS policy")
+
+	// ErrMTASTSNoDomain is returned if the TLSMTASTS policy is used without a policy domain.
+	ErrMTASTSNoDomain = errors.New("MTA-STS policy domain must not be empty")
+
+	// ErrMTASTSResolverIsNil is returned if a nil MTASTSResolver is provided to the Client.
+	ErrMTASTSResolverIsNil = errors.New("MTA-STS resolver must not be nil")
+
+	// ErrMTASTSHostMismatch is returned if the hostname of the mail server does not match any of the
+	// MX patterns of an enforced MTA-STS policy.
+	ErrMTASTSHostMismatch = errors.New("mail server hostname does not match the MTA-STS policy")
+
+	// ErrMTASTSNoStartTLS is returned if an enforced MTA-STS policy is in place, but the mail server
+	// does not support STARTTLS.
+	ErrMTASTSNoStartTLS = errors.New("MTA-STS policy is enforced, but mail server does not support STARTTLS")
+)
+
+// MTASTSPolicy represents an MTA-STS policy of a domain, as described in RFC 8461.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3.2
+type MTASTSPolicy struct {
+	// Mode is the MTASTSMode of the policy.
+	Mode MTASTSMode
+
+	// MX holds the MX host patterns of the policy. A pattern is either a fully qualified hostname
+	// or a wildcard pattern like "*.example.com", which matches exactly one additional label.
+	MX []string
+
+	// MaxAge is the maximum lifetime of the policy, for which it may be cached.
+	MaxAge time.Duration
+}
+
+// MTASTSResolver is an interface for types that resolve the MTA-STS policy of a domain.
+//
+// Implementations should return ErrNoMTASTSPolicy, if the domain does not publish an MTA-STS
+// policy. The Client caches the resolved policies for their MaxAge. A custom MTASTSResolver
+// can be set on the Client with WithMTASTSResolver.
+type MTASTSResolver interface {
+	// ResolveMTASTSPolicy resolves the MTA-STS policy of the given domain.
+	ResolveMTASTSPolicy(ctx context.Context, domain string) (*MTASTSPolicy, error)
+}
+
+// MTASTSHTTPResolver is the default MTASTSResolver of the Client. It looks up the "_mta-sts" TXT
+// record of the domain and fetches the policy from the well-known HTTPS URL of the policy host.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3
+type MTASTSHTTPResolver struct {
+	// HTTPClient is the http.Client that is used to fetch the policy. If nil, a http.Client with a
+	// timeout of DefaultTimeout is used. Redirects are never followed, as required by RFC 8461.
+	HTTPClient *http.Client
+
+	// LookupTXT is the function that is used to look up the "_mta-sts" TXT record of the domain. If
+	// nil, net.DefaultResolver.LookupTXT is used.
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// MTASTSPolicyCache is a MTASTSResolver that caches the policies of another MTASTSResolver for
+// their MaxAge. Expired policies are kept as a fallback, in case they cannot be refreshed.
+//
+// The MTASTSPolicyCache is safe for concurrent use. A single MTASTSPolicyCache can be shared
+// between several Clients by passing it to WithMTASTSResolver.
+type MTASTSPolicyCache struct {
+	mutex    sync.Mutex
+	now      func() time.Time
+	policies map[string]mtastsCacheEntry
+	resolver MTASTSResolver
+}
+
+// mtastsCacheEntry is a cached MTA-STS policy alongside its expiration time.
+type mtastsCacheEntry struct {
+	expires time.Time
+	policy  *MTASTSPolicy
+}
+
+// WithMTASTS enables the TLSMTASTS policy for the Client, using the MTA-STS policy of the given
+// domain.
+//
+// The domain is the policy domain, usually the domain of the recipients the Client delivers to.
+// When dialing, the Client resolves the MTA-STS policy of the domain. If the policy is enforced,
+// the hostname of the Client must match one of the MX patterns of the policy and the connection
+// must be TLS protected via STARTTLS or implicit SSL/TLS. Otherwise, the Client falls back to
+// TLSOpportunistic.
+//
+// Parameters:
+//   - domain: The MTA-STS policy domain.
+//
+// Returns:
+//   - An Option function that enables the TLSMTASTS policy for the Client.
+//   - An error if the domain is empty.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461
+func WithMTASTS(domain string) Option {
+	return func(c *Client) error {
+		if domain == "" {
+			return ErrMTASTSNoDomain
+		}
+		c.mtastsDomain = domain
+		c.tlspolicy = TLSMTASTS
+		return nil
+	}
+}
+
+// WithMTASTSResolver sets the MTASTSResolver that is used by the Client to resolve the MTA-STS
+// policy of the domain set with WithMTASTS.
+//
+// The resolved policies are cached for their MaxAge. If the provided resolver is a
+// MTASTSPolicyCache, it is used as is, which allows to share the cache between Clients.
+// Otherwise, the resolver is wrapped into a new MTASTSPolicyCache.
+//
+// Parameters:
+//   - resolver: The MTASTSResolver to resolve the MTA-STS policies.
+//
+// Returns:
+//   - An Option function that sets the MTASTSResolver for the Client.
+//   - An error if the resolver is nil.
+func WithMTASTSResolver(resolver MTASTSResolver) Option {
+	return func(c *Client) error {
+		if resolver == nil {
+			return ErrMTASTSResolverIsNil
+		}
+		if cache, ok := resolver.(*MTASTSPolicyCache); ok {
+			c.mtastsCache = cache
+			return nil
+		}
+		c.mtastsCache = NewMTASTSPolicyCache(resolver)
+		return nil
+	}
+}
+
+// NewMTASTSPolicyCache returns a new MTASTSPolicyCache for the given MTASTSResolver.
+//
+// Parameters:
+//   - resolver: The MTASTSResolver to resolve policies that are not cached yet or have expired.
+//
+// Returns:
+//   - A pointer to the new MTASTSPolicyCache.
+func NewMTASTSPolicyCache(resolver MTASTSResolver) *MTASTSPolicyCache {
+	return &MTASTSPolicyCache{
+		now:      time.Now,
+		policies: make(map[string]mtastsCacheEntry),
+		resolver: resolver,
+	}
+}
+
+// ResolveMTASTSPolicy satisfies the MTASTSResolver interface for the MTASTSPolicyCache.
+//
+// A cached policy is returned as long as its MaxAge has not expired. Otherwise, the policy is
+// resolved with the underlying MTASTSResolver and added to the cache. Errors of the underlying
+// resolver are not cached.
+//
+// If an expired policy cannot be refreshed, because the DNS lookup or the fetching of the policy
+// failed, the expired policy is returned instead of the error, so that a temporary failure or an
+// attacker blocking the policy fetch does not downgrade the delivery. The policy is resolved again
+// on the next call. If the domain no longer publishes a policy, i.e. ErrNoMTASTSPolicy is
+// returned, the expired policy is removed from the cache.
+//
+// Parameters:
+//   - ctx: The context.Context for the resolving of the policy.
+//   - domain: The MTA-STS policy domain.
+//
+// Returns:
+//   - A pointer to the MTASTSPolicy of the domain.
+//   - An error if the policy could not be resolved and no expired policy is cached.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-5.1
+func (p *MTASTSPolicyCache) ResolveMTASTSPolicy(ctx context.Context, domain string) (*MTASTSPolicy, error) {
+	domain = normalizeMTASTSHost(domain)
+	p.mutex.Lock()
+	entry, ok := p.policies[domain]
+	p.mutex.Unlock()
+	if ok && p.now().Before(entry.expires) {
+		return entry.policy, nil
+	}
+
+	policy, err := p.resolver.ResolveMTASTSPolicy(ctx, domain)
+	switch {
+	case errors.Is(err, ErrNoMTASTSPolicy):
+		p.mutex.Lock()
+		delete(p.policies, domain)
+		p.mutex.Unlock()
+		return nil, err
+	case err != nil && ok:
+		return entry.policy, nil
+	case err != nil:
+		return nil, err
+	}
+	p.mutex.Lock()
+	p.policies[domain] = mtastsCacheEntry{expires: p.now().Add(policy.MaxAge), policy: policy}
+	p.mutex.Unlock()
+	return policy, nil
+}
+
+// ResolveMTASTSPolicy satisfies the MTASTSResolver interface for the MTASTSHTTPResolver.
+//
+// It looks up the "_mta-sts" TXT record of the domain to check if the domain publishes an
+// MTA-STS policy and then fetches the policy from "https://mta-sts.<domain>/.well-known/mta-sts.txt".
+//
+// Parameters:
+//   - ctx: The context.Context for the DNS lookup and the HTTP request.
+//   - domain: The MTA-STS policy domain.
+//
+// Returns:
+//   - A pointer to the MTASTSPolicy of the domain.
+//   - ErrNoMTASTSPolicy if the domain does not publish a policy, or another error if the policy
+//     could not be fetched or parsed.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3.1
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3.3
+func (r *MTASTSHTTPResolver) ResolveMTASTSPolicy(ctx context.Context, domain string) (*MTASTSPolicy, error) {
+	lookupTXT := r.LookupTXT
+	if lookupTXT == nil {
+		lookupTXT = net.DefaultResolver.LookupTXT
+	}
+	records, err := lookupTXT(ctx, "_mta-sts."+domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, ErrNoMTASTSPolicy
+		}
+		return nil, fmt.Errorf("failed to look up MTA-STS TXT record: %w", err)
+	}
+	if !hasMTASTSRecord(records) {
+		return nil, ErrNoMTASTSPolicy
+	}
+
+	httpClient := &http.Client{Timeout: DefaultTimeout}
+	if r.HTTPClient != nil {
+		clientCopy := *r.HTTPClient
+		httpClient = &clientCopy
+	}
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	policyURL := "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, policyURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MTA-STS policy request: %w", err)
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch MTA-STS policy: %w", err)
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch MTA-STS policy: unexpected HTTP status %q", response.Status)
+	}
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil || !strings.EqualFold(mediaType, "text/plain") {
+		return nil, fmt.Errorf("%w: unexpected content type %q", ErrInvalidMTASTSPolicy,
+			response.Header.Get("Content-Type"))
+	}
+	return ParseMTASTSPolicy(io.LimitReader(response.Body, mtastsMaxPolicySize))
+}
+
+// ParseMTASTSPolicy parses an MTA-STS policy file from the given io.Reader.
+//
+// Parameters:
+//   - reader: The io.Reader to read the policy file from.
+//
+// Returns:
+//   - A pointer to the parsed MTASTSPolicy.
+//   - An error wrapping ErrInvalidMTASTSPolicy if the policy is invalid.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3.2
+func ParseMTASTSPolicy(reader io.Reader) (*MTASTSPolicy, error) {
+	policy := &MTASTSPolicy{}
+	var version string
+	hasMaxAge := false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid line %q", ErrInvalidMTASTSPolicy, line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			policy.Mode = MTASTSMode(value)
+		case "mx":
+			policy.MX = append(policy.MX, value)
+		case "max_age":
+			maxAge, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || maxAge < 0 {
+				return nil, fmt.Errorf("%w: invalid max_age %q", ErrInvalidMTASTSPolicy, value)
+			}
+			policy.MaxAge = min(time.Duration(maxAge)*time.Second, mtastsMaxAge)
+			hasMaxAge = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MTA-STS policy: %w", err)
+	}
+
+	if version != "STSv1" {
+		return nil, fmt.Errorf("%w: unsupported version %q", ErrInvalidMTASTSPolicy, version)
+	}
+	switch policy.Mode {
+	case MTASTSModeEnforce, MTASTSModeTesting:
+		if len(policy.MX) == 0 {
+			return nil, fmt.Errorf("%w: missing mx patterns", ErrInvalidMTASTSPolicy)
+		}
+	case MTASTSModeNone:
+	default:
+		return nil, fmt.Errorf("%w: invalid mode %q", ErrInvalidMTASTSPolicy, policy.Mode)
+	}
+	if !hasMaxAge {
+		return nil, fmt.Errorf("%w: missing max_age", ErrInvalidMTASTSPolicy)
+	}
+	return policy, nil
+}
+
+// MatchesMX reports whether the given mail server hostname matches any of the MX patterns of
+// the MTASTSPolicy.
+//
+// The comparison is case-insensitive. A wildcard pattern like "*.example.com" matches exactly
+// one additional label, i. e. "mx.example.com", but neither "example.com" nor "a.mx.example.com".
+//
+// Parameters:
+//   - host: The hostname of the mail server.
+//
+// Returns:
+//   - true if the hostname matches any of the MX patterns, false otherwise.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-4.1
+func (p *MTASTSPolicy) MatchesMX(host string) bool {
+	host = normalizeMTASTSHost(host)
+	for _, pattern := range p.MX {
+		pattern = normalizeMTASTSHost(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// mtastsPolicy resolves the MTA-STS policy of the Client's policy domain and returns the effective
+// TLSPolicy for the connection to the given host.
+//
+// If the policy is enforced, the host is checked against the MX patterns of the policy and
+// TLSMandatory is returned. If the domain does not publish a policy, or the policy is not enforced,
+// TLSOpportunistic is returned.
+//
+// Parameters:
+//   - ctx: The context.Context for the resolving of the policy.
+//   - host: The hostname of the server the Client connects to.
+//
+// Returns:
+//   - The effective TLSPolicy for the connection.
+//   - An error if the policy could not be resolved or the hostname does not match the policy.
+func (c *Client) mtastsPolicy(ctx context.Context, host string) (TLSPolicy, error) {
+	if c.mtastsDomain == "" {
+		return c.tlspolicy, ErrMTASTSNoDomain
+	}
+	cache := c.mtastsCache
+	if cache == nil {
+		cache = defaultMTASTSCache
+	}
+	policy, err := cache.ResolveMTASTSPolicy(ctx, c.mtastsDomain)
+	if errors.Is(err, ErrNoMTASTSPolicy) {
+		return TLSOpportunistic, nil
+	}
+	if err != nil {
+		return c.tlspolicy, fmt.Errorf("failed to resolve MTA-STS policy: %w", err)
+	}
+	if policy.Mode != MTASTSModeEnforce {
+		return TLSOpportunistic, nil
+	}
+	if !policy.MatchesMX(host) {
+		return c.tlspolicy, fmt.Errorf("%w: %s", ErrMTASTSHostMismatch, host)
+	}
+	return TLSMandatory, nil
+}
+
+// defaultMTASTSCache is the MTASTSPolicyCache that is used by Clients without a custom MTASTSResolver.
+var defaultMTASTSCache = NewMTASTSPolicyCache(&MTASTSHTTPResolver{})
+
+// hasMTASTSRecord reports whether exactly one of the given TXT records is a MTA-STS record. As
+// required by RFC 8461, multiple MTA-STS records are treated as if no record was published.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc8461#section-3.1
+func hasMTASTSRecord(records []string) bool {
+	count := 0
+	for _, record := range records {
+		version, _, _ := strings.Cut(record, ";")
+		if strings.TrimSpace(version) == "v=STSv1" {
+			count++
+		}
+	}
+	return count == 1
+}
+
+// normalizeMTASTSHost returns the lower-cased hostname without a trailing dot.
+func normalizeMTASTSHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
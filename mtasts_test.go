@@ -0,0 +1,464 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testMTASTSPolicy = "version: STSv1\r\nmode: enforce\r\nmx: mail.example.com\r\nmx: *.example.net\r\n" +
+	"max_age: 86400\r\n"
+
+func TestWithMTASTS(t *testing.T) {
+	t.Run("MTA-STS policy domain is set", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithMTASTS("example.com"))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.tlspolicy != TLSMTASTS {
+			t.Errorf("expected TLS policy to be %s, got: %s", TLSMTASTS, client.tlspolicy)
+		}
+		if client.mtastsDomain != "example.com" {
+			t.Errorf("expected MTA-STS domain to be %q, got: %q", "example.com", client.mtastsDomain)
+		}
+	})
+	t.Run("empty MTA-STS policy domain fails", func(t *testing.T) {
+		_, err := NewClient(DefaultHost, WithMTASTS(""))
+		if !errors.Is(err, ErrMTASTSNoDomain) {
+			t.Errorf("expected error to be %s, got: %s", ErrMTASTSNoDomain, err)
+		}
+	})
+}
+
+func TestWithMTASTSResolver(t *testing.T) {
+	t.Run("resolver is wrapped into a cache", func(t *testing.T) {
+		resolver := &testMTASTSResolver{}
+		client, err := NewClient(DefaultHost, WithMTASTSResolver(resolver))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.mtastsCache == nil {
+			t.Fatal("expected MTA-STS cache to be set")
+		}
+		if client.mtastsCache.resolver != resolver {
+			t.Error("expected MTA-STS cache to use the provided resolver")
+		}
+	})
+	t.Run("cache is used as is", func(t *testing.T) {
+		cache := NewMTASTSPolicyCache(&testMTASTSResolver{})
+		client, err := NewClient(DefaultHost, WithMTASTSResolver(cache))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.mtastsCache != cache {
+			t.Error("expected MTA-STS cache to be the provided cache")
+		}
+	})
+	t.Run("nil resolver fails", func(t *testing.T) {
+		_, err := NewClient(DefaultHost, WithMTASTSResolver(nil))
+		if !errors.Is(err, ErrMTASTSResolverIsNil) {
+			t.Errorf("expected error to be %s, got: %s", ErrMTASTSResolverIsNil, err)
+		}
+	})
+}
+
+func TestParseMTASTSPolicy(t *testing.T) {
+	t.Run("valid policy is parsed", func(t *testing.T) {
+		policy, err := ParseMTASTSPolicy(strings.NewReader(testMTASTSPolicy))
+		if err != nil {
+			t.Fatalf("failed to parse policy: %s", err)
+		}
+		if policy.Mode != MTASTSModeEnforce {
+			t.Errorf("expected mode to be %s, got: %s", MTASTSModeEnforce, policy.Mode)
+		}
+		if len(policy.MX) != 2 || policy.MX[0] != "mail.example.com" || policy.MX[1] != "*.example.net" {
+			t.Errorf("unexpected mx patterns: %v", policy.MX)
+		}
+		if policy.MaxAge != time.Hour*24 {
+			t.Errorf("expected max age to be %s, got: %s", time.Hour*24, policy.MaxAge)
+		}
+	})
+	t.Run("max_age is capped", func(t *testing.T) {
+		policy, err := ParseMTASTSPolicy(strings.NewReader("version: STSv1\nmode: none\nmax_age: 99999999999\n"))
+		if err != nil {
+			t.Fatalf("failed to parse policy: %s", err)
+		}
+		if policy.MaxAge != mtastsMaxAge {
+			t.Errorf("expected max age to be %s, got: %s", mtastsMaxAge, policy.MaxAge)
+		}
+	})
+	t.Run("invalid policies fail", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			policy string
+		}{
+			{"empty policy", ""},
+			{"invalid version", "version: STSv2\nmode: enforce\nmx: mail.example.com\nmax_age: 86400\n"},
+			{"missing version", "mode: enforce\nmx: mail.example.com\nmax_age: 86400\n"},
+			{"invalid mode", "version: STSv1\nmode: strict\nmx: mail.example.com\nmax_age: 86400\n"},
+			{"missing mx", "version: STSv1\nmode: enforce\nmax_age: 86400\n"},
+			{"missing max_age", "version: STSv1\nmode: enforce\nmx: mail.example.com\n"},
+			{"invalid max_age", "version: STSv1\nmode: enforce\nmx: mail.example.com\nmax_age: forever\n"},
+			{"invalid line", "version: STSv1\nmode enforce\n"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := ParseMTASTSPolicy(strings.NewReader(tt.policy))
+				if !errors.Is(err, ErrInvalidMTASTSPolicy) {
+					t.Errorf("expected error to be %s, got: %s", ErrInvalidMTASTSPolicy, err)
+				}
+			})
+		}
+	})
+}
+
+func TestMTASTSPolicy_MatchesMX(t *testing.T) {
+	policy := &MTASTSPolicy{MX: []string{"mail.example.com", "*.example.net"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"mail.example.com", true},
+		{"MAIL.Example.COM.", true},
+		{"mx.example.com", false},
+		{"mx.example.net", true},
+		{"example.net", false},
+		{"a.mx.example.net", false},
+		{".example.net", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := policy.MatchesMX(tt.host); got != tt.want {
+				t.Errorf("expected MatchesMX(%q) to be %t, got: %t", tt.host, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMTASTSPolicyCache_ResolveMTASTSPolicy(t *testing.T) {
+	t.Run("policy is cached for max_age", func(t *testing.T) {
+		resolver := &testMTASTSResolver{policies: map[string]*MTASTSPolicy{
+			"example.com": {Mode: MTASTSModeEnforce, MX: []string{"mail.example.com"}, MaxAge: time.Hour},
+		}}
+		cache := NewMTASTSPolicyCache(resolver)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		for i := 0; i < 3; i++ {
+			if _, err := cache.ResolveMTASTSPolicy(t.Context(), "Example.com."); err != nil {
+				t.Fatalf("failed to resolve policy: %s", err)
+			}
+		}
+		if calls := resolver.calls.Load(); calls != 1 {
+			t.Errorf("expected resolver to be called once, got: %d", calls)
+		}
+		now = now.Add(time.Hour)
+		if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); err != nil {
+			t.Fatalf("failed to resolve policy: %s", err)
+		}
+		if calls := resolver.calls.Load(); calls != 2 {
+			t.Errorf("expected expired policy to be resolved again, got %d calls", calls)
+		}
+	})
+	t.Run("expired policy is used if it cannot be refreshed", func(t *testing.T) {
+		expected := &MTASTSPolicy{Mode: MTASTSModeEnforce, MX: []string{"mail.example.com"}, MaxAge: time.Hour}
+		resolver := &testMTASTSResolver{policies: map[string]*MTASTSPolicy{"example.com": expected}}
+		cache := NewMTASTSPolicyCache(resolver)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); err != nil {
+			t.Fatalf("failed to resolve policy: %s", err)
+		}
+		now = now.Add(2 * time.Hour)
+		resolver.err = errors.New("failed to fetch MTA-STS policy: connection refused")
+		for i := 0; i < 2; i++ {
+			policy, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com")
+			if err != nil {
+				t.Fatalf("expected expired policy to be used, got: %s", err)
+			}
+			if policy != expected {
+				t.Errorf("expected expired policy to be returned, got: %+v", policy)
+			}
+		}
+		if calls := resolver.calls.Load(); calls != 3 {
+			t.Errorf("expected expired policy to be resolved on every call, got %d calls", calls)
+		}
+	})
+	t.Run("expired policy is removed if the domain no longer publishes a policy", func(t *testing.T) {
+		resolver := &testMTASTSResolver{policies: map[string]*MTASTSPolicy{
+			"example.com": {Mode: MTASTSModeEnforce, MX: []string{"mail.example.com"}, MaxAge: time.Hour},
+		}}
+		cache := NewMTASTSPolicyCache(resolver)
+		now := time.Now()
+		cache.now = func() time.Time { return now }
+		if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); err != nil {
+			t.Fatalf("failed to resolve policy: %s", err)
+		}
+		now = now.Add(2 * time.Hour)
+		resolver.err = ErrNoMTASTSPolicy
+		if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); !errors.Is(err, ErrNoMTASTSPolicy) {
+			t.Errorf("expected error to be %s, got: %s", ErrNoMTASTSPolicy, err)
+		}
+		resolver.err = errors.New("failed to fetch MTA-STS policy: connection refused")
+		if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); err == nil {
+			t.Error("expected removed policy not to be used")
+		}
+	})
+	t.Run("errors are not cached", func(t *testing.T) {
+		resolver := &testMTASTSResolver{}
+		cache := NewMTASTSPolicyCache(resolver)
+		for i := 0; i < 2; i++ {
+			if _, err := cache.ResolveMTASTSPolicy(t.Context(), "example.com"); !errors.Is(err, ErrNoMTASTSPolicy) {
+				t.Errorf("expected error to be %s, got: %s", ErrNoMTASTSPolicy, err)
+			}
+		}
+		if calls := resolver.calls.Load(); calls != 2 {
+			t.Errorf("expected resolver to be called twice, got: %d", calls)
+		}
+	})
+}
+
+func TestMTASTSHTTPResolver_ResolveMTASTSPolicy(t *testing.T) {
+	stsRecord := func(context.Context, string) ([]string, error) {
+		return []string{"v=STSv1; id=20260101T000000;"}, nil
+	}
+	t.Run("policy is fetched via HTTPS", func(t *testing.T) {
+		var requestHost, requestPath string
+		resolver := testMTASTSHTTPResolver(t, func(w http.ResponseWriter, r *http.Request) {
+			requestHost, requestPath = r.Host, r.URL.Path
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(testMTASTSPolicy))
+		})
+		resolver.LookupTXT = func(_ context.Context, name string) ([]string, error) {
+			if name != "_mta-sts.example.com" {
+				return nil, fmt.Errorf("unexpected TXT lookup for %s", name)
+			}
+			return []string{"v=STSv1; id=20260101T000000;"}, nil
+		}
+		policy, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if err != nil {
+			t.Fatalf("failed to resolve policy: %s", err)
+		}
+		if policy.Mode != MTASTSModeEnforce {
+			t.Errorf("expected mode to be %s, got: %s", MTASTSModeEnforce, policy.Mode)
+		}
+		if requestHost != "mta-sts.example.com" {
+			t.Errorf("expected request to mta-sts.example.com, got: %s", requestHost)
+		}
+		if requestPath != "/.well-known/mta-sts.txt" {
+			t.Errorf("expected request path to be /.well-known/mta-sts.txt, got: %s", requestPath)
+		}
+	})
+	t.Run("missing TXT record returns ErrNoMTASTSPolicy", func(t *testing.T) {
+		resolver := &MTASTSHTTPResolver{LookupTXT: func(context.Context, string) ([]string, error) {
+			return nil, &net.DNSError{Err: "no such host", IsNotFound: true}
+		}}
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if !errors.Is(err, ErrNoMTASTSPolicy) {
+			t.Errorf("expected error to be %s, got: %s", ErrNoMTASTSPolicy, err)
+		}
+	})
+	t.Run("TXT record without STSv1 returns ErrNoMTASTSPolicy", func(t *testing.T) {
+		resolver := &MTASTSHTTPResolver{LookupTXT: func(context.Context, string) ([]string, error) {
+			return []string{"v=spf1 -all"}, nil
+		}}
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if !errors.Is(err, ErrNoMTASTSPolicy) {
+			t.Errorf("expected error to be %s, got: %s", ErrNoMTASTSPolicy, err)
+		}
+	})
+	t.Run("multiple STSv1 TXT records return ErrNoMTASTSPolicy", func(t *testing.T) {
+		resolver := &MTASTSHTTPResolver{LookupTXT: func(context.Context, string) ([]string, error) {
+			return []string{"v=STSv1; id=20260101T000000;", "v=spf1 -all", "v=STSv1; id=20260201T000000;"}, nil
+		}}
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if !errors.Is(err, ErrNoMTASTSPolicy) {
+			t.Errorf("expected error to be %s, got: %s", ErrNoMTASTSPolicy, err)
+		}
+	})
+	t.Run("DNS failure is returned", func(t *testing.T) {
+		resolver := &MTASTSHTTPResolver{LookupTXT: func(context.Context, string) ([]string, error) {
+			return nil, &net.DNSError{Err: "server misbehaving", IsTemporary: true}
+		}}
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if err == nil || errors.Is(err, ErrNoMTASTSPolicy) {
+			t.Errorf("expected DNS error, got: %s", err)
+		}
+	})
+	t.Run("unexpected content type fails", func(t *testing.T) {
+		resolver := testMTASTSHTTPResolver(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(testMTASTSPolicy))
+		})
+		resolver.LookupTXT = stsRecord
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if !errors.Is(err, ErrInvalidMTASTSPolicy) {
+			t.Errorf("expected error to be %s, got: %s", ErrInvalidMTASTSPolicy, err)
+		}
+	})
+	t.Run("non-200 status fails", func(t *testing.T) {
+		resolver := testMTASTSHTTPResolver(t, func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		resolver.LookupTXT = stsRecord
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("expected HTTP status error, got: %s", err)
+		}
+	})
+	t.Run("redirects are not followed", func(t *testing.T) {
+		resolver := testMTASTSHTTPResolver(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/policy.txt" {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte(testMTASTSPolicy))
+				return
+			}
+			http.Redirect(w, r, "/policy.txt", http.StatusFound)
+		})
+		resolver.LookupTXT = stsRecord
+		_, err := resolver.ResolveMTASTSPolicy(t.Context(), "example.com")
+		if err == nil || !strings.Contains(err.Error(), "302") {
+			t.Errorf("expected HTTP status error, got: %s", err)
+		}
+	})
+}
+
+func TestClient_DialWithContext_MTASTS(t *testing.T) {
+	resolver := &testMTASTSResolver{policies: map[string]*MTASTSPolicy{
+		"enforce.tld":  {Mode: MTASTSModeEnforce, MX: []string{DefaultHost}, MaxAge: time.Hour},
+		"mismatch.tld": {Mode: MTASTSModeEnforce, MX: []string{"mail.mismatch.tld"}, MaxAge: time.Hour},
+		"testing.tld":  {Mode: MTASTSModeTesting, MX: []string{"mail.testing.tld"}, MaxAge: time.Hour},
+	}}
+	startTLSFeatures := "250-8BITMIME\r\n250-STARTTLS\r\n250 SMTPUTF8"
+	plainFeatures := "250-8BITMIME\r\n250 SMTPUTF8"
+	t.Run("enforced policy with STARTTLS succeeds", func(t *testing.T) {
+		client := testMTASTSClient(t, startTLSFeatures, "enforce.tld", resolver)
+		if err := client.DialWithContext(t.Context()); err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		t.Cleanup(func() {
+			if err := client.Close(); err != nil {
+				t.Errorf("failed to close client: %s", err)
+			}
+		})
+		if _, err := client.smtpClient.GetTLSConnectionState(); err != nil {
+			t.Errorf("expected TLS protected connection, got: %s", err)
+		}
+	})
+	t.Run("enforced policy without STARTTLS fails", func(t *testing.T) {
+		client := testMTASTSClient(t, plainFeatures, "enforce.tld", resolver)
+		if err := client.DialWithContext(t.Context()); !errors.Is(err, ErrMTASTSNoStartTLS) {
+			t.Errorf("expected error to be %s, got: %s", ErrMTASTSNoStartTLS, err)
+		}
+	})
+	t.Run("enforced policy with mismatching hostname fails", func(t *testing.T) {
+		client := testMTASTSClient(t, startTLSFeatures, "mismatch.tld", resolver)
+		if err := client.DialWithContext(t.Context()); !errors.Is(err, ErrMTASTSHostMismatch) {
+			t.Errorf("expected error to be %s, got: %s", ErrMTASTSHostMismatch, err)
+		}
+	})
+	t.Run("testing policy falls back to opportunistic TLS", func(t *testing.T) {
+		client := testMTASTSClient(t, plainFeatures, "testing.tld", resolver)
+		if err := client.DialWithContext(t.Context()); err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("failed to close client: %s", err)
+		}
+	})
+	t.Run("missing policy falls back to opportunistic TLS", func(t *testing.T) {
+		client := testMTASTSClient(t, plainFeatures, "nopolicy.tld", resolver)
+		if err := client.DialWithContext(t.Context()); err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		if err := client.Close(); err != nil {
+			t.Errorf("failed to close client: %s", err)
+		}
+	})
+	t.Run("resolver failure fails the dial", func(t *testing.T) {
+		failing := &testMTASTSResolver{err: errors.New("resolver failure")}
+		client := testMTASTSClient(t, startTLSFeatures, "enforce.tld", failing)
+		if err := client.DialWithContext(t.Context()); err == nil || !strings.Contains(err.Error(), "resolver failure") {
+			t.Errorf("expected resolver failure, got: %s", err)
+		}
+	})
+	t.Run("TLSMTASTS without policy domain fails", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithTLSPolicy(TLSMTASTS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialWithContext(t.Context()); !errors.Is(err, ErrMTASTSNoDomain) {
+			t.Errorf("expected error to be %s, got: %s", ErrMTASTSNoDomain, err)
+		}
+	})
+}
+
+// testMTASTSResolver is an in-memory MTASTSResolver that counts the number of resolved policies.
+type testMTASTSResolver struct {
+	calls    atomic.Int32
+	err      error
+	policies map[string]*MTASTSPolicy
+}
+
+func (r *testMTASTSResolver) ResolveMTASTSPolicy(_ context.Context, domain string) (*MTASTSPolicy, error) {
+	r.calls.Add(1)
+	if r.err != nil {
+		return nil, r.err
+	}
+	policy, ok := r.policies[domain]
+	if !ok {
+		return nil, ErrNoMTASTSPolicy
+	}
+	return policy, nil
+}
+
+// testMTASTSHTTPResolver starts a HTTPS test server with the given handler and returns a
+// MTASTSHTTPResolver that sends all requests to it.
+func testMTASTSHTTPResolver(t *testing.T, handler http.HandlerFunc) *MTASTSHTTPResolver {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		dialer := net.Dialer{}
+		return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return &MTASTSHTTPResolver{HTTPClient: &http.Client{Transport: transport}}
+}
+
+// testMTASTSClient starts a test server with the given feature set and returns a Client that
+// connects to it with the TLSMTASTS policy for the given domain.
+func testMTASTSClient(t *testing.T, featureSet, domain string, resolver MTASTSResolver) *Client {
+	t.Helper()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	go func() {
+		if err := simpleSMTPServer(t.Context(), t, &serverProps{
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+
+	client, err := NewClient(DefaultHost, WithPort(serverPort), WithMTASTS(domain),
+		WithMTASTSResolver(resolver), WithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}
@@ -0,0 +1,311 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrMsgIsNil is returned if a nil Msg is provided for the delivery.
+	ErrMsgIsNil = errors.New("message is nil")
+
+	// ErrNullMX is returned if the recipient domain publishes a null MX record and therefore does
+	// not accept any mail.
+	//
+	// https://datatracker.ietf.org/doc/html/rfc7505
+	ErrNullMX = errors.New("domain does not accept mail (null MX)")
+
+	// ErrNoMailServer is returned if the recipient domain has neither MX records nor address records.
+	ErrNoMailServer = errors.New("domain has no MX or address records")
+)
+
+// MXResolver is an interface for types that look up the MX and address records of a recipient
+// domain for the direct delivery with a MXClient. It is satisfied by *net.Resolver.
+type MXResolver interface {
+	// LookupMX returns the MX records for the given domain name.
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+
+	// LookupHost returns the addresses of the given host. It is used to check if a domain without
+	// MX records can be used as implicit MX.
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// MXClient delivers messages directly to the MX hosts of the recipient domains, instead of
+// submitting them to a fixed relay host.
+//
+// For each delivery, the recipients of the Msg are grouped by their domain. For every domain,
+// the MX hosts are looked up and tried in the order of their preference, until the message has
+// been delivered. A Client is created for each MX host with the Options provided to NewMXClient.
+type MXClient struct {
+	opts     []Option
+	resolver MXResolver
+}
+
+// MXResult represents the result of the delivery of a Msg to the recipients of a single domain
+// by a MXClient.
+type MXResult struct {
+	// Domain is the recipient domain.
+	Domain string
+
+	// Recipients holds the envelope recipients of the domain.
+	Recipients []string
+
+	// Host is the MX host that accepted the Msg or, if the delivery failed, the MX host that has
+	// been tried last. It is empty if the MX lookup failed.
+	Host string
+
+	// ServerResponse is the response of the MX host after the Msg has been accepted.
+	ServerResponse string
+
+	// DeliveryResult is the DeliveryResult of the delivery to the domain. It is nil if the MX host
+	// did not accept the message data.
+	DeliveryResult *DeliveryResult
+
+	// RcptResults holds the outcome of the delivery for each recipient of the domain, as returned
+	// by the MX host. It is empty if the connection to the MX host failed.
+	RcptResults []RcptResult
+
+	// Err is the error of the delivery to the domain, or nil if the Msg has been delivered.
+	Err error
+}
+
+// NewMXClient returns a new MXClient that uses the provided MXResolver to look up the MX hosts of
+// the recipient domains.
+//
+// The provided Options are applied to the Client of every MX host, i. e. to configure the HELO/EHLO
+// hostname, the timeout or the TLSPolicy. As MX hosts are expected to accept mail on port 25,
+// DefaultPort is used unless overridden with WithPort. The ServerName of the tls.Config is set to
+// the respective MX host. If the TLSMTASTS policy is used, the MTA-STS policy of the recipient
+// domain is enforced.
+//
+// Parameters:
+//   - resolver: The MXResolver to look up the MX and address records. If nil, net.DefaultResolver is used.
+//   - opts: Optional configuration functions for the Client of each MX host.
+//
+// Returns:
+//   - A pointer to the new MXClient.
+//   - An error if any of the Options fail to apply.
+func NewMXClient(resolver MXResolver, opts ...Option) (*MXClient, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	if _, err := NewClient("localhost", opts...); err != nil {
+		return nil, err
+	}
+	return &MXClient{opts: opts, resolver: resolver}, nil
+}
+
+// Send delivers the provided Msg directly to the MX hosts of its recipient domains.
+//
+// The recipients returned by Msg.GetRecipients are grouped by their domain and each domain is
+// delivered separately, so a failing domain does not affect the delivery to the other domains.
+// The MX hosts of a domain are tried in the order of their preference. If a domain has no MX
+// records, the domain itself is used as implicit MX host, given that it has address records. The
+// next MX host is tried if the connection fails or the delivery fails temporarily.
+//
+// After the delivery, the Msg is marked as delivered if all domains accepted it. Otherwise, the
+// SendError of the Msg holds the errors of the failed domains.
+//
+// As every domain is delivered in a separate mail transaction, the results of Msg.RcptResults and
+// Msg.DeliveryResult only reflect the transaction that has been made last and are not meaningful
+// after Send. The DeliveryResult and RcptResults of each domain are stored in its MXResult instead.
+//
+// Parameters:
+//   - ctx: The context.Context to control the lookups, connections and the delivery.
+//   - message: A pointer to the Msg to deliver.
+//
+// Returns:
+//   - A slice of MXResult pointers, one for each recipient domain, in the order of their first
+//     appearance in the recipients of the Msg.
+//   - An error if the Msg is nil or its recipients could not be retrieved.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc5321#section-5
+func (m *MXClient) Send(ctx context.Context, message *Msg) ([]*MXResult, error) {
+	if message == nil {
+		return nil, ErrMsgIsNil
+	}
+	rcpts, err := message.GetRecipients()
+	if err != nil {
+		sendErr := &SendError{Reason: ErrGetRcpts, errlist: []error{err}, affectedMsg: message}
+		message.sendError = sendErr
+		return nil, sendErr
+	}
+
+	results := groupRcptsByDomain(rcpts)
+	for _, result := range results {
+		m.sendDomain(ctx, message, result)
+	}
+
+	var errs []error
+	var failedRcpts []string
+	isTemp := true
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", result.Domain, result.Err))
+		failedRcpts = append(failedRcpts, result.Recipients...)
+		isTemp = isTemp && isRetryableError(result.Err)
+	}
+	message.isDelivered = len(errs) == 0
+	message.sendError = nil
+	if len(errs) > 0 {
+		message.sendError = &SendError{
+			Reason: ErrAmbiguous, errlist: errs, rcpt: failedRcpts, isTemp: isTemp,
+			affectedMsg: message,
+		}
+	}
+	return results, nil
+}
+
+// sendDomain delivers the Msg to the recipients of a single domain, trying the MX hosts of the
+// domain in the order of their preference. The outcome is stored in the provided MXResult.
+//
+// Parameters:
+//   - ctx: The context.Context to control the lookups, connections and the delivery.
+//   - message: A pointer to the Msg to deliver.
+//   - result: A pointer to the MXResult holding the domain and its recipients.
+func (m *MXClient) sendDomain(ctx context.Context, message *Msg, result *MXResult) {
+	hosts, err := m.lookupMX(ctx, result.Domain)
+	if err != nil {
+		result.Err = err
+		return
+	}
+	for _, host := range hosts {
+		if err = ctx.Err(); err != nil {
+			result.Err = err
+			return
+		}
+		result.Host = host
+		dialed, sendErr := m.sendHost(ctx, message, host, result.Domain, result.Recipients)
+		result.Err = sendErr
+		result.DeliveryResult, result.RcptResults = nil, nil
+		if dialed {
+			result.DeliveryResult = message.deliveryResult
+			result.RcptResults = slices.Clone(message.rcptResults)
+		}
+		if sendErr == nil {
+			result.ServerResponse = message.serverResponse
+			return
+		}
+		if dialed && !isRetryableError(sendErr) {
+			return
+		}
+	}
+}
+
+// sendHost connects to the provided MX host and delivers the Msg to the given recipients.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection and the delivery.
+//   - message: A pointer to the Msg to deliver.
+//   - host: The MX host to connect to.
+//   - domain: The recipient domain, used as policy domain for TLSMTASTS.
+//   - rcpts: The envelope recipients of the domain.
+//
+// Returns:
+//   - A boolean indicating whether the connection to the MX host has been established.
+//   - An error if the connection or the delivery failed; otherwise, returns nil.
+func (m *MXClient) sendHost(ctx context.Context, message *Msg, host, domain string, rcpts []string,
+) (bool, error) {
+	client, err := NewClient(host, m.opts...)
+	if err != nil {
+		return false, err
+	}
+	if client.tlsconfig != nil {
+		client.tlsconfig = client.tlsconfig.Clone()
+		client.tlsconfig.ServerName = host
+	}
+	if client.tlspolicy == TLSMTASTS {
+		client.mtastsDomain = domain
+	}
+
+	smtpClient, err := client.DialToSMTPClientWithContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = client.closeWithSMTPClient(ctx, smtpClient)
+	}()
+	return true, client.sendSingleMsgTo(ctx, smtpClient, message, rcpts)
+}
+
+// lookupMX returns the MX hosts of the provided domain in the order of their preference.
+//
+// If the domain has no MX records, but address records, the domain itself is returned as implicit
+// MX host. If the domain publishes a null MX record, ErrNullMX is returned.
+//
+// Parameters:
+//   - ctx: The context.Context for the DNS lookups.
+//   - domain: The recipient domain.
+//
+// Returns:
+//   - A slice of MX hostnames.
+//   - An error if the lookup failed or the domain does not accept mail.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc5321#section-5.1
+//   - https://datatracker.ietf.org/doc/html/rfc7505
+func (m *MXClient) lookupMX(ctx context.Context, domain string) ([]string, error) {
+	records, err := m.resolver.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			return nil, fmt.Errorf("failed to look up MX records: %w", err)
+		}
+		records = nil
+	}
+	if len(records) == 1 && strings.TrimSuffix(records[0].Host, ".") == "" {
+		return nil, ErrNullMX
+	}
+	if len(records) == 0 {
+		addrs, lookupErr := m.resolver.LookupHost(ctx, domain)
+		var dnsErr *net.DNSError
+		if lookupErr != nil && (!errors.As(lookupErr, &dnsErr) || !dnsErr.IsNotFound) {
+			return nil, fmt.Errorf("failed to look up address records: %w", lookupErr)
+		}
+		if len(addrs) == 0 {
+			return nil, ErrNoMailServer
+		}
+		return []string{domain}, nil
+	}
+
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b *net.MX) int {
+		return int(a.Pref) - int(b.Pref)
+	})
+	hosts := make([]string, 0, len(records))
+	for _, record := range records {
+		hosts = append(hosts, strings.TrimSuffix(record.Host, "."))
+	}
+	return hosts, nil
+}
+
+// groupRcptsByDomain groups the provided recipient addresses by their domain and returns a MXResult
+// for each domain, in the order of their first appearance. The recipient addresses may be enclosed
+// in angle brackets, as returned by Msg.GetRecipients.
+func groupRcptsByDomain(rcpts []string) []*MXResult {
+	var results []*MXResult
+	index := make(map[string]*MXResult)
+	for _, rcpt := range rcpts {
+		address := strings.Trim(rcpt, "<>")
+		domain := strings.ToLower(address[strings.LastIndex(address, "@")+1:])
+		result, ok := index[domain]
+		if !ok {
+			result = &MXResult{Domain: domain}
+			index[domain] = result
+			results = append(results, result)
+		}
+		result.Recipients = append(result.Recipients, rcpt)
+	}
+	return results
+}
@@ -0,0 +1,379 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestNewMXClient(t *testing.T) {
+	t.Run("nil resolver uses the default resolver", func(t *testing.T) {
+		client, err := NewMXClient(nil)
+		if err != nil {
+			t.Fatalf("failed to create new MX client: %s", err)
+		}
+		if client.resolver != net.DefaultResolver {
+			t.Error("expected resolver to be net.DefaultResolver")
+		}
+	})
+	t.Run("invalid option fails", func(t *testing.T) {
+		_, err := NewMXClient(nil, WithPort(-1))
+		if !errors.Is(err, ErrInvalidPort) {
+			t.Errorf("expected error to be %s, got: %s", ErrInvalidPort, err)
+		}
+	})
+}
+
+func TestMXClient_lookupMX(t *testing.T) {
+	resolver := &testMXResolver{
+		mx: map[string][]*net.MX{
+			"example.com": {
+				{Host: "mx3.example.com.", Pref: 30},
+				{Host: "mx1.example.com.", Pref: 10},
+				{Host: "mx2.example.com.", Pref: 20},
+			},
+			"nullmx.tld": {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{"implicit.tld": {"192.0.2.1"}},
+	}
+	client, err := NewMXClient(resolver)
+	if err != nil {
+		t.Fatalf("failed to create new MX client: %s", err)
+	}
+	t.Run("MX hosts are sorted by preference", func(t *testing.T) {
+		hosts, err := client.lookupMX(t.Context(), "example.com")
+		if err != nil {
+			t.Fatalf("failed to look up MX hosts: %s", err)
+		}
+		want := "mx1.example.com,mx2.example.com,mx3.example.com"
+		if strings.Join(hosts, ",") != want {
+			t.Errorf("expected MX hosts to be %s, got: %s", want, strings.Join(hosts, ","))
+		}
+	})
+	t.Run("domain without MX records is used as implicit MX", func(t *testing.T) {
+		hosts, err := client.lookupMX(t.Context(), "implicit.tld")
+		if err != nil {
+			t.Fatalf("failed to look up MX hosts: %s", err)
+		}
+		if len(hosts) != 1 || hosts[0] != "implicit.tld" {
+			t.Errorf("expected implicit MX host, got: %v", hosts)
+		}
+	})
+	t.Run("null MX fails", func(t *testing.T) {
+		if _, err := client.lookupMX(t.Context(), "nullmx.tld"); !errors.Is(err, ErrNullMX) {
+			t.Errorf("expected error to be %s, got: %s", ErrNullMX, err)
+		}
+	})
+	t.Run("domain without MX and address records fails", func(t *testing.T) {
+		if _, err := client.lookupMX(t.Context(), "unknown.tld"); !errors.Is(err, ErrNoMailServer) {
+			t.Errorf("expected error to be %s, got: %s", ErrNoMailServer, err)
+		}
+	})
+	t.Run("MX lookup failure is returned", func(t *testing.T) {
+		failing, err := NewMXClient(&testMXResolver{err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}})
+		if err != nil {
+			t.Fatalf("failed to create new MX client: %s", err)
+		}
+		_, err = failing.lookupMX(t.Context(), "example.com")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) {
+			t.Errorf("expected DNS error, got: %s", err)
+		}
+	})
+}
+
+func TestGroupRcptsByDomain(t *testing.T) {
+	results := groupRcptsByDomain([]string{
+		"<a@example.com>", "b@other.tld", "<c@Example.COM>", "d@third.tld",
+	})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 domains, got: %d", len(results))
+	}
+	tests := []struct {
+		domain string
+		rcpts  string
+	}{
+		{"example.com", "<a@example.com>,<c@Example.COM>"},
+		{"other.tld", "b@other.tld"},
+		{"third.tld", "d@third.tld"},
+	}
+	for i, tt := range tests {
+		if results[i].Domain != tt.domain {
+			t.Errorf("expected domain %d to be %s, got: %s", i, tt.domain, results[i].Domain)
+		}
+		if strings.Join(results[i].Recipients, ",") != tt.rcpts {
+			t.Errorf("expected recipients of %s to be %s, got: %v", tt.domain, tt.rcpts, results[i].Recipients)
+		}
+	}
+}
+
+func TestMXClient_Send(t *testing.T) {
+	t.Run("message is delivered to the MX host of the domain", func(t *testing.T) {
+		port, echoBuffer := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx.domain.tld.", Pref: 10}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx.domain.tld": port})
+		message := testMessage(t)
+		results, err := client.Send(t.Context(), message)
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("expected 1 result, got: %d", len(results))
+		}
+		if results[0].Err != nil {
+			t.Errorf("expected delivery to succeed, got: %s", results[0].Err)
+		}
+		if results[0].Host != "mx.domain.tld" {
+			t.Errorf("expected MX host to be mx.domain.tld, got: %s", results[0].Host)
+		}
+		if !strings.Contains(results[0].ServerResponse, "queued as") {
+			t.Errorf("expected server response, got: %s", results[0].ServerResponse)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+		if queued := echoBuffer.count("queued as"); queued != 1 {
+			t.Errorf("expected 1 queued message, got: %d", queued)
+		}
+	})
+	t.Run("failing domain does not affect other domains", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx.domain.tld", Pref: 10}},
+			"nullmx.tld": {{Host: ".", Pref: 0}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx.domain.tld": port})
+		message := testMessage(t)
+		if err := message.AddTo("someone@nullmx.tld"); err != nil {
+			t.Fatalf("failed to add recipient: %s", err)
+		}
+		results, err := client.Send(t.Context(), message)
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got: %d", len(results))
+		}
+		if results[0].Err != nil {
+			t.Errorf("expected delivery to domain.tld to succeed, got: %s", results[0].Err)
+		}
+		if !errors.Is(results[1].Err, ErrNullMX) {
+			t.Errorf("expected error to be %s, got: %s", ErrNullMX, results[1].Err)
+		}
+		if message.IsDelivered() {
+			t.Error("expected message not to be marked as delivered")
+		}
+		var sendErr *SendError
+		if !errors.As(message.SendError(), &sendErr) {
+			t.Fatalf("expected SendError on message, got: %s", message.SendError())
+		}
+		if len(sendErr.rcpt) != 1 || sendErr.rcpt[0] != "<someone@nullmx.tld>" {
+			t.Errorf("expected failed recipient someone@nullmx.tld, got: %v", sendErr.rcpt)
+		}
+	})
+	t.Run("each domain holds its own delivery results", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{})
+		otherPort, _ := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx.domain.tld", Pref: 10}},
+			"other.tld":  {{Host: "mx.other.tld", Pref: 10}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx.domain.tld": port, "mx.other.tld": otherPort})
+		message := testMessage(t)
+		if err := message.AddTo("someone@other.tld"); err != nil {
+			t.Fatalf("failed to add recipient: %s", err)
+		}
+		results, err := client.Send(t.Context(), message)
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results, got: %d", len(results))
+		}
+		accepted, rejected := results[0], results[1]
+		if accepted.Err != nil {
+			t.Errorf("expected delivery to domain.tld to succeed, got: %s", accepted.Err)
+		}
+		if accepted.DeliveryResult == nil || accepted.DeliveryResult.ServerHost != "mx.domain.tld" {
+			t.Errorf("expected delivery result of mx.domain.tld, got: %+v", accepted.DeliveryResult)
+		}
+		if len(accepted.RcptResults) != 1 || accepted.RcptResults[0].Rcpt != "<valid-to@domain.tld>" ||
+			accepted.RcptResults[0].Err != nil {
+			t.Errorf("expected accepted recipient result, got: %+v", accepted.RcptResults)
+		}
+		if rejected.Err == nil {
+			t.Error("expected delivery to other.tld to fail")
+		}
+		if rejected.DeliveryResult != nil {
+			t.Errorf("expected no delivery result for other.tld, got: %+v", rejected.DeliveryResult)
+		}
+		if len(rejected.RcptResults) != 1 || rejected.RcptResults[0].Rcpt != "<someone@other.tld>" ||
+			rejected.RcptResults[0].Err == nil {
+			t.Errorf("expected rejected recipient result, got: %+v", rejected.RcptResults)
+		}
+	})
+	t.Run("next MX host is tried if the connection fails", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx1.domain.tld", Pref: 10}, {Host: "mx2.domain.tld", Pref: 20}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx2.domain.tld": port})
+		results, err := client.Send(t.Context(), testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if results[0].Err != nil {
+			t.Errorf("expected delivery to succeed, got: %s", results[0].Err)
+		}
+		if results[0].Host != "mx2.domain.tld" {
+			t.Errorf("expected MX host to be mx2.domain.tld, got: %s", results[0].Host)
+		}
+	})
+	t.Run("next MX host is tried on temporary failure", func(t *testing.T) {
+		failingPort, failingBuffer := testMXServer(t, &serverProps{FailTemp: true})
+		port, echoBuffer := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx1.domain.tld", Pref: 10}, {Host: "mx2.domain.tld", Pref: 20}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx1.domain.tld": failingPort, "mx2.domain.tld": port})
+		results, err := client.Send(t.Context(), testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if results[0].Err != nil {
+			t.Errorf("expected delivery to succeed, got: %s", results[0].Err)
+		}
+		if results[0].Host != "mx2.domain.tld" {
+			t.Errorf("expected MX host to be mx2.domain.tld, got: %s", results[0].Host)
+		}
+		if failingBuffer.connections() != 1 || echoBuffer.connections() != 1 {
+			t.Errorf("expected one connection to each MX host, got: %d and %d", failingBuffer.connections(),
+				echoBuffer.connections())
+		}
+	})
+	t.Run("next MX host is not tried on permanent failure", func(t *testing.T) {
+		failingPort, _ := testMXServer(t, &serverProps{FailOnDataClose: true})
+		port, echoBuffer := testMXServer(t, &serverProps{})
+		resolver := &testMXResolver{mx: map[string][]*net.MX{
+			"domain.tld": {{Host: "mx1.domain.tld", Pref: 10}, {Host: "mx2.domain.tld", Pref: 20}},
+		}}
+		client := testMXClient(t, resolver, map[string]int{"mx1.domain.tld": failingPort, "mx2.domain.tld": port})
+		message := testMessage(t)
+		results, err := client.Send(t.Context(), message)
+		if err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		var sendErr *SendError
+		if !errors.As(results[0].Err, &sendErr) || sendErr.Reason != ErrSMTPDataClose {
+			t.Errorf("expected ErrSMTPDataClose, got: %s", results[0].Err)
+		}
+		if results[0].Host != "mx1.domain.tld" {
+			t.Errorf("expected MX host to be mx1.domain.tld, got: %s", results[0].Host)
+		}
+		if echoBuffer.connections() != 0 {
+			t.Errorf("expected no connection to the second MX host, got: %d", echoBuffer.connections())
+		}
+		var msgErr *SendError
+		if !errors.As(message.SendError(), &msgErr) || msgErr.IsTemp() {
+			t.Errorf("expected permanent send error on message, got: %s", message.SendError())
+		}
+	})
+	t.Run("nil message fails", func(t *testing.T) {
+		client := testMXClient(t, &testMXResolver{}, nil)
+		if _, err := client.Send(t.Context(), nil); !errors.Is(err, ErrMsgIsNil) {
+			t.Errorf("expected error to be %s, got: %s", ErrMsgIsNil, err)
+		}
+	})
+	t.Run("message without recipients fails", func(t *testing.T) {
+		client := testMXClient(t, &testMXResolver{}, nil)
+		message := NewMsg()
+		_, err := client.Send(t.Context(), message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) || sendErr.Reason != ErrGetRcpts {
+			t.Errorf("expected ErrGetRcpts, got: %s", err)
+		}
+	})
+}
+
+// testMXResolver is an in-memory MXResolver.
+type testMXResolver struct {
+	err   error
+	hosts map[string][]string
+	mx    map[string][]*net.MX
+}
+
+func (r *testMXResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	records, ok := r.mx[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func (r *testMXResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+// testMXServer starts a test server with the given properties and returns its port alongside its
+// echo buffer.
+func testMXServer(t *testing.T, props *serverProps) (int, *countingEchoBuffer) {
+	t.Helper()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	buffer := bytes.NewBuffer(nil)
+	props.EchoBuffer = buffer
+	if props.FeatureSet == "" {
+		props.FeatureSet = "250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+	}
+	props.ListenPort = serverPort
+	go func() {
+		if err := simpleSMTPServer(t.Context(), t, props); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+	return serverPort, &countingEchoBuffer{props: props, buffer: buffer}
+}
+
+// testMXClient returns a MXClient that connects to the test server ports mapped to the MX hosts.
+// Connections to unmapped hosts are refused.
+func testMXClient(t *testing.T, resolver MXResolver, ports map[string]int) *MXClient {
+	t.Helper()
+	dialer := func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		port, ok := ports[host]
+		if !ok {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+		}
+		netDialer := net.Dialer{}
+		return netDialer.DialContext(ctx, network, fmt.Sprintf("%s:%d", TestServerAddr, port))
+	}
+	client, err := NewMXClient(resolver, WithTLSPolicy(NoTLS), WithDialContextFunc(dialer))
+	if err != nil {
+		t.Fatalf("failed to create new MX client: %s", err)
+	}
+	return client
+}
@@ -0,0 +1,995 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wneessen/go-mail/internal/dkim"
+)
+
+const (
+	// DefaultOutboxWorkers is the default number of worker goroutines of an Outbox.
+	DefaultOutboxWorkers = 2
+
+	// DefaultOutboxPollInterval is the default interval in which an Outbox checks its queue for
+	// messages that are due for delivery.
+	DefaultOutboxPollInterval = 5 * time.Second
+
+	// outboxDirQueue is the subdirectory of an Outbox holding the queued messages.
+	outboxDirQueue = "queue"
+
+	// outboxDirDead is the subdirectory of an Outbox holding the permanently failed messages.
+	outboxDirDead = "dead"
+
+	// outboxDirTmp is the subdirectory of an Outbox for messages that are being written or removed.
+	outboxDirTmp = "tmp"
+
+	// outboxFileMessage is the name of the file holding the rendered message of an Outbox entry.
+	outboxFileMessage = "message.eml"
+
+	// outboxFileMeta is the name of the file holding the OutboxEntry metadata of an Outbox entry.
+	outboxFileMeta = "meta.json"
+)
+
+// DefaultOutboxRetrySchedule is the default schedule of delays between the delivery attempts
+// of a temporarily failed message in an Outbox.
+var DefaultOutboxRetrySchedule = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour, 2 * time.Hour, 4 * time.Hour,
+}
+
+var (
+	// ErrOutboxRunning is returned when an Outbox is started while it is already running.
+	ErrOutboxRunning = errors.New("outbox is already running")
+
+	// ErrOutboxNotRunning is returned when an Outbox is stopped while it is not running.
+	ErrOutboxNotRunning = errors.New("outbox is not running")
+
+	// ErrOutboxEntryNotFound is returned when an Outbox entry with the requested ID does not exist.
+	ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+
+	// ErrOutboxEntryInFlight is returned when an Outbox entry is modified while it is being delivered.
+	ErrOutboxEntryInFlight = errors.New("outbox entry is being delivered")
+
+	// ErrInvalidOutboxWorkers is returned when the provided number of workers for an Outbox is zero
+	// or negative.
+	ErrInvalidOutboxWorkers = errors.New("number of outbox workers must be greater than zero")
+
+	// ErrInvalidOutboxPollInterval is returned when the provided poll interval for an Outbox is zero
+	// or negative.
+	ErrInvalidOutboxPollInterval = errors.New("outbox poll interval must be greater than zero")
+
+	// ErrInvalidOutboxRetrySchedule is returned when the provided retry schedule for an Outbox
+	// contains a negative delay.
+	ErrInvalidOutboxRetrySchedule = errors.New("outbox retry schedule cannot contain negative delays")
+)
+
+type (
+	// OutboxOption is a function type that modifies the configuration or behavior of an Outbox instance.
+	OutboxOption func(*Outbox) error
+
+	// Outbox is a persistent, on-disk queue for messages with background delivery.
+	//
+	// Messages passed to Outbox.Enqueue are rendered and stored, together with their envelope, in a
+	// directory queue, so that they survive a crash or restart of the application. Once started,
+	// worker goroutines deliver the queued messages via the Client the Outbox was created with. A
+	// message that failed with a temporary error, or because the SMTP server could not be reached,
+	// is retried according to the retry schedule of the Outbox. A message that failed permanently,
+	// or that is still failing after the last scheduled retry, is moved to the dead-letter directory,
+	// from where it can be inspected, requeued or removed.
+	//
+	// Each entry is stored in its own subdirectory, holding the rendered message and the OutboxEntry
+	// metadata. New entries are written into a temporary directory first and then moved into the
+	// queue, and all state changes are performed via renames, so that a crash never leaves a partial
+	// entry in the queue. A directory must only be used by a single Outbox at a time.
+	Outbox struct {
+		// client is the Client that is used to deliver the messages.
+		client *Client
+
+		// dir is the root directory of the Outbox.
+		dir string
+
+		// inFlight holds the IDs of the entries that are currently being delivered.
+		inFlight map[string]struct{}
+
+		// jobs hands out the IDs of the entries that are due for delivery to the workers.
+		jobs chan string
+
+		// mutex is used to synchronize access to the state of the Outbox.
+		mutex sync.Mutex
+
+		// pollInterval is the interval in which the queue is checked for due entries.
+		pollInterval time.Duration
+
+		// schedule holds the delays between the delivery attempts of a temporarily failed entry.
+		schedule []time.Duration
+
+		// stop cancels the context of the running workers.
+		stop context.CancelFunc
+
+		// wake signals the dispatcher that new entries have been queued.
+		wake chan struct{}
+
+		// wg is used to wait for the dispatcher and the workers to finish.
+		wg sync.WaitGroup
+
+		// workers is the number of worker goroutines.
+		workers int
+	}
+
+	// OutboxEntry holds the metadata of a message in an Outbox.
+	OutboxEntry struct {
+		// ID is the unique identifier of the entry.
+		ID string `json:"id"`
+
+		// From is the envelope sender address of the message.
+		From string `json:"from"`
+
+		// Rcpts holds the envelope recipient addresses of the message. After a partial delivery,
+		// only the rejected recipients are kept.
+		Rcpts []string `json:"rcpts"`
+
+		// MessageID is the Message-ID header of the message, if set.
+		MessageID string `json:"message_id,omitempty"`
+
+		// Subject is the Subject header of the message, if set.
+		Subject string `json:"subject,omitempty"`
+
+		// Size is the size of the rendered message in bytes.
+		Size int64 `json:"size"`
+
+		// EnvelopeID is the DSN envelope identifier of the message, if set.
+		EnvelopeID string `json:"envelope_id,omitempty"`
+
+		// OriginalRcpts holds the DSN original recipients of the message, if set.
+		OriginalRcpts map[string]string `json:"original_rcpts,omitempty"`
+
+		// RequireTLS indicates that the message must only be transmitted over TLS protected
+		// connections.
+		RequireTLS bool `json:"require_tls,omitempty"`
+
+		// Unencoded indicates that the message has been rendered with NoEncoding and requires
+		// the 8BITMIME extension.
+		Unencoded bool `json:"unencoded,omitempty"`
+
+		// UnencodedFiles indicates that the message contains attachments or embeds with NoEncoding,
+		// which are transmitted with BODY=BINARYMIME if the server supports it.
+		UnencodedFiles bool `json:"unencoded_files,omitempty"`
+
+		// Enqueued is the time the message has been queued.
+		Enqueued time.Time `json:"enqueued"`
+
+		// Attempts is the number of delivery attempts that have been performed.
+		Attempts int `json:"attempts"`
+
+		// NextAttempt is the time the next delivery attempt is due.
+		NextAttempt time.Time `json:"next_attempt"`
+
+		// LastError is the error of the last delivery attempt, if it failed.
+		LastError string `json:"last_error,omitempty"`
+	}
+)
+
+// NewOutbox returns a new Outbox that stores its messages in the provided directory and delivers
+// them via the provided Client. The directory and its subdirectories are created if they do not
+// exist. Leftovers of an interrupted write or removal are cleaned up, while queued messages of a
+// previous Outbox are kept and delivered once the Outbox is started.
+//
+// Parameters:
+//   - client: A pointer to the Client used for the delivery.
+//   - dir: The root directory of the Outbox.
+//   - opts: Optional parameters for customizing the Outbox.
+//
+// Returns:
+//   - A pointer to the new Outbox.
+//   - An error if any option fails or the directories cannot be created.
+func NewOutbox(client *Client, dir string, opts ...OutboxOption) (*Outbox, error) {
+	if client == nil {
+		return nil, errors.New("no Client set for Outbox")
+	}
+	outbox := &Outbox{
+		client:       client,
+		dir:          dir,
+		inFlight:     make(map[string]struct{}),
+		jobs:         make(chan string),
+		pollInterval: DefaultOutboxPollInterval,
+		schedule:     DefaultOutboxRetrySchedule,
+		wake:         make(chan struct{}, 1),
+		workers:      DefaultOutboxWorkers,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(outbox); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	for _, subdir := range []string{outboxDirQueue, outboxDirDead, outboxDirTmp} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+		}
+	}
+	leftovers, err := os.ReadDir(filepath.Join(dir, outboxDirTmp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+	for _, leftover := range leftovers {
+		if err = os.RemoveAll(filepath.Join(dir, outboxDirTmp, leftover.Name())); err != nil {
+			return nil, fmt.Errorf("failed to clean up outbox directory: %w", err)
+		}
+	}
+	return outbox, nil
+}
+
+// WithOutboxWorkers sets the number of worker goroutines that deliver the messages of the Outbox
+// concurrently. Each worker establishes its own connection to the SMTP server.
+//
+// Parameters:
+//   - workers: The number of workers. Must be greater than zero.
+//
+// Returns:
+//   - An OutboxOption function that sets the number of workers for the Outbox.
+//   - An error if the number of workers is zero or negative.
+func WithOutboxWorkers(workers int) OutboxOption {
+	return func(o *Outbox) error {
+		if workers <= 0 {
+			return ErrInvalidOutboxWorkers
+		}
+		o.workers = workers
+		return nil
+	}
+}
+
+// WithOutboxPollInterval sets the interval in which the Outbox checks its queue for messages that
+// are due for delivery. Newly queued messages are picked up immediately.
+//
+// Parameters:
+//   - interval: The poll interval. Must be greater than zero.
+//
+// Returns:
+//   - An OutboxOption function that sets the poll interval for the Outbox.
+//   - An error if the poll interval is zero or negative.
+func WithOutboxPollInterval(interval time.Duration) OutboxOption {
+	return func(o *Outbox) error {
+		if interval <= 0 {
+			return ErrInvalidOutboxPollInterval
+		}
+		o.pollInterval = interval
+		return nil
+	}
+}
+
+// WithOutboxRetrySchedule sets the delays between the delivery attempts of a temporarily failed
+// message. After a failed attempt n, the next attempt is scheduled after the n-th delay. If the
+// attempt after the last delay fails as well, the message is moved to the dead-letter directory.
+// An empty schedule moves a message to the dead-letter directory after its first failed attempt.
+//
+// Parameters:
+//   - delays: The delays between the delivery attempts.
+//
+// Returns:
+//   - An OutboxOption function that sets the retry schedule for the Outbox.
+//   - An error if any of the delays is negative.
+func WithOutboxRetrySchedule(delays ...time.Duration) OutboxOption {
+	return func(o *Outbox) error {
+		for _, delay := range delays {
+			if delay < 0 {
+				return ErrInvalidOutboxRetrySchedule
+			}
+		}
+		o.schedule = slices.Clone(delays)
+		return nil
+	}
+}
+
+// Enqueue renders the provided messages and stores them in the queue of the Outbox. If the Client
+// of the Outbox has a DKIM configuration, the messages are signed with it while being rendered,
+// without changing the provided Msg. Changes to a Msg after it has been queued do not affect the
+// queued message.
+//
+// If the Outbox is running, the messages are delivered in the background. Each message is queued
+// independently, so if an error occurs, the messages before the failed one remain queued.
+//
+// Parameters:
+//   - messages: A variadic list of pointers to Msg objects to be queued.
+//
+// Returns:
+//   - The IDs of the queued entries, in the order of the messages.
+//   - An error if a message has no sender or recipients, or if it cannot be rendered or stored.
+func (o *Outbox) Enqueue(messages ...*Msg) ([]string, error) {
+	o.client.mutex.RLock()
+	clientDKIM := o.client.dkim
+	o.client.mutex.RUnlock()
+
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		id, err := o.enqueue(message, clientDKIM)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return ids, nil
+}
+
+// Start starts the dispatcher and the worker goroutines that deliver the queued messages. The
+// Outbox runs until the provided context is canceled or Stop is called.
+//
+// Parameters:
+//   - ctx: The context.Context that controls the lifetime of the workers.
+//
+// Returns:
+//   - An error if the Outbox is already running; otherwise, returns nil.
+func (o *Outbox) Start(ctx context.Context) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.stop != nil {
+		return ErrOutboxRunning
+	}
+	ctx, o.stop = context.WithCancel(ctx)
+
+	o.wg.Add(o.workers + 1)
+	go o.dispatch(ctx)
+	for range o.workers {
+		go o.work(ctx)
+	}
+	return nil
+}
+
+// Stop stops the Outbox and waits for the workers to finish. Deliveries that are in progress are
+// canceled and remain queued without counting as an attempt.
+//
+// Returns:
+//   - An error if the Outbox is not running; otherwise, returns nil.
+func (o *Outbox) Stop() error {
+	o.mutex.Lock()
+	stop := o.stop
+	o.mutex.Unlock()
+	if stop == nil {
+		return ErrOutboxNotRunning
+	}
+	stop()
+	o.wg.Wait()
+
+	o.mutex.Lock()
+	o.stop = nil
+	o.mutex.Unlock()
+	return nil
+}
+
+// Queued returns the entries in the queue of the Outbox, ordered by the time they have been queued.
+//
+// Returns:
+//   - A slice of the queued OutboxEntry.
+//   - An error if the queue cannot be read.
+func (o *Outbox) Queued() ([]OutboxEntry, error) {
+	return o.entries(outboxDirQueue)
+}
+
+// DeadLetters returns the entries in the dead-letter directory of the Outbox, ordered by the time
+// they have been queued. LastError holds the error that caused the message to be dead-lettered.
+//
+// Returns:
+//   - A slice of the dead-lettered OutboxEntry.
+//   - An error if the dead-letter directory cannot be read.
+func (o *Outbox) DeadLetters() ([]OutboxEntry, error) {
+	return o.entries(outboxDirDead)
+}
+
+// RawMessage returns the rendered message of the queued or dead-lettered entry with the given ID.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - The rendered message.
+//   - An error if the entry does not exist or cannot be read.
+func (o *Outbox) RawMessage(id string) ([]byte, error) {
+	for _, subdir := range []string{outboxDirQueue, outboxDirDead} {
+		data, err := os.ReadFile(filepath.Join(o.entryPath(subdir, id), outboxFileMessage))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read outbox message: %w", err)
+		}
+	}
+	return nil, ErrOutboxEntryNotFound
+}
+
+// Requeue moves the dead-lettered entry with the given ID back into the queue, resetting its
+// delivery attempts, so that it is delivered again right away.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - An error if the entry does not exist or cannot be moved.
+func (o *Outbox) Requeue(id string) error {
+	path := o.entryPath(outboxDirDead, id)
+	entry, err := readOutboxEntry(path)
+	if err != nil {
+		return err
+	}
+	entry.Attempts = 0
+	entry.NextAttempt = time.Now()
+	entry.LastError = ""
+	if err = writeOutboxEntry(path, entry); err != nil {
+		return err
+	}
+	if err = os.Rename(path, o.entryPath(outboxDirQueue, id)); err != nil {
+		return fmt.Errorf("failed to requeue outbox entry: %w", err)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Remove removes the queued or dead-lettered entry with the given ID from the Outbox.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - An error if the entry does not exist, is currently being delivered or cannot be removed.
+func (o *Outbox) Remove(id string) error {
+	o.mutex.Lock()
+	_, inFlight := o.inFlight[id]
+	o.mutex.Unlock()
+	if inFlight {
+		return ErrOutboxEntryInFlight
+	}
+	for _, subdir := range []string{outboxDirQueue, outboxDirDead} {
+		err := o.remove(subdir, id)
+		if err == nil || !errors.Is(err, ErrOutboxEntryNotFound) {
+			return err
+		}
+	}
+	return ErrOutboxEntryNotFound
+}
+
+// enqueue renders the provided Msg and stores it as a new entry in the queue.
+//
+// Parameters:
+//   - message: A pointer to the Msg to be queued.
+//   - signer: The DKIM signer of the Client, used instead of the DKIM configuration of the Msg. If
+//     nil, the DKIM configuration of the Msg is used.
+//
+// Returns:
+//   - The ID of the new entry.
+//   - An error if the Msg has no sender or recipients, or if it cannot be rendered or stored.
+func (o *Outbox) enqueue(message *Msg, signer *dkim.Signer) (string, error) {
+	from, err := message.GetSender(false)
+	if err != nil {
+		return "", err
+	}
+	rcpts, err := message.GetRecipients()
+	if err != nil {
+		return "", err
+	}
+	rendered := message
+	if signer != nil {
+		// The signer is set on a copy, so that the Msg of the caller remains unchanged
+		signed := *message
+		signed.dkim = signer
+		rendered = &signed
+	}
+	var buffer bytes.Buffer
+	if _, err = rendered.WriteTo(&buffer); err != nil {
+		return "", fmt.Errorf("failed to render message: %w", err)
+	}
+
+	random := make([]byte, 8)
+	if _, err = rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate outbox entry ID: %w", err)
+	}
+	now := time.Now()
+	entry := OutboxEntry{
+		ID:             fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(random)),
+		From:           strings.Trim(from, "<>"),
+		MessageID:      strings.Join(message.GetGenHeader(HeaderMessageID), ", "),
+		Subject:        strings.Join(message.GetGenHeader(HeaderSubject), " "),
+		Size:           int64(buffer.Len()),
+		EnvelopeID:     message.envelopeID,
+		OriginalRcpts:  message.originalRcpts,
+		RequireTLS:     message.requireTLS,
+		Unencoded:      message.encoding == NoEncoding,
+		UnencodedFiles: message.hasUnencodedFiles(),
+		Enqueued:       now,
+		NextAttempt:    now,
+	}
+	for _, rcpt := range rcpts {
+		entry.Rcpts = append(entry.Rcpts, strings.Trim(rcpt, "<>"))
+	}
+
+	tmpPath := o.entryPath(outboxDirTmp, entry.ID)
+	if err = os.Mkdir(tmpPath, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create outbox entry: %w", err)
+	}
+	if err = writeFileSync(filepath.Join(tmpPath, outboxFileMessage), buffer.Bytes()); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return "", err
+	}
+	if err = writeOutboxEntry(tmpPath, entry); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return "", err
+	}
+	if err = os.Rename(tmpPath, o.entryPath(outboxDirQueue, entry.ID)); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return "", fmt.Errorf("failed to queue outbox entry: %w", err)
+	}
+	syncDir(filepath.Join(o.dir, outboxDirQueue))
+	return entry.ID, nil
+}
+
+// dispatch hands out the entries that are due for delivery to the workers, whenever the poll
+// interval elapses or new entries have been queued, until the context is canceled.
+//
+// Parameters:
+//   - ctx: The context.Context that controls the lifetime of the dispatcher.
+func (o *Outbox) dispatch(ctx context.Context) {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	for {
+		entries, invalid, _ := o.readEntries(outboxDirQueue)
+		now := time.Now()
+		due := invalid
+		for _, entry := range entries {
+			if !entry.NextAttempt.After(now) {
+				due = append(due, entry.ID)
+			}
+		}
+		for _, id := range due {
+			if !o.claim(id) {
+				continue
+			}
+			select {
+			case o.jobs <- id:
+			case <-ctx.Done():
+				o.release(id)
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// work delivers the entries handed out by the dispatcher until the context is canceled.
+//
+// Parameters:
+//   - ctx: The context.Context that controls the lifetime of the worker.
+func (o *Outbox) work(ctx context.Context) {
+	defer o.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-o.jobs:
+			o.deliver(ctx, id)
+			o.release(id)
+		}
+	}
+}
+
+// deliver performs a delivery attempt for the queued entry with the given ID. A delivered entry is
+// removed from the queue. A failed entry is either rescheduled or moved to the dead-letter
+// directory, depending on the error and the retry schedule. After a partial delivery, only the
+// rejected recipients are kept in the entry, which is rescheduled if any of them has been rejected
+// temporarily. An entry whose metadata or message cannot be read is moved to the dead-letter
+// directory right away.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - id: The ID of the entry.
+func (o *Outbox) deliver(ctx context.Context, id string) {
+	path := o.entryPath(outboxDirQueue, id)
+	entry, err := readOutboxEntry(path)
+	if errors.Is(err, ErrOutboxEntryNotFound) {
+		// The entry has been removed in the meantime
+		return
+	}
+	if err != nil {
+		o.deadLetterInvalid(id, err)
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(path, outboxFileMessage))
+	if err != nil {
+		o.deadLetter(entry, fmt.Errorf("failed to read outbox message: %w", err))
+		return
+	}
+	message, err := entry.message(data)
+	if err == nil {
+		err = o.client.dialAndSend(ctx, []*Msg{message})
+	}
+	if message != nil && message.isDelivered {
+		_ = o.remove(outboxDirQueue, id)
+		return
+	}
+	if ctx.Err() != nil {
+		// The Outbox has been stopped, the entry will be delivered on the next start
+		return
+	}
+
+	msgErr := err
+	if message != nil && message.sendError != nil {
+		msgErr = message.sendError
+	}
+	entry.Attempts++
+	var sendErr *SendError
+	permanent := errors.As(msgErr, &sendErr) && !isRetryableError(msgErr)
+	if sendErr != nil && sendErr.Reason == ErrPartialDelivery {
+		// The accepted recipients must not receive the message again on a retry or requeue
+		permanent = entry.keepRejectedRcpts(sendErr)
+	}
+	if permanent || entry.Attempts > len(o.schedule) {
+		o.deadLetter(entry, msgErr)
+		return
+	}
+	entry.LastError = msgErr.Error()
+	entry.NextAttempt = time.Now().Add(o.schedule[entry.Attempts-1])
+	_ = writeOutboxEntry(path, entry)
+}
+
+// deadLetter records the given error as the LastError of the queued entry and moves the entry
+// into the dead-letter directory.
+//
+// Parameters:
+//   - entry: The OutboxEntry to be dead-lettered.
+//   - err: The error that caused the entry to be dead-lettered.
+func (o *Outbox) deadLetter(entry OutboxEntry, err error) {
+	path := o.entryPath(outboxDirQueue, entry.ID)
+	entry.LastError = err.Error()
+	if err = writeOutboxEntry(path, entry); err != nil {
+		return
+	}
+	_ = os.Rename(path, o.entryPath(outboxDirDead, entry.ID))
+}
+
+// deadLetterInvalid moves the queued entry with the given ID, whose metadata cannot be read, into
+// the dead-letter directory. The unreadable metadata is kept with the suffix ".invalid" and
+// replaced by an OutboxEntry holding the ID and the given error, so that the entry is listed by
+// DeadLetters.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//   - err: The error that occurred while reading the metadata.
+func (o *Outbox) deadLetterInvalid(id string, err error) {
+	path := o.entryPath(outboxDirQueue, id)
+	entry := OutboxEntry{ID: filepath.Base(path), Enqueued: time.Now()}
+	if info, serr := os.Stat(path); serr == nil {
+		entry.Enqueued = info.ModTime()
+	}
+	metaFile := filepath.Join(path, outboxFileMeta)
+	if rerr := os.Rename(metaFile, metaFile+".invalid"); rerr != nil && !errors.Is(rerr, os.ErrNotExist) {
+		return
+	}
+	o.deadLetter(entry, err)
+}
+
+// claim marks the entry with the given ID as being delivered.
+//
+// Parameters:
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - true if the entry has been claimed, false if it is already being delivered.
+func (o *Outbox) claim(id string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.inFlight[id]; ok {
+		return false
+	}
+	o.inFlight[id] = struct{}{}
+	return true
+}
+
+// release removes the mark of the entry with the given ID as being delivered.
+//
+// Parameters:
+//   - id: The ID of the entry.
+func (o *Outbox) release(id string) {
+	o.mutex.Lock()
+	delete(o.inFlight, id)
+	o.mutex.Unlock()
+}
+
+// entries returns the entries in the given subdirectory of the Outbox, ordered by the time they
+// have been queued. Entries with unreadable metadata are skipped.
+//
+// Parameters:
+//   - subdir: The subdirectory of the Outbox.
+//
+// Returns:
+//   - A slice of the OutboxEntry.
+//   - An error if the subdirectory cannot be read.
+func (o *Outbox) entries(subdir string) ([]OutboxEntry, error) {
+	entries, _, err := o.readEntries(subdir)
+	return entries, err
+}
+
+// readEntries reads the entries in the given subdirectory of the Outbox, ordered by the time they
+// have been queued, as well as the IDs of the entries with unreadable metadata.
+//
+// Parameters:
+//   - subdir: The subdirectory of the Outbox.
+//
+// Returns:
+//   - A slice of the OutboxEntry.
+//   - A slice of the IDs of the entries with unreadable metadata.
+//   - An error if the subdirectory cannot be read.
+func (o *Outbox) readEntries(subdir string) ([]OutboxEntry, []string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(o.dir, subdir))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read outbox directory: %w", err)
+	}
+	entries := make([]OutboxEntry, 0, len(dirEntries))
+	var invalid []string
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		entry, rerr := readOutboxEntry(o.entryPath(subdir, dirEntry.Name()))
+		if errors.Is(rerr, ErrOutboxEntryNotFound) {
+			continue
+		}
+		if rerr != nil {
+			invalid = append(invalid, dirEntry.Name())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b OutboxEntry) int {
+		return a.Enqueued.Compare(b.Enqueued)
+	})
+	return entries, invalid, nil
+}
+
+// remove removes the entry with the given ID from the given subdirectory. The entry is moved to
+// the temporary directory first, so that it disappears from the subdirectory at once.
+//
+// Parameters:
+//   - subdir: The subdirectory of the Outbox.
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - An error if the entry does not exist or cannot be removed.
+func (o *Outbox) remove(subdir, id string) error {
+	tmpPath := o.entryPath(outboxDirTmp, id)
+	if err := os.Rename(o.entryPath(subdir, id), tmpPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrOutboxEntryNotFound
+		}
+		return fmt.Errorf("failed to remove outbox entry: %w", err)
+	}
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return fmt.Errorf("failed to remove outbox entry: %w", err)
+	}
+	return nil
+}
+
+// entryPath returns the path of the entry with the given ID in the given subdirectory. The ID is
+// reduced to its base name, so that it cannot point outside the subdirectory.
+//
+// Parameters:
+//   - subdir: The subdirectory of the Outbox.
+//   - id: The ID of the entry.
+//
+// Returns:
+//   - The path of the entry.
+func (o *Outbox) entryPath(subdir, id string) string {
+	return filepath.Join(o.dir, subdir, filepath.Base(filepath.Clean("/"+id)))
+}
+
+// keepRejectedRcpts reduces the recipients of the OutboxEntry to the recipients that have been
+// rejected in a partial delivery, so that a retry or requeue does not deliver the message to the
+// accepted recipients again.
+//
+// Parameters:
+//   - sendErr: The SendError with the ErrPartialDelivery reason, listing the rejected recipients.
+//
+// Returns:
+//   - true if all recipients have been rejected permanently, false if any of them has been
+//     rejected temporarily.
+func (e *OutboxEntry) keepRejectedRcpts(sendErr *SendError) bool {
+	rcpts := make([]string, 0, len(sendErr.rcpt))
+	for _, rcpt := range sendErr.rcpt {
+		rcpts = append(rcpts, strings.Trim(rcpt, "<>"))
+	}
+	e.Rcpts = rcpts
+
+	for _, err := range sendErr.errlist {
+		if isTempError(err) {
+			return false
+		}
+	}
+	return true
+}
+
+// message restores a Msg from the OutboxEntry and the rendered message, that is written as is
+// when the Msg is sent. The encoding of the message and its files is restored from the
+// OutboxEntry, so that the Client chooses the same BODY parameter as for the original Msg.
+//
+// Parameters:
+//   - data: The rendered message.
+//
+// Returns:
+//   - A pointer to the restored Msg.
+//   - An error if the envelope addresses of the OutboxEntry are invalid.
+func (e OutboxEntry) message(data []byte) (*Msg, error) {
+	message := NewMsg()
+	message.outboxMessage = &outboxMessage{data: data, unencodedFiles: e.UnencodedFiles}
+	if err := message.EnvelopeFrom(e.From); err != nil {
+		return nil, err
+	}
+	// The recipients are only used for the envelope, since the Msg is not rendered again
+	if err := message.Bcc(e.Rcpts...); err != nil {
+		return nil, err
+	}
+	message.envelopeID = e.EnvelopeID
+	message.originalRcpts = e.OriginalRcpts
+	message.requireTLS = e.RequireTLS
+	if e.Unencoded {
+		message.encoding = NoEncoding
+	}
+	return message, nil
+}
+
+// outboxMessage is the rendered message of an OutboxEntry. It is attached to the Msg restored from
+// the OutboxEntry, which only holds the envelope, so that the Client transmits the message as it
+// has been rendered when it was queued.
+type outboxMessage struct {
+	// data holds the rendered message.
+	data []byte
+
+	// unencodedFiles indicates that the message contains attachments or embeds with NoEncoding.
+	unencodedFiles bool
+}
+
+// WriteTo writes the rendered message into the given io.Writer and satisfies the io.WriterTo
+// interface.
+//
+// Parameters:
+//   - w: The io.Writer to which the message is written.
+//
+// Returns:
+//   - The number of bytes written.
+//   - An error if writing the message failed; otherwise, returns nil.
+func (m *outboxMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(m.data)
+	return int64(n), err
+}
+
+// messageWriter returns the io.WriterTo that writes the provided Msg for the transmission. For a
+// Msg restored from an Outbox, this is its rendered message, otherwise the Msg itself.
+//
+// Parameters:
+//   - message: A pointer to the Msg to be transmitted.
+//
+// Returns:
+//   - The io.WriterTo for the transmission of the Msg.
+func messageWriter(message *Msg) io.WriterTo {
+	if message.outboxMessage != nil {
+		return message.outboxMessage
+	}
+	return message
+}
+
+// readOutboxEntry reads the OutboxEntry metadata of the entry at the given path.
+//
+// Parameters:
+//   - path: The path of the entry.
+//
+// Returns:
+//   - The OutboxEntry.
+//   - An error if the entry does not exist or its metadata cannot be read.
+func readOutboxEntry(path string) (OutboxEntry, error) {
+	var entry OutboxEntry
+	data, err := os.ReadFile(filepath.Join(path, outboxFileMeta))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entry, ErrOutboxEntryNotFound
+		}
+		return entry, fmt.Errorf("failed to read outbox entry: %w", err)
+	}
+	if err = json.Unmarshal(data, &entry); err != nil {
+		return entry, fmt.Errorf("failed to decode outbox entry: %w", err)
+	}
+	return entry, nil
+}
+
+// writeOutboxEntry atomically replaces the OutboxEntry metadata of the entry at the given path.
+//
+// Parameters:
+//   - path: The path of the entry.
+//   - entry: The OutboxEntry to be written.
+//
+// Returns:
+//   - An error if the metadata cannot be written.
+func writeOutboxEntry(path string, entry OutboxEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+	tmpFile := filepath.Join(path, outboxFileMeta+".tmp")
+	if err = writeFileSync(tmpFile, data); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFile, filepath.Join(path, outboxFileMeta)); err != nil {
+		_ = os.Remove(tmpFile)
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// writeFileSync writes the given data into a new file and flushes it to the disk.
+//
+// Parameters:
+//   - name: The name of the file.
+//   - data: The data to be written.
+//
+// Returns:
+//   - An error if the file cannot be written.
+func writeFileSync(name string, data []byte) error {
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox file: %w", err)
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync outbox file: %w", err)
+	}
+	return file.Close()
+}
+
+// syncDir flushes the given directory to the disk, so that a rename within it is persisted. Errors
+// are ignored, since not all platforms support syncing directories.
+//
+// Parameters:
+//   - dir: The path of the directory.
+func syncDir(dir string) {
+	handle, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = handle.Sync()
+	_ = handle.Close()
+}
@@ -0,0 +1,566 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wneessen/go-mail/smtptest"
+)
+
+func TestNewOutbox(t *testing.T) {
+	client, err := NewClient(DefaultHost)
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	t.Run("new outbox creates directories", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "outbox")
+		if _, err := NewOutbox(client, dir); err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		for _, subdir := range []string{outboxDirQueue, outboxDirDead, outboxDirTmp} {
+			if _, err := os.Stat(filepath.Join(dir, subdir)); err != nil {
+				t.Errorf("expected outbox directory %s to exist: %s", subdir, err)
+			}
+		}
+	})
+	t.Run("new outbox cleans up leftovers", func(t *testing.T) {
+		dir := t.TempDir()
+		leftover := filepath.Join(dir, outboxDirTmp, "leftover")
+		if err := os.MkdirAll(leftover, 0o750); err != nil {
+			t.Fatalf("failed to create leftover: %s", err)
+		}
+		if _, err := NewOutbox(client, dir); err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		if _, err := os.Stat(leftover); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected leftover to be removed, got: %s", err)
+		}
+	})
+	t.Run("new outbox without client fails", func(t *testing.T) {
+		if _, err := NewOutbox(nil, t.TempDir()); err == nil {
+			t.Error("expected NewOutbox without client to fail")
+		}
+	})
+	t.Run("new outbox with nil option", func(t *testing.T) {
+		if _, err := NewOutbox(client, t.TempDir(), nil); err != nil {
+			t.Errorf("failed to create outbox: %s", err)
+		}
+	})
+	t.Run("new outbox fails on file path", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatalf("failed to create file: %s", err)
+		}
+		if _, err := NewOutbox(client, file); err == nil {
+			t.Error("expected NewOutbox to fail on file path")
+		}
+	})
+	tests := []struct {
+		name   string
+		option OutboxOption
+		want   error
+	}{
+		{"zero workers", WithOutboxWorkers(0), ErrInvalidOutboxWorkers},
+		{"negative workers", WithOutboxWorkers(-1), ErrInvalidOutboxWorkers},
+		{"zero poll interval", WithOutboxPollInterval(0), ErrInvalidOutboxPollInterval},
+		{"negative retry delay", WithOutboxRetrySchedule(time.Second, -time.Second), ErrInvalidOutboxRetrySchedule},
+	}
+	for _, tt := range tests {
+		t.Run("new outbox with "+tt.name+" fails", func(t *testing.T) {
+			if _, err := NewOutbox(client, t.TempDir(), tt.option); !errors.Is(err, tt.want) {
+				t.Errorf("expected error %s, got: %s", tt.want, err)
+			}
+		})
+	}
+	t.Run("new outbox with options", func(t *testing.T) {
+		outbox, err := NewOutbox(client, t.TempDir(), WithOutboxWorkers(5),
+			WithOutboxPollInterval(time.Second), WithOutboxRetrySchedule(time.Minute))
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		if outbox.workers != 5 {
+			t.Errorf("expected 5 workers, got: %d", outbox.workers)
+		}
+		if outbox.pollInterval != time.Second {
+			t.Errorf("expected poll interval of 1s, got: %s", outbox.pollInterval)
+		}
+		if len(outbox.schedule) != 1 || outbox.schedule[0] != time.Minute {
+			t.Errorf("unexpected retry schedule: %v", outbox.schedule)
+		}
+	})
+}
+
+func TestOutbox_Enqueue(t *testing.T) {
+	client, err := NewClient(DefaultHost)
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	t.Run("enqueue stores messages", func(t *testing.T) {
+		outbox, err := NewOutbox(client, t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		message := testMessage(t)
+		if err = message.Cc("tina.tester@example.com"); err != nil {
+			t.Fatalf("failed to set cc address: %s", err)
+		}
+		ids, err := outbox.Enqueue(message, nil, testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to enqueue messages: %s", err)
+		}
+		if len(ids) != 2 {
+			t.Fatalf("expected 2 IDs, got: %d", len(ids))
+		}
+		queued, err := outbox.Queued()
+		if err != nil {
+			t.Fatalf("failed to read queue: %s", err)
+		}
+		if len(queued) != 2 {
+			t.Fatalf("expected 2 queued entries, got: %d", len(queued))
+		}
+		entry := queued[0]
+		if entry.ID != ids[0] {
+			t.Errorf("expected ID %s, got: %s", ids[0], entry.ID)
+		}
+		if entry.From != TestSenderValid {
+			t.Errorf("expected sender %s, got: %s", TestSenderValid, entry.From)
+		}
+		if strings.Join(entry.Rcpts, ",") != TestRcptValid+",tina.tester@example.com" {
+			t.Errorf("unexpected recipients: %v", entry.Rcpts)
+		}
+		if entry.Subject != "Testmail" {
+			t.Errorf("expected subject Testmail, got: %s", entry.Subject)
+		}
+		if entry.Attempts != 0 || entry.LastError != "" {
+			t.Errorf("unexpected delivery state: %+v", entry)
+		}
+
+		message.Subject("Changed after enqueue")
+		data, err := outbox.RawMessage(ids[0])
+		if err != nil {
+			t.Fatalf("failed to read raw message: %s", err)
+		}
+		if !bytes.Contains(data, []byte("Subject: Testmail")) {
+			t.Errorf("expected rendered message, got: %s", data)
+		}
+		if int64(len(data)) != entry.Size {
+			t.Errorf("expected size %d, got: %d", len(data), entry.Size)
+		}
+	})
+	t.Run("encoding of files is restored", func(t *testing.T) {
+		outbox, err := NewOutbox(client, t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		message := testMessage(t)
+		if err = message.AttachReader("binary.bin", bytes.NewReader([]byte("binary\x00data")),
+			WithFileEncoding(NoEncoding)); err != nil {
+			t.Fatalf("failed to attach binary data: %s", err)
+		}
+		ids, err := outbox.Enqueue(message, testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to enqueue messages: %s", err)
+		}
+		for i, want := range []bool{true, false} {
+			path := outbox.entryPath(outboxDirQueue, ids[i])
+			entry, err := readOutboxEntry(path)
+			if err != nil {
+				t.Fatalf("failed to read outbox entry: %s", err)
+			}
+			if entry.UnencodedFiles != want {
+				t.Errorf("expected unencoded files to be %t, got: %t", want, entry.UnencodedFiles)
+			}
+			data, err := os.ReadFile(filepath.Join(path, outboxFileMessage))
+			if err != nil {
+				t.Fatalf("failed to read outbox message: %s", err)
+			}
+			restored, err := entry.message(data)
+			if err != nil {
+				t.Fatalf("failed to restore message: %s", err)
+			}
+			if restored.hasUnencodedFiles() != want {
+				t.Errorf("expected restored message to have unencoded files: %t", want)
+			}
+		}
+	})
+	t.Run("DKIM signer of the client is applied without changing the message", func(t *testing.T) {
+		privKey, err := PrivKeyFromPEM(testKeyRSA)
+		if err != nil {
+			t.Fatalf("failed to parse test key: %s", err)
+		}
+		signingClient, err := NewClient(DefaultHost,
+			WithAlwaysDKIMSign(NewDKIMSigner(testDomain, testSelector, privKey)))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		outbox, err := NewOutbox(signingClient, t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		message := testMessage(t)
+		ids, err := outbox.Enqueue(message)
+		if err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		data, err := outbox.RawMessage(ids[0])
+		if err != nil {
+			t.Fatalf("failed to read raw message: %s", err)
+		}
+		if !bytes.HasPrefix(data, []byte("DKIM-Signature:")) {
+			t.Errorf("expected DKIM signed message, got: %s", data)
+		}
+		if message.hasDKIM() {
+			t.Error("expected DKIM configuration of the message to be unchanged")
+		}
+	})
+	t.Run("enqueue without sender fails", func(t *testing.T) {
+		outbox, err := NewOutbox(client, t.TempDir())
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		message := NewMsg()
+		if err = message.To(TestRcptValid); err != nil {
+			t.Fatalf("failed to set recipient address: %s", err)
+		}
+		if _, err = outbox.Enqueue(message); err == nil {
+			t.Error("expected enqueue without sender to fail")
+		}
+		queued, err := outbox.Queued()
+		if err != nil {
+			t.Fatalf("failed to read queue: %s", err)
+		}
+		if len(queued) != 0 {
+			t.Errorf("expected empty queue, got: %d entries", len(queued))
+		}
+	})
+}
+
+func TestOutbox_Start(t *testing.T) {
+	t.Run("queued messages are delivered", func(t *testing.T) {
+		server, outbox := testOutbox(t)
+		message := testMessage(t)
+		if _, err := outbox.Enqueue(message); err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+		testOutboxWait(t, func() bool { return len(server.Messages()) == 1 })
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.Queued) == 0 })
+
+		received := server.Messages()[0]
+		if received.From != TestSenderValid {
+			t.Errorf("expected sender %s, got: %s", TestSenderValid, received.From)
+		}
+		if !bytes.Contains(received.Data, []byte("Subject: Testmail")) {
+			t.Errorf("expected message data, got: %s", received.Data)
+		}
+	})
+	t.Run("temporary failure is retried", func(t *testing.T) {
+		server, outbox := testOutbox(t, WithOutboxRetrySchedule(200*time.Millisecond, 200*time.Millisecond))
+		server.SetReply("MAIL", "451 4.3.0 Temporary failure")
+		if _, err := outbox.Enqueue(testMessage(t)); err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+		testOutboxWait(t, func() bool {
+			queued, err := outbox.Queued()
+			return err == nil && len(queued) == 1 && queued[0].Attempts >= 1
+		})
+		server.SetReply("MAIL", "")
+		testOutboxWait(t, func() bool { return len(server.Messages()) == 1 })
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.Queued) == 0 })
+		if testOutboxLen(t, outbox.DeadLetters) != 0 {
+			t.Error("expected no dead letters")
+		}
+	})
+	t.Run("permanent failure is dead-lettered and can be requeued", func(t *testing.T) {
+		server, outbox := testOutbox(t)
+		server.SetReply("RCPT", "550 5.1.1 User unknown")
+		ids, err := outbox.Enqueue(testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.DeadLetters) == 1 })
+		dead, err := outbox.DeadLetters()
+		if err != nil {
+			t.Fatalf("failed to read dead letters: %s", err)
+		}
+		if dead[0].Attempts != 1 {
+			t.Errorf("expected 1 attempt, got: %d", dead[0].Attempts)
+		}
+		if !strings.Contains(dead[0].LastError, "User unknown") {
+			t.Errorf("expected last error to contain the server reply, got: %s", dead[0].LastError)
+		}
+		if _, err = outbox.RawMessage(ids[0]); err != nil {
+			t.Errorf("failed to read raw message of dead letter: %s", err)
+		}
+
+		server.SetReply("RCPT", "")
+		if err = outbox.Requeue(ids[0]); err != nil {
+			t.Fatalf("failed to requeue message: %s", err)
+		}
+		testOutboxWait(t, func() bool { return len(server.Messages()) == 1 })
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.Queued) == 0 })
+		if testOutboxLen(t, outbox.DeadLetters) != 0 {
+			t.Error("expected no dead letters")
+		}
+	})
+	t.Run("partial delivery keeps only the rejected recipients", func(t *testing.T) {
+		server, err := smtptest.NewServer()
+		if err != nil {
+			t.Fatalf("failed to start test server: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = server.Close()
+		})
+		client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS),
+			WithPartialDelivery())
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		outbox, err := NewOutbox(client, t.TempDir(), WithOutboxPollInterval(10*time.Millisecond),
+			WithOutboxRetrySchedule(200*time.Millisecond, 200*time.Millisecond))
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		var reply atomic.Value
+		reply.Store("450 4.2.1 Mailbox busy")
+		server.SetReplyFunc("RCPT", func(arg string) string {
+			if arg == "TO:<unknown@example.com>" {
+				return reply.Load().(string)
+			}
+			return ""
+		})
+		message := testMessage(t)
+		if err = message.AddTo("unknown@example.com"); err != nil {
+			t.Fatalf("failed to add recipient address: %s", err)
+		}
+		ids, err := outbox.Enqueue(message)
+		if err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+
+		testOutboxWait(t, func() bool {
+			queued, err := outbox.Queued()
+			return err == nil && len(queued) == 1 && queued[0].Attempts >= 1
+		})
+		queued, err := outbox.Queued()
+		if err != nil {
+			t.Fatalf("failed to read queued entries: %s", err)
+		}
+		if !slices.Equal(queued[0].Rcpts, []string{"unknown@example.com"}) {
+			t.Errorf("expected only the rejected recipient to be queued, got: %v", queued[0].Rcpts)
+		}
+
+		reply.Store("550 5.1.1 User unknown")
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.DeadLetters) == 1 })
+		dead, err := outbox.DeadLetters()
+		if err != nil {
+			t.Fatalf("failed to read dead letters: %s", err)
+		}
+		if !slices.Equal(dead[0].Rcpts, []string{"unknown@example.com"}) {
+			t.Errorf("expected only the rejected recipient to be dead-lettered, got: %v", dead[0].Rcpts)
+		}
+
+		reply.Store("")
+		if err = outbox.Requeue(ids[0]); err != nil {
+			t.Fatalf("failed to requeue message: %s", err)
+		}
+		testOutboxWait(t, func() bool { return len(server.Messages()) == 2 })
+		messages := server.Messages()
+		for i, want := range []string{TestRcptValid, "unknown@example.com"} {
+			if len(messages[i].Rcpts) != 1 || messages[i].Rcpts[0].Address != want {
+				t.Errorf("expected delivery %d to recipient %s only, got: %v", i+1, want, messages[i].Rcpts)
+			}
+		}
+	})
+	t.Run("unreadable entries are dead-lettered", func(t *testing.T) {
+		server, outbox := testOutbox(t)
+		ids, err := outbox.Enqueue(testMessage(t), testMessage(t))
+		if err != nil {
+			t.Fatalf("failed to enqueue messages: %s", err)
+		}
+		metaFile := filepath.Join(outbox.entryPath(outboxDirQueue, ids[0]), outboxFileMeta)
+		if err = os.WriteFile(metaFile, []byte("{"), 0o600); err != nil {
+			t.Fatalf("failed to corrupt metadata: %s", err)
+		}
+		messageFile := filepath.Join(outbox.entryPath(outboxDirQueue, ids[1]), outboxFileMessage)
+		if err = os.Remove(messageFile); err != nil {
+			t.Fatalf("failed to remove message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.DeadLetters) == 2 })
+		if testOutboxLen(t, outbox.Queued) != 0 {
+			t.Error("expected empty queue")
+		}
+		dead, err := outbox.DeadLetters()
+		if err != nil {
+			t.Fatalf("failed to read dead letters: %s", err)
+		}
+		lastErrors := make(map[string]string, len(dead))
+		for _, entry := range dead {
+			lastErrors[entry.ID] = entry.LastError
+		}
+		if !strings.Contains(lastErrors[ids[0]], "failed to decode outbox entry") {
+			t.Errorf("expected last error to contain the metadata error, got: %s", lastErrors[ids[0]])
+		}
+		if !strings.Contains(lastErrors[ids[1]], "failed to read outbox message") {
+			t.Errorf("expected last error to contain the message error, got: %s", lastErrors[ids[1]])
+		}
+		invalidFile := filepath.Join(outbox.entryPath(outboxDirDead, ids[0]), outboxFileMeta+".invalid")
+		if _, err = os.Stat(invalidFile); err != nil {
+			t.Errorf("expected unreadable metadata to be kept: %s", err)
+		}
+		if len(server.Messages()) != 0 {
+			t.Errorf("expected no messages on the test server, got: %d", len(server.Messages()))
+		}
+	})
+	t.Run("unreachable server is dead-lettered after retry schedule", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %s", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		_ = listener.Close()
+		client, err := NewClient("127.0.0.1", WithPort(port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		outbox, err := NewOutbox(client, t.TempDir(), WithOutboxRetrySchedule(0, 0),
+			WithOutboxPollInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		if _, err = outbox.Enqueue(testMessage(t)); err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		testOutboxStart(t, outbox)
+		testOutboxWait(t, func() bool { return testOutboxLen(t, outbox.DeadLetters) == 1 })
+		dead, err := outbox.DeadLetters()
+		if err != nil {
+			t.Fatalf("failed to read dead letters: %s", err)
+		}
+		if dead[0].Attempts != 3 {
+			t.Errorf("expected 3 attempts, got: %d", dead[0].Attempts)
+		}
+	})
+	t.Run("queue persists across outboxes", func(t *testing.T) {
+		server, outbox := testOutbox(t)
+		if _, err := outbox.Enqueue(testMessage(t)); err != nil {
+			t.Fatalf("failed to enqueue message: %s", err)
+		}
+		restored, err := NewOutbox(outbox.client, outbox.dir, WithOutboxPollInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatalf("failed to create outbox: %s", err)
+		}
+		testOutboxStart(t, restored)
+		testOutboxWait(t, func() bool { return len(server.Messages()) == 1 })
+	})
+	t.Run("start running outbox fails", func(t *testing.T) {
+		_, outbox := testOutbox(t)
+		testOutboxStart(t, outbox)
+		if err := outbox.Start(t.Context()); !errors.Is(err, ErrOutboxRunning) {
+			t.Errorf("expected ErrOutboxRunning, got: %s", err)
+		}
+	})
+	t.Run("stop outbox that is not running fails", func(t *testing.T) {
+		_, outbox := testOutbox(t)
+		if err := outbox.Stop(); !errors.Is(err, ErrOutboxNotRunning) {
+			t.Errorf("expected ErrOutboxNotRunning, got: %s", err)
+		}
+	})
+}
+
+func TestOutbox_Remove(t *testing.T) {
+	_, outbox := testOutbox(t)
+	ids, err := outbox.Enqueue(testMessage(t))
+	if err != nil {
+		t.Fatalf("failed to enqueue message: %s", err)
+	}
+	if err = outbox.Remove(ids[0]); err != nil {
+		t.Fatalf("failed to remove entry: %s", err)
+	}
+	if testOutboxLen(t, outbox.Queued) != 0 {
+		t.Error("expected empty queue")
+	}
+	if err = outbox.Remove(ids[0]); !errors.Is(err, ErrOutboxEntryNotFound) {
+		t.Errorf("expected ErrOutboxEntryNotFound, got: %s", err)
+	}
+	if _, err = outbox.RawMessage(ids[0]); !errors.Is(err, ErrOutboxEntryNotFound) {
+		t.Errorf("expected ErrOutboxEntryNotFound, got: %s", err)
+	}
+	if err = outbox.Requeue(ids[0]); !errors.Is(err, ErrOutboxEntryNotFound) {
+		t.Errorf("expected ErrOutboxEntryNotFound, got: %s", err)
+	}
+	if err = outbox.Remove("../../etc"); !errors.Is(err, ErrOutboxEntryNotFound) {
+		t.Errorf("expected ErrOutboxEntryNotFound, got: %s", err)
+	}
+}
+
+// testOutbox starts a test server and returns it together with a new Outbox that delivers to it.
+func testOutbox(t *testing.T, opts ...OutboxOption) (*smtptest.Server, *Outbox) {
+	t.Helper()
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	opts = append([]OutboxOption{WithOutboxPollInterval(10 * time.Millisecond)}, opts...)
+	outbox, err := NewOutbox(client, t.TempDir(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create outbox: %s", err)
+	}
+	return server, outbox
+}
+
+// testOutboxStart starts the given Outbox and stops it when the test finishes.
+func testOutboxStart(t *testing.T, outbox *Outbox) {
+	t.Helper()
+	if err := outbox.Start(t.Context()); err != nil {
+		t.Fatalf("failed to start outbox: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = outbox.Stop()
+	})
+}
+
+// testOutboxWait waits until the given condition is met or fails the test after a timeout.
+func testOutboxWait(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for outbox")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testOutboxLen returns the number of entries returned by the given Outbox listing.
+func testOutboxLen(t *testing.T, list func() ([]OutboxEntry, error)) int {
+	t.Helper()
+	entries, err := list()
+	if err != nil {
+		t.Fatalf("failed to list outbox entries: %s", err)
+	}
+	return len(entries)
+}
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"net/textproto"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+// WithPartialDelivery enables partial delivery for the Client.
+//
+// By default, the Client aborts the mail transaction as soon as any of the recipients is rejected
+// by the server. With partial delivery enabled, the Client proceeds with the message data as long
+// as at least one recipient has been accepted. The replies of the server for the accepted and the
+// rejected recipients are available via Msg.RcptResults. If any of the recipients have been
+// rejected, the SendError of the Msg has the ErrPartialDelivery reason and lists the rejected
+// recipients. If all recipients are rejected, the mail transaction is aborted as usual.
+//
+// Returns:
+//   - An Option function that enables partial delivery for the Client.
+func WithPartialDelivery() Option {
+	return func(c *Client) error {
+		c.partialDelivery = true
+		return nil
+	}
+}
+
+// acceptPartialRcpts stores the replies of the server for the RCPT commands as RcptResults in the
+// provided Msg and reports whether the mail transaction can proceed with a partial delivery.
+//
+// Parameters:
+//   - message: A pointer to the Msg that is being sent.
+//   - responses: The replies of the server for the RCPT commands.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - A boolean value indicating whether the mail transaction can proceed with the accepted
+//     recipients. It is false if partial delivery is disabled or no recipient has been accepted.
+func (c *Client) acceptPartialRcpts(message *Msg, responses []smtp.RcptResponse, escSupport bool) bool {
+	accepted := false
+	message.rcptResults = make([]RcptResult, 0, len(responses))
+	for _, response := range responses {
+		message.rcptResults = append(message.rcptResults, newRcptResult(response, escSupport))
+		if response.Err == nil {
+			accepted = true
+		}
+	}
+	return accepted && c.partialDelivery
+}
+
+// partialSendError returns a SendError for the recipients that have been rejected in a partial
+// delivery of the provided Msg.
+//
+// Parameters:
+//   - message: A pointer to the Msg that has been sent.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - A SendError with the reason ErrPartialDelivery, or nil if no recipient has been rejected.
+//     The SendError is never temporary, as a retry would deliver the Msg to the accepted
+//     recipients again.
+func partialSendError(message *Msg, escSupport bool) *SendError {
+	var errs []error
+	var failedRcpts []string
+	for _, result := range message.rcptResults {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+			failedRcpts = append(failedRcpts, result.Rcpt)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &SendError{
+		Reason: ErrPartialDelivery, errlist: errs, rcpt: failedRcpts, isTemp: false,
+		affectedMsg: message, errcode: errorCode(errs[0]),
+		enhancedStatusCode: enhancedStatusCode(errs[0], escSupport),
+	}
+}
+
+// newRcptResult converts the reply of the server for a single recipient into a RcptResult.
+//
+// Parameters:
+//   - response: The reply of the server for the recipient.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The RcptResult for the recipient.
+func newRcptResult(response smtp.RcptResponse, escSupport bool) RcptResult {
+	reply := &textproto.Error{Code: response.Code, Msg: response.Msg}
+	return RcptResult{
+		Rcpt:               response.Rcpt,
+		Code:               response.Code,
+		EnhancedStatusCode: enhancedStatusCode(reply, escSupport),
+		Response:           response.Msg,
+		Err:                response.Err,
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithPartialDelivery(t *testing.T) {
+	client, err := NewClient(DefaultHost, WithPartialDelivery())
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	if !client.partialDelivery {
+		t.Error("expected partial delivery to be enabled")
+	}
+}
+
+func TestClient_Send_partialDelivery(t *testing.T) {
+	const invalidRcpt = "invalid-to@domain.tld"
+	tests := []struct {
+		name       string
+		featureSet string
+	}{
+		{"without pipelining", "250-8BITMIME\r\n250-ENHANCEDSTATUSCODES\r\n250 SMTPUTF8"},
+		{"with pipelining", "250-8BITMIME\r\n250-ENHANCEDSTATUSCODES\r\n250-PIPELINING\r\n250 SMTPUTF8"},
+		{
+			"with pipelining and chunking",
+			"250-8BITMIME\r\n250-ENHANCEDSTATUSCODES\r\n250-PIPELINING\r\n250-CHUNKING\r\n250 SMTPUTF8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run("message is delivered to the accepted recipients "+tt.name, func(t *testing.T) {
+			port, buffer := testMXServer(t, &serverProps{FeatureSet: tt.featureSet})
+			client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS), WithPartialDelivery())
+			if err != nil {
+				t.Fatalf("failed to create new client: %s", err)
+			}
+			message := testMessage(t)
+			if err = message.AddTo(invalidRcpt); err != nil {
+				t.Fatalf("failed to add recipient: %s", err)
+			}
+			err = client.DialAndSend(message)
+			var sendErr *SendError
+			if !errors.As(err, &sendErr) {
+				t.Fatalf("expected SendError, got: %s", err)
+			}
+			if sendErr.Reason != ErrPartialDelivery {
+				t.Errorf("expected ErrPartialDelivery, got: %s", sendErr.Reason)
+			}
+			if sendErr.IsTemp() {
+				t.Error("expected partial delivery not to be temporary")
+			}
+			if len(sendErr.rcpt) != 1 || sendErr.rcpt[0] != "<"+invalidRcpt+">" {
+				t.Errorf("expected rejected recipient to be reported, got: %v", sendErr.rcpt)
+			}
+			if sendErr.ErrorCode() != 500 || sendErr.EnhancedStatusCode() != "5.1.2" {
+				t.Errorf("expected 500 5.1.2, got: %d %s", sendErr.ErrorCode(), sendErr.EnhancedStatusCode())
+			}
+			if buffer.count("Testmail") == 0 {
+				t.Error("expected message data to be transmitted")
+			}
+			if message.ServerResponse() == "" {
+				t.Error("expected server response for the delivered message")
+			}
+
+			results := message.RcptResults()
+			if len(results) != 2 {
+				t.Fatalf("expected 2 recipient results, got: %d", len(results))
+			}
+			accepted, rejected := results[0], results[1]
+			if accepted.Rcpt != "<"+TestRcptValid+">" || accepted.Err != nil || accepted.Code != 250 ||
+				accepted.EnhancedStatusCode != "2.0.0" {
+				t.Errorf("unexpected result for accepted recipient: %+v", accepted)
+			}
+			if rejected.Rcpt != "<"+invalidRcpt+">" || rejected.Err == nil || rejected.Code != 500 ||
+				rejected.EnhancedStatusCode != "5.1.2" {
+				t.Errorf("unexpected result for rejected recipient: %+v", rejected)
+			}
+		})
+	}
+	t.Run("message is delivered if all recipients are accepted", func(t *testing.T) {
+		port, _ := testMXServer(t, &serverProps{})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS), WithPartialDelivery())
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+		if results := message.RcptResults(); len(results) != 1 || results[0].Err != nil {
+			t.Errorf("expected accepted recipient result, got: %+v", results)
+		}
+	})
+	t.Run("transaction is aborted if all recipients are rejected", func(t *testing.T) {
+		port, buffer := testMXServer(t, &serverProps{})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS), WithPartialDelivery())
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = message.To(invalidRcpt); err != nil {
+			t.Fatalf("failed to set recipient: %s", err)
+		}
+		err = client.DialAndSend(message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected ErrSMTPRcptTo, got: %s", sendErr.Reason)
+		}
+		if buffer.count("DATA") != 0 {
+			t.Error("expected no DATA command to be sent")
+		}
+		if results := message.RcptResults(); len(results) != 1 || results[0].Err == nil {
+			t.Errorf("expected rejected recipient result, got: %+v", results)
+		}
+	})
+	t.Run("transaction is aborted without partial delivery", func(t *testing.T) {
+		port, buffer := testMXServer(t, &serverProps{})
+		client, err := NewClient(DefaultHost, WithPort(port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		message := testMessage(t)
+		if err = message.AddTo(invalidRcpt); err != nil {
+			t.Fatalf("failed to add recipient: %s", err)
+		}
+		err = client.DialAndSend(message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected ErrSMTPRcptTo, got: %s", sendErr.Reason)
+		}
+		if buffer.count("Testmail") != 0 {
+			t.Error("expected no message data to be transmitted")
+		}
+		if results := message.RcptResults(); len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+			t.Errorf("expected accepted and rejected recipient results, got: %+v", results)
+		}
+	})
+}
@@ -0,0 +1,356 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+const (
+	// DefaultPoolSize is the default number of concurrent SMTP sessions a ClientPool keeps.
+	DefaultPoolSize = 4
+
+	// DefaultPoolIdleTimeout is the default duration after which an idle SMTP session of a
+	// ClientPool is considered stale and will be recycled.
+	DefaultPoolIdleTimeout = time.Minute
+)
+
+var (
+	// ErrPoolClosed is returned when a ClientPool is used after it has been closed.
+	ErrPoolClosed = errors.New("client pool is closed")
+
+	// ErrInvalidPoolSize is returned when the provided size for a ClientPool is zero or negative.
+	ErrInvalidPoolSize = errors.New("pool size must be greater than zero")
+
+	// ErrInvalidPoolMaxMessages is returned when the provided maximum number of messages per
+	// session for a ClientPool is negative.
+	ErrInvalidPoolMaxMessages = errors.New("maximum number of messages per session cannot be negative")
+
+	// ErrInvalidPoolIdleTimeout is returned when the provided idle timeout for a ClientPool is
+	// negative.
+	ErrInvalidPoolIdleTimeout = errors.New("idle timeout cannot be negative")
+)
+
+type (
+	// PoolOption is a function type that modifies the configuration or behavior of a ClientPool instance.
+	PoolOption func(*ClientPool) error
+
+	// ClientPool manages a pool of live SMTP sessions on top of a Client.
+	//
+	// A Client holds a single smtp.Client, so all messages sent via Client.Send share one SMTP session
+	// and are serialized. The ClientPool instead keeps up to a configurable number of smtp.Client
+	// sessions, which are established via Client.DialToSMTPClientWithContext, and hands them out to
+	// concurrent senders. Idle sessions are health-checked with a NOOP command before they are reused
+	// and are recycled after a configurable number of messages or after being idle for too long.
+	//
+	// All connection and delivery settings (host, port, TLS, SMTP authentication, DSN, etc.) are taken
+	// from the Client the ClientPool was created with.
+	ClientPool struct {
+		// client is the Client that is used to establish and configure the SMTP sessions.
+		client *Client
+
+		// closed indicates that the ClientPool has been closed and must not hand out sessions anymore.
+		closed bool
+
+		// idle holds the SMTP sessions that are currently not in use.
+		idle chan *pooledConn
+
+		// idleTimeout is the duration after which an idle SMTP session is recycled. A value of 0
+		// disables the idle timeout.
+		idleTimeout time.Duration
+
+		// maxMessages is the number of messages after which a SMTP session is recycled. A value of 0
+		// disables the recycling based on the message count.
+		maxMessages int
+
+		// mutex is used to synchronize access to the closed state of the ClientPool.
+		mutex sync.RWMutex
+
+		// size is the maximum number of concurrent SMTP sessions of the ClientPool.
+		size int
+
+		// slots limits the number of SMTP sessions that are in use at the same time.
+		slots chan struct{}
+	}
+
+	// pooledConn represents a single SMTP session that is managed by a ClientPool.
+	pooledConn struct {
+		// lastUsed is the time the session was last returned to the ClientPool.
+		lastUsed time.Time
+
+		// messages is the number of messages that have been sent via the session.
+		messages int
+
+		// smtpClient is the smtp.Client holding the connection to the SMTP server.
+		smtpClient *smtp.Client
+	}
+)
+
+// NewClientPool creates a new ClientPool for the provided Client with optional configuration
+// PoolOption functions.
+//
+// The ClientPool is initialized with DefaultPoolSize sessions and DefaultPoolIdleTimeout, without
+// a limit on the number of messages per session. SMTP sessions are established lazily when they
+// are first needed.
+//
+// Parameters:
+//   - client: The Client that is used to establish and configure the SMTP sessions.
+//   - opts: Optional configuration functions to override default settings.
+//
+// Returns:
+//   - A pointer to the initialized ClientPool.
+//   - An error if the provided Client is nil or options fail to apply.
+func NewClientPool(client *Client, opts ...PoolOption) (*ClientPool, error) {
+	if client == nil {
+		return nil, ErrClientIsNil
+	}
+	pool := &ClientPool{
+		client:      client,
+		idleTimeout: DefaultPoolIdleTimeout,
+		size:        DefaultPoolSize,
+	}
+
+	// Override defaults with optionally provided PoolOption functions
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(pool); err != nil {
+			return nil, err
+		}
+	}
+
+	pool.idle = make(chan *pooledConn, pool.size)
+	pool.slots = make(chan struct{}, pool.size)
+	return pool, nil
+}
+
+// WithPoolSize sets the maximum number of concurrent SMTP sessions of the ClientPool.
+//
+// Parameters:
+//   - size: The maximum number of concurrent SMTP sessions. Must be greater than zero.
+//
+// Returns:
+//   - A PoolOption function that sets the size of the ClientPool.
+//   - An error if the provided size is zero or negative.
+func WithPoolSize(size int) PoolOption {
+	return func(p *ClientPool) error {
+		if size <= 0 {
+			return ErrInvalidPoolSize
+		}
+		p.size = size
+		return nil
+	}
+}
+
+// WithPoolMaxMessages sets the number of messages after which a SMTP session of the ClientPool
+// is closed and replaced by a new one.
+//
+// This is useful for servers that limit the number of messages per session. A value of 0 disables
+// the recycling based on the message count.
+//
+// Parameters:
+//   - maxMessages: The maximum number of messages per SMTP session.
+//
+// Returns:
+//   - A PoolOption function that sets the maximum number of messages per session.
+//   - An error if the provided number is negative.
+func WithPoolMaxMessages(maxMessages int) PoolOption {
+	return func(p *ClientPool) error {
+		if maxMessages < 0 {
+			return ErrInvalidPoolMaxMessages
+		}
+		p.maxMessages = maxMessages
+		return nil
+	}
+}
+
+// WithPoolIdleTimeout sets the duration after which an idle SMTP session of the ClientPool is
+// closed and replaced by a new one.
+//
+// A value of 0 disables the idle timeout. Idle sessions are still health-checked with a NOOP
+// command before they are reused.
+//
+// Parameters:
+//   - timeout: The duration after which an idle SMTP session is recycled.
+//
+// Returns:
+//   - A PoolOption function that sets the idle timeout of the ClientPool.
+//   - An error if the provided timeout is negative.
+func WithPoolIdleTimeout(timeout time.Duration) PoolOption {
+	return func(p *ClientPool) error {
+		if timeout < 0 {
+			return ErrInvalidPoolIdleTimeout
+		}
+		p.idleTimeout = timeout
+		return nil
+	}
+}
+
+// Send sends one or more Msg using one of the SMTP sessions of the ClientPool.
+//
+// The method waits until a SMTP session is available or the provided context is canceled. An idle
+// session is reused if available, otherwise a new session is established. The messages are then
+// sent in the same way as with Client.SendWithSMTPClient and the session is returned to the
+// ClientPool afterwards. If the session reaches the maximum number of messages set with
+// WithPoolMaxMessages while sending, it is recycled and the remaining messages are sent via
+// another session. Send is safe for concurrent use.
+//
+// Parameters:
+//   - ctx: The context.Context to control waiting for a session, the connection timeout and cancellation.
+//   - messages: A variadic list of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error if no session could be acquired or if sending the messages fails; otherwise, returns nil.
+func (p *ClientPool) Send(ctx context.Context, messages ...*Msg) error {
+	var conn *pooledConn
+	var errs []error
+	defer func() {
+		if conn != nil {
+			p.release(conn)
+		}
+	}()
+
+	for _, message := range messages {
+		if message == nil {
+			continue
+		}
+		if conn == nil {
+			var err error
+			if conn, err = p.acquire(ctx); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+		if sendErr := p.client.sendSingleMsg(ctx, conn.smtpClient, message); sendErr != nil {
+			message.sendError = sendErr
+			errs = append(errs, sendErr)
+		}
+		conn.messages++
+		if p.maxMessages > 0 && conn.messages >= p.maxMessages {
+			p.release(conn)
+			conn = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all idle SMTP sessions of the ClientPool. Sessions that are in use at the time
+// Close is called are closed as soon as they are returned to the ClientPool. After Close has
+// been called, Send will fail with ErrPoolClosed.
+//
+// Returns:
+//   - An error if closing any of the idle sessions fails; otherwise, returns nil.
+func (p *ClientPool) Close() error {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	var errs []error
+	for {
+		select {
+		case conn := <-p.idle:
+			if err := p.client.CloseWithSMTPClient(conn.smtpClient); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+// acquire returns a SMTP session of the ClientPool that is ready to be used.
+//
+// The method blocks until a slot is available or the context is canceled. Idle sessions that
+// exceeded the idle timeout or fail the health check are discarded. If no usable idle session
+// exists, a new session is established.
+//
+// Parameters:
+//   - ctx: The context.Context to control waiting for a session and the connection timeout.
+//
+// Returns:
+//   - A pointer to the pooledConn that is ready to be used.
+//   - An error if the ClientPool is closed, the context is canceled or dialing fails.
+func (p *ClientPool) acquire(ctx context.Context) (*pooledConn, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case p.slots <- struct{}{}:
+	}
+
+	for {
+		select {
+		case conn := <-p.idle:
+			if p.idleTimeout > 0 && time.Since(conn.lastUsed) > p.idleTimeout {
+				p.discard(conn)
+				continue
+			}
+			if err := p.client.checkConn(conn.smtpClient); err != nil {
+				p.discard(conn)
+				continue
+			}
+			return conn, nil
+		default:
+			client, err := p.client.DialToSMTPClientWithContext(ctx)
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return &pooledConn{smtpClient: client}, nil
+		}
+	}
+}
+
+// release returns a SMTP session to the ClientPool. If the ClientPool has been closed or the
+// session reached the maximum number of messages, the session is closed instead.
+//
+// Parameters:
+//   - conn: The pooledConn that is returned to the ClientPool.
+func (p *ClientPool) release(conn *pooledConn) {
+	defer func() { <-p.slots }()
+
+	// We hold the read lock until the session is handed back to the idle channel, so that
+	// Close cannot drain the channel in between and miss this session.
+	p.mutex.RLock()
+	if p.closed || (p.maxMessages > 0 && conn.messages >= p.maxMessages) {
+		p.mutex.RUnlock()
+		p.discard(conn)
+		return
+	}
+	conn.lastUsed = time.Now()
+	select {
+	case p.idle <- conn:
+		p.mutex.RUnlock()
+	default:
+		p.mutex.RUnlock()
+		p.discard(conn)
+	}
+}
+
+// discard closes the connection of the provided SMTP session. Any error is ignored, since the
+// session is not used anymore.
+//
+// Parameters:
+//   - conn: The pooledConn that is discarded.
+func (p *ClientPool) discard(conn *pooledConn) {
+	if err := p.client.CloseWithSMTPClient(conn.smtpClient); err != nil {
+		_ = conn.smtpClient.Close()
+	}
+}
+
+// isClosed returns true if the ClientPool has been closed.
+func (p *ClientPool) isClosed() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.closed
+}
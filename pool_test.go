@@ -0,0 +1,354 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewClientPool(t *testing.T) {
+	t.Run("new pool with defaults", func(t *testing.T) {
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		pool, err := NewClientPool(client)
+		if err != nil {
+			t.Fatalf("failed to create new client pool: %s", err)
+		}
+		if pool.size != DefaultPoolSize {
+			t.Errorf("expected pool size to be %d, got %d", DefaultPoolSize, pool.size)
+		}
+		if pool.idleTimeout != DefaultPoolIdleTimeout {
+			t.Errorf("expected idle timeout to be %s, got %s", DefaultPoolIdleTimeout, pool.idleTimeout)
+		}
+		if pool.maxMessages != 0 {
+			t.Errorf("expected max messages to be 0, got %d", pool.maxMessages)
+		}
+		if cap(pool.slots) != DefaultPoolSize {
+			t.Errorf("expected slots capacity to be %d, got %d", DefaultPoolSize, cap(pool.slots))
+		}
+	})
+	t.Run("new pool with nil client fails", func(t *testing.T) {
+		_, err := NewClientPool(nil)
+		if !errors.Is(err, ErrClientIsNil) {
+			t.Errorf("expected error to be %s, got %s", ErrClientIsNil, err)
+		}
+	})
+	t.Run("new pool with nil option", func(t *testing.T) {
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if _, err = NewClientPool(client, nil); err != nil {
+			t.Errorf("failed to create new client pool: %s", err)
+		}
+	})
+	t.Run("new pool with options", func(t *testing.T) {
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		pool, err := NewClientPool(client, WithPoolSize(10), WithPoolMaxMessages(50),
+			WithPoolIdleTimeout(time.Second*30))
+		if err != nil {
+			t.Fatalf("failed to create new client pool: %s", err)
+		}
+		if pool.size != 10 {
+			t.Errorf("expected pool size to be 10, got %d", pool.size)
+		}
+		if cap(pool.idle) != 10 {
+			t.Errorf("expected idle capacity to be 10, got %d", cap(pool.idle))
+		}
+		if pool.maxMessages != 50 {
+			t.Errorf("expected max messages to be 50, got %d", pool.maxMessages)
+		}
+		if pool.idleTimeout != time.Second*30 {
+			t.Errorf("expected idle timeout to be 30s, got %s", pool.idleTimeout)
+		}
+	})
+	t.Run("new pool with invalid options fails", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			option PoolOption
+			want   error
+		}{
+			{"zero pool size", WithPoolSize(0), ErrInvalidPoolSize},
+			{"negative pool size", WithPoolSize(-1), ErrInvalidPoolSize},
+			{"negative max messages", WithPoolMaxMessages(-1), ErrInvalidPoolMaxMessages},
+			{"negative idle timeout", WithPoolIdleTimeout(-time.Second), ErrInvalidPoolIdleTimeout},
+		}
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewClientPool(client, tt.option)
+				if !errors.Is(err, tt.want) {
+					t.Errorf("expected error to be %s, got %s", tt.want, err)
+				}
+			})
+		}
+	})
+}
+
+func TestClientPool_Send(t *testing.T) {
+	t.Run("send a single message", func(t *testing.T) {
+		pool, _ := testClientPool(t, &serverProps{})
+		message := testMessage(t)
+		if err := pool.Send(t.Context(), message); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+	})
+	t.Run("send multiple messages reuses the idle session", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{})
+		for i := 0; i < 3; i++ {
+			if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+				t.Fatalf("failed to send message via pool: %s", err)
+			}
+		}
+		if conns := echoBuffer.connections(); conns != 1 {
+			t.Errorf("expected 1 connection, got %d", conns)
+		}
+	})
+	t.Run("send concurrently does not exceed the pool size", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{}, WithPoolSize(2))
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+					t.Errorf("failed to send message via pool: %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if conns := echoBuffer.connections(); conns < 1 || conns > 2 {
+			t.Errorf("expected 1 or 2 connections, got %d", conns)
+		}
+		if queued := echoBuffer.count("queued as"); queued != 8 {
+			t.Errorf("expected 8 queued messages, got %d", queued)
+		}
+	})
+	t.Run("sessions are recycled after max messages", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{}, WithPoolMaxMessages(2))
+		for i := 0; i < 3; i++ {
+			if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+				t.Fatalf("failed to send message via pool: %s", err)
+			}
+		}
+		if conns := echoBuffer.connections(); conns != 2 {
+			t.Errorf("expected 2 connections, got %d", conns)
+		}
+	})
+	t.Run("sessions are recycled within a batch after max messages", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{}, WithPoolMaxMessages(2))
+		messages := []*Msg{testMessage(t), testMessage(t), testMessage(t), testMessage(t), testMessage(t)}
+		if err := pool.Send(t.Context(), messages...); err != nil {
+			t.Fatalf("failed to send messages via pool: %s", err)
+		}
+		for i, message := range messages {
+			if !message.IsDelivered() {
+				t.Errorf("expected message %d to be delivered", i)
+			}
+		}
+		if conns := echoBuffer.connections(); conns != 3 {
+			t.Errorf("expected 3 connections, got %d", conns)
+		}
+	})
+	t.Run("sessions are recycled after the idle timeout", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{}, WithPoolIdleTimeout(time.Millisecond*10))
+		if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		time.Sleep(time.Millisecond * 50)
+		if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		if conns := echoBuffer.connections(); conns != 2 {
+			t.Errorf("expected 2 connections, got %d", conns)
+		}
+	})
+	t.Run("sessions failing the health check are replaced", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{})
+		if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		conn := <-pool.idle
+		if err := conn.smtpClient.Close(); err != nil {
+			t.Fatalf("failed to close idle session: %s", err)
+		}
+		pool.idle <- conn
+		if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		if conns := echoBuffer.connections(); conns != 2 {
+			t.Errorf("expected 2 connections, got %d", conns)
+		}
+	})
+	t.Run("send with delivery error returns the session to the pool", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{})
+		message := testMessage(t)
+		if err := message.To("invalid@domain.tld"); err != nil {
+			t.Fatalf("failed to set recipient: %s", err)
+		}
+		err := pool.Send(t.Context(), message)
+		if err == nil {
+			t.Fatal("expected send to fail with invalid recipient")
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) || sendErr.Reason != ErrSMTPRcptTo {
+			t.Errorf("expected SendError with reason %s, got %s", ErrSMTPRcptTo, err)
+		}
+		if err = pool.Send(t.Context(), testMessage(t)); err != nil {
+			t.Fatalf("failed to send message via pool: %s", err)
+		}
+		if conns := echoBuffer.connections(); conns != 1 {
+			t.Errorf("expected 1 connection, got %d", conns)
+		}
+	})
+	t.Run("send on closed pool fails", func(t *testing.T) {
+		pool, _ := testClientPool(t, &serverProps{})
+		if err := pool.Close(); err != nil {
+			t.Fatalf("failed to close pool: %s", err)
+		}
+		if err := pool.Send(t.Context(), testMessage(t)); !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("expected error to be %s, got %s", ErrPoolClosed, err)
+		}
+	})
+	t.Run("send fails when context is canceled while waiting for a session", func(t *testing.T) {
+		pool, _ := testClientPool(t, &serverProps{}, WithPoolSize(1))
+		pool.slots <- struct{}{}
+		t.Cleanup(func() { <-pool.slots })
+
+		ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*50)
+		defer cancel()
+		if err := pool.Send(ctx, testMessage(t)); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected error to be %s, got %s", context.DeadlineExceeded, err)
+		}
+	})
+	t.Run("send fails when dial fails and releases the slot", func(t *testing.T) {
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS),
+			WithTimeout(time.Millisecond*200))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		pool, err := NewClientPool(client, WithPoolSize(1))
+		if err != nil {
+			t.Fatalf("failed to create new client pool: %s", err)
+		}
+		if err = pool.Send(t.Context(), testMessage(t)); err == nil {
+			t.Error("expected send to fail without a server")
+		}
+		if len(pool.slots) != 0 {
+			t.Errorf("expected slot to be released, got %d slots in use", len(pool.slots))
+		}
+	})
+}
+
+func TestClientPool_Close(t *testing.T) {
+	t.Run("close quits idle sessions", func(t *testing.T) {
+		pool, echoBuffer := testClientPool(t, &serverProps{}, WithPoolSize(2))
+		wg := sync.WaitGroup{}
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := pool.Send(t.Context(), testMessage(t)); err != nil {
+					t.Errorf("failed to send message via pool: %s", err)
+				}
+			}()
+		}
+		wg.Wait()
+		if err := pool.Close(); err != nil {
+			t.Fatalf("failed to close pool: %s", err)
+		}
+		if len(pool.idle) != 0 {
+			t.Errorf("expected no idle sessions, got %d", len(pool.idle))
+		}
+		if quits := echoBuffer.count("221 2.0.0 Bye"); quits != echoBuffer.connections() {
+			t.Errorf("expected all %d sessions to be quit, got %d", echoBuffer.connections(), quits)
+		}
+	})
+	t.Run("close on pool without sessions", func(t *testing.T) {
+		client, err := NewClient(DefaultHost)
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		pool, err := NewClientPool(client)
+		if err != nil {
+			t.Fatalf("failed to create new client pool: %s", err)
+		}
+		if err = pool.Close(); err != nil {
+			t.Errorf("failed to close pool: %s", err)
+		}
+	})
+}
+
+// countingEchoBuffer is a concurrency-safe echo buffer for the test server, that allows to
+// count the occurrences of responses, e.g. the number of SMTP sessions established.
+type countingEchoBuffer struct {
+	props  *serverProps
+	buffer *bytes.Buffer
+}
+
+// connections returns the number of SMTP sessions the test server has greeted.
+func (b *countingEchoBuffer) connections() int {
+	return b.count("220 go-mail test server ready")
+}
+
+// count returns the number of occurrences of the given substring in the echo buffer.
+func (b *countingEchoBuffer) count(substr string) int {
+	b.props.BufferMutex.RLock()
+	defer b.props.BufferMutex.RUnlock()
+	return strings.Count(b.buffer.String(), substr)
+}
+
+// testClientPool starts a concurrent test server with the given properties and returns a
+// ClientPool connected to it, alongside the echo buffer of the test server.
+func testClientPool(t *testing.T, props *serverProps, opts ...PoolOption) (*ClientPool, *countingEchoBuffer) {
+	t.Helper()
+	ctx := t.Context()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	buffer := bytes.NewBuffer(nil)
+	props.Concurrent = true
+	props.EchoBuffer = buffer
+	props.FeatureSet = "250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+	props.ListenPort = serverPort
+	go func() {
+		if err := simpleSMTPServer(ctx, t, props); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+
+	client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	pool, err := NewClientPool(client, opts...)
+	if err != nil {
+		t.Fatalf("failed to create new client pool: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+	return pool, &countingEchoBuffer{props: props, buffer: buffer}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"cmp"
+	"crypto/tls"
+	"errors"
+	"math/rand/v2"
+	"slices"
+)
+
+// ErrInvalidRelayWeight is returned if a RelayHost with a negative weight is provided.
+var ErrInvalidRelayWeight = errors.New("relay host weight must not be negative")
+
+// RelayHost represents a fallback SMTP relay host of a Client.
+//
+// If the connection to the primary host of the Client fails, the fallback hosts are tried in the
+// order of their Priority. Hosts with the same Priority are tried in a random order that is
+// weighted by their Weight, similar to the selection of SRV records.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc2782
+type RelayHost struct {
+	// Host is the hostname of the relay host.
+	Host string
+
+	// Port is the port of the relay host. If 0, the port of the Client is used.
+	Port int
+
+	// Priority is the priority of the relay host. Hosts with a lower Priority are tried first.
+	Priority int
+
+	// Weight is the relative weight of the relay host among the hosts with the same Priority.
+	// Hosts with a higher Weight are more likely to be tried first. Hosts with a Weight of 0 are
+	// tried after all other hosts with the same Priority.
+	Weight int
+}
+
+// WithFallbackHosts sets the fallback relay hosts of the Client.
+//
+// When dialing, the Client first connects to its primary host. If the connection fails or the
+// server rejects the connection in its greeting, i. e. with a "421 Service not available" reply,
+// the fallback hosts are tried in the order of their Priority and Weight, until a server accepts
+// the connection. Errors that occur after the greeting, like a failed STARTTLS or a failed
+// authentication, do not cause a failover. The hostname of the server that accepted a Msg is
+// available via Msg.ServerHost.
+//
+// The TLS settings, the authentication and all other settings of the Client apply to the fallback
+// hosts as well. If the ServerName of the tls.Config is the primary host, it is replaced by the
+// respective fallback host.
+//
+// Parameters:
+//   - hosts: The fallback RelayHosts of the Client.
+//
+// Returns:
+//   - An Option function that sets the fallback hosts of the Client.
+//   - An error if any RelayHost has no hostname, an invalid port or a negative weight.
+func WithFallbackHosts(hosts ...RelayHost) Option {
+	return func(c *Client) error {
+		for _, host := range hosts {
+			if host.Host == "" {
+				return ErrNoHostname
+			}
+			if host.Port < 0 || host.Port > 65535 {
+				return ErrInvalidPort
+			}
+			if host.Weight < 0 {
+				return ErrInvalidRelayWeight
+			}
+		}
+		c.fallbackHosts = slices.Clone(hosts)
+		return nil
+	}
+}
+
+// relayHosts returns the relay hosts of the Client in the order they are tried when dialing. The
+// primary host is always the first host, followed by the fallback hosts.
+func (c *Client) relayHosts() []RelayHost {
+	hosts := make([]RelayHost, 0, len(c.fallbackHosts)+1)
+	hosts = append(hosts, RelayHost{Host: c.host, Port: c.port})
+	for _, host := range orderRelayHosts(c.fallbackHosts, rand.IntN) {
+		if host.Port == 0 {
+			host.Port = c.port
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// relayTLSConfig returns the tls.Config for the connection to the given relay host. If the
+// ServerName of the provided tls.Config is the hostname of the Client, a copy with the relay host
+// as ServerName is returned, so that the certificate of a fallback host is verified against its
+// own hostname. Otherwise, the provided tls.Config is returned unchanged.
+//
+// Parameters:
+//   - config: The tls.Config for the connection. May be nil.
+//   - host: The hostname of the relay host.
+//
+// Returns:
+//   - The tls.Config for the connection to the relay host.
+func (c *Client) relayTLSConfig(config *tls.Config, host string) *tls.Config {
+	if config == nil || host == c.host || config.ServerName != c.host {
+		return config
+	}
+	config = config.Clone()
+	config.ServerName = host
+	return config
+}
+
+// orderRelayHosts returns a copy of the provided RelayHosts, ordered by their Priority. Within the
+// same Priority, the hosts are ordered randomly, weighted by their Weight.
+//
+// Parameters:
+//   - hosts: The RelayHosts to order.
+//   - intN: A function returning a random number in the half-open interval [0,n).
+//
+// Returns:
+//   - A new slice with the ordered RelayHosts.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc2782
+func orderRelayHosts(hosts []RelayHost, intN func(n int) int) []RelayHost {
+	remaining := slices.Clone(hosts)
+	slices.SortStableFunc(remaining, func(a, b RelayHost) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+	ordered := make([]RelayHost, 0, len(remaining))
+	for len(remaining) > 0 {
+		group := 1
+		for group < len(remaining) && remaining[group].Priority == remaining[0].Priority {
+			group++
+		}
+		total := 0
+		for _, host := range remaining[:group] {
+			total += host.Weight
+		}
+		selected := 0
+		if total > 0 {
+			pick := intN(total)
+			for pick >= remaining[selected].Weight {
+				pick -= remaining[selected].Weight
+				selected++
+			}
+		}
+		ordered = append(ordered, remaining[selected])
+		remaining = slices.Delete(remaining, selected, selected+1)
+	}
+	return ordered
+}
@@ -0,0 +1,303 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWithFallbackHosts(t *testing.T) {
+	t.Run("fallback hosts are set", func(t *testing.T) {
+		hosts := []RelayHost{
+			{Host: "backup1.example.com", Port: 587, Priority: 10, Weight: 5},
+			{Host: "backup2.example.com"},
+		}
+		client, err := NewClient(DefaultHost, WithFallbackHosts(hosts...))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if len(client.fallbackHosts) != len(hosts) {
+			t.Fatalf("expected %d fallback hosts, got: %d", len(hosts), len(client.fallbackHosts))
+		}
+		hosts[0].Host = "changed.example.com"
+		if client.fallbackHosts[0].Host != "backup1.example.com" {
+			t.Errorf("expected fallback hosts to be copied, got: %s", client.fallbackHosts[0].Host)
+		}
+	})
+	t.Run("relay hosts start with the primary host", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithPort(587),
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		relays := client.relayHosts()
+		if len(relays) != 2 {
+			t.Fatalf("expected 2 relay hosts, got: %d", len(relays))
+		}
+		if relays[0].Host != DefaultHost || relays[0].Port != 587 {
+			t.Errorf("expected primary host %s:587, got: %s:%d", DefaultHost, relays[0].Host, relays[0].Port)
+		}
+		if relays[1].Host != "backup.example.com" || relays[1].Port != 587 {
+			t.Errorf("expected fallback host to use the client port, got: %s:%d", relays[1].Host,
+				relays[1].Port)
+		}
+	})
+	tests := []struct {
+		name string
+		host RelayHost
+		want error
+	}{
+		{"empty hostname", RelayHost{Port: 25}, ErrNoHostname},
+		{"negative port", RelayHost{Host: "backup.example.com", Port: -1}, ErrInvalidPort},
+		{"port too high", RelayHost{Host: "backup.example.com", Port: 65536}, ErrInvalidPort},
+		{"negative weight", RelayHost{Host: "backup.example.com", Weight: -1}, ErrInvalidRelayWeight},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(DefaultHost, WithFallbackHosts(tt.host))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected error %s, got: %s", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOrderRelayHosts(t *testing.T) {
+	first := func(int) int { return 0 }
+	last := func(n int) int { return n - 1 }
+	names := func(hosts []RelayHost) string {
+		list := make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			list = append(list, host.Host)
+		}
+		return strings.Join(list, ",")
+	}
+	tests := []struct {
+		name  string
+		hosts []RelayHost
+		intN  func(int) int
+		want  string
+	}{
+		{"empty list", nil, first, ""},
+		{
+			"ordered by priority",
+			[]RelayHost{{Host: "c", Priority: 30}, {Host: "a", Priority: 10}, {Host: "b", Priority: 20}},
+			first, "a,b,c",
+		},
+		{
+			"zero weights keep their order",
+			[]RelayHost{{Host: "a", Priority: 10}, {Host: "b", Priority: 10}, {Host: "c", Priority: 10}},
+			last, "a,b,c",
+		},
+		{
+			"weighted selection picks the first weight range",
+			[]RelayHost{{Host: "a", Priority: 10, Weight: 1}, {Host: "b", Priority: 10, Weight: 3}},
+			first, "a,b",
+		},
+		{
+			"weighted selection picks the last weight range",
+			[]RelayHost{{Host: "a", Priority: 10, Weight: 1}, {Host: "b", Priority: 10, Weight: 3}},
+			last, "b,a",
+		},
+		{
+			"zero weights are tried after weighted hosts",
+			[]RelayHost{{Host: "a", Priority: 10}, {Host: "b", Priority: 10, Weight: 1}, {Host: "c", Priority: 5}},
+			first, "c,b,a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(orderRelayHosts(tt.hosts, tt.intN))
+			if got != tt.want {
+				t.Errorf("expected order %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_relayTLSConfig(t *testing.T) {
+	client, err := NewClient(DefaultHost)
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	t.Run("primary host keeps the tls.Config", func(t *testing.T) {
+		if config := client.relayTLSConfig(client.tlsconfig, DefaultHost); config != client.tlsconfig {
+			t.Error("expected tls.Config of the Client to be returned")
+		}
+	})
+	t.Run("fallback host gets its own ServerName", func(t *testing.T) {
+		config := client.relayTLSConfig(client.tlsconfig, "relay.domain.tld")
+		if config == client.tlsconfig {
+			t.Fatal("expected copy of the tls.Config")
+		}
+		if config.ServerName != "relay.domain.tld" {
+			t.Errorf("expected ServerName to be relay.domain.tld, got: %s", config.ServerName)
+		}
+		if client.tlsconfig.ServerName != DefaultHost {
+			t.Errorf("expected ServerName of the Client to be unchanged, got: %s", client.tlsconfig.ServerName)
+		}
+	})
+	t.Run("custom ServerName is kept", func(t *testing.T) {
+		custom := &tls.Config{ServerName: "custom.domain.tld"}
+		if config := client.relayTLSConfig(custom, "relay.domain.tld"); config != custom {
+			t.Error("expected custom tls.Config to be returned")
+		}
+	})
+	t.Run("nil tls.Config", func(t *testing.T) {
+		if config := client.relayTLSConfig(nil, "relay.domain.tld"); config != nil {
+			t.Errorf("expected nil tls.Config, got: %+v", config)
+		}
+	})
+}
+
+func TestClient_DialWithContext_fallbackHosts(t *testing.T) {
+	t.Run("fallback host is used if the primary host is unavailable", func(t *testing.T) {
+		serverPort, _ := testMXServer(t, &serverProps{})
+		client := testRelayClient(t, map[string]int{"backup.example.com": serverPort},
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		message := testMessage(t)
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if message.ServerHost() != "backup.example.com" {
+			t.Errorf("expected message to be accepted by backup.example.com, got: %s", message.ServerHost())
+		}
+	})
+	t.Run("primary host is used if available", func(t *testing.T) {
+		primaryPort, _ := testMXServer(t, &serverProps{})
+		backupPort, backupBuffer := testMXServer(t, &serverProps{})
+		client := testRelayClient(t, map[string]int{"primary.example.com": primaryPort, "backup.example.com": backupPort},
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		message := testMessage(t)
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if message.ServerHost() != "primary.example.com" {
+			t.Errorf("expected message to be accepted by primary.example.com, got: %s", message.ServerHost())
+		}
+		if backupBuffer.connections() != 0 {
+			t.Errorf("expected no connection to the backup host, got: %d", backupBuffer.connections())
+		}
+	})
+	t.Run("fallback host is used if the primary host greets with 421", func(t *testing.T) {
+		primaryPort := testRejectingServer(t, "421 4.3.2 Service not available")
+		backupPort, _ := testMXServer(t, &serverProps{})
+		client := testRelayClient(t, map[string]int{"primary.example.com": primaryPort, "backup.example.com": backupPort},
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		message := testMessage(t)
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if message.ServerHost() != "backup.example.com" {
+			t.Errorf("expected message to be accepted by backup.example.com, got: %s", message.ServerHost())
+		}
+	})
+	t.Run("fallback hosts are tried in order of their priority", func(t *testing.T) {
+		serverPort, _ := testMXServer(t, &serverProps{})
+		client := testRelayClient(t, map[string]int{"backup2.example.com": serverPort, "backup3.example.com": serverPort},
+			WithFallbackHosts(
+				RelayHost{Host: "backup3.example.com", Priority: 30},
+				RelayHost{Host: "backup1.example.com", Priority: 10},
+				RelayHost{Host: "backup2.example.com", Priority: 20},
+			))
+		message := testMessage(t)
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if message.ServerHost() != "backup2.example.com" {
+			t.Errorf("expected message to be accepted by backup2.example.com, got: %s", message.ServerHost())
+		}
+	})
+	t.Run("dial fails if all relay hosts are unavailable", func(t *testing.T) {
+		client := testRelayClient(t, map[string]int{},
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second*5)
+		defer cancel()
+		err := client.DialWithContext(ctx)
+		if err == nil {
+			t.Fatal("expected dial to fail")
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Errorf("expected connection refused error, got: %s", err)
+		}
+		for _, host := range []string{"primary.example.com", "backup.example.com"} {
+			if !strings.Contains(err.Error(), host) {
+				t.Errorf("expected error to mention %s, got: %s", host, err)
+			}
+		}
+	})
+	t.Run("errors after the greeting do not fail over", func(t *testing.T) {
+		primaryPort, _ := testMXServer(t, &serverProps{FailOnHelo: true})
+		backupPort, backupBuffer := testMXServer(t, &serverProps{})
+		client := testRelayClient(t, map[string]int{"primary.example.com": primaryPort, "backup.example.com": backupPort},
+			WithFallbackHosts(RelayHost{Host: "backup.example.com"}))
+		ctx, cancel := context.WithTimeout(t.Context(), time.Second*5)
+		defer cancel()
+		if err := client.DialWithContext(ctx); err == nil {
+			t.Fatal("expected dial to fail")
+		}
+		if backupBuffer.connections() != 0 {
+			t.Errorf("expected no connection to the backup host, got: %d", backupBuffer.connections())
+		}
+	})
+}
+
+// testRelayClient returns a Client for the host "primary.example.com" that connects to the test
+// server ports mapped to the relay hosts. Connections to unmapped hosts are refused.
+func testRelayClient(t *testing.T, ports map[string]int, opts ...Option) *Client {
+	t.Helper()
+	dialer := func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		port, ok := ports[host]
+		if !ok {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: syscall.ECONNREFUSED}
+		}
+		netDialer := net.Dialer{}
+		return netDialer.DialContext(ctx, network, fmt.Sprintf("%s:%d", TestServerAddr, port))
+	}
+	opts = append([]Option{WithTLSPolicy(NoTLS), WithDialContextFunc(dialer)}, opts...)
+	client, err := NewClient("primary.example.com", opts...)
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}
+
+// testRejectingServer starts a server that greets every connection with the given reply and
+// closes the connection afterward.
+func testRejectingServer(t *testing.T, greeting string) int {
+	t.Helper()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	listener, err := net.Listen(TestServerProto, fmt.Sprintf("%s:%d", TestServerAddr, serverPort))
+	if err != nil {
+		t.Fatalf("failed to listen on test server port: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, aerr := listener.Accept()
+			if aerr != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(greeting + "\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	return serverPort
+}
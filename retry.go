@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultRetryBaseDelay is the default delay before the first retry of a RetryPolicy.
+	DefaultRetryBaseDelay = time.Second
+
+	// DefaultRetryMaxDelay is the default upper limit for the delay between two attempts of a RetryPolicy.
+	DefaultRetryMaxDelay = time.Minute
+)
+
+// ErrInvalidRetryPolicy is returned when the provided RetryPolicy is nil or has invalid values.
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy: max attempts must be greater than zero " +
+	"and delays cannot be negative")
+
+// RetryPolicy configures the automatic retry of temporarily failed deliveries in
+// Client.DialAndSendWithContext.
+//
+// A message is retried if its SendError is of a temporary nature (SendError.IsTemp), if the
+// connection check failed (ErrConnCheck) or if the SMTP session was dropped during the delivery.
+// Each retry is performed on a newly established connection to the SMTP server. Messages that
+// have been delivered or that failed with a permanent error are not retried.
+//
+// Between two attempts, the Client waits for an exponentially increasing delay, starting with
+// BaseDelay and doubling with each attempt up to MaxDelay. A random jitter of up to half the
+// delay is applied, so that multiple clients do not retry at the same time.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of delivery attempts per message, including the first one.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. If zero, DefaultRetryBaseDelay is used.
+	BaseDelay time.Duration
+
+	// MaxDelay is the upper limit for the delay between two attempts. If zero, DefaultRetryMaxDelay
+	// is used.
+	MaxDelay time.Duration
+}
+
+// WithRetryPolicy enables the automatic retry of temporarily failed deliveries with the provided
+// RetryPolicy.
+//
+// The RetryPolicy only applies to DialAndSend and DialAndSendWithContext, since a retry might
+// require a new connection to the SMTP server. The number of attempts of a failed delivery is
+// available via SendError.Attempts.
+//
+// Parameters:
+//   - policy: The RetryPolicy to be used by the Client.
+//
+// Returns:
+//   - An Option function that sets the RetryPolicy for the Client.
+//   - An error if the RetryPolicy is nil or has invalid values.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(c *Client) error {
+		if policy == nil || policy.MaxAttempts <= 0 || policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			return ErrInvalidRetryPolicy
+		}
+		retryPolicy := *policy
+		if retryPolicy.BaseDelay == 0 {
+			retryPolicy.BaseDelay = DefaultRetryBaseDelay
+		}
+		if retryPolicy.MaxDelay == 0 {
+			retryPolicy.MaxDelay = DefaultRetryMaxDelay
+		}
+		c.retryPolicy = &retryPolicy
+		return nil
+	}
+}
+
+// dialAndSendWithRetry establishes a connection to the SMTP server and sends out the given Msg,
+// retrying the messages that failed temporarily according to the RetryPolicy of the Client.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout, the backoff and cancellation.
+//   - messages: A slice of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error if any of the messages could not be delivered after all attempts; otherwise, returns nil.
+func (c *Client) dialAndSendWithRetry(ctx context.Context, messages []*Msg) error {
+	all := make([]*Msg, 0, len(messages))
+	for _, message := range messages {
+		if message != nil {
+			all = append(all, message)
+		}
+	}
+
+	pending := all
+	attempt := 0
+	var err error
+	for {
+		attempt++
+		for _, message := range pending {
+			message.sendError = nil
+		}
+		if err = c.dialAndSend(ctx, pending); err == nil {
+			break
+		}
+
+		var retry []*Msg
+		for _, message := range pending {
+			if message.isDelivered {
+				continue
+			}
+			msgErr := message.sendError
+			if msgErr == nil {
+				// The message has not been attempted, since the dial or the connection check failed
+				msgErr = err
+			}
+			var sendErr *SendError
+			if errors.As(message.sendError, &sendErr) {
+				sendErr.attempts = attempt
+			}
+			if isRetryableError(msgErr) {
+				retry = append(retry, message)
+			}
+		}
+		if len(retry) == 0 || attempt >= c.retryPolicy.MaxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(c.retryPolicy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(retryResult(err, all, pending, attempt), ctx.Err())
+		case <-timer.C:
+		}
+		pending = retry
+	}
+
+	return retryResult(err, all, pending, attempt)
+}
+
+// dialAndSend establishes a connection to the SMTP server, sends out the given Msg and closes
+// the connection afterwards.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - messages: A slice of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error if the connection fails, if sending the messages fails, or if closing the
+//     connection fails; otherwise, returns nil.
+func (c *Client) dialAndSend(ctx context.Context, messages []*Msg) (err error) {
+	client, err := c.DialToSMTPClientWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("dial failed: %w", err)
+	}
+	defer func() {
+		if closeErr := c.closeWithSMTPClient(ctx, client); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close connection: %w", closeErr))
+		}
+	}()
+
+	if err = c.sendWithSMTPClient(ctx, client, messages); err != nil {
+		return fmt.Errorf("send failed: %w", err)
+	}
+
+	return nil
+}
+
+// retryResult returns the combined result of all attempts of dialAndSendWithRetry.
+//
+// The SendErrors of all messages that could not be delivered are returned. If the last attempt
+// failed before any of its messages could be sent, e.g. because the dial failed, the error of the
+// last attempt is returned as well, including the number of attempts.
+//
+// Parameters:
+//   - err: The error of the last attempt.
+//   - messages: All messages that have been passed to dialAndSendWithRetry.
+//   - last: The messages of the last attempt.
+//   - attempts: The number of attempts that have been performed.
+//
+// Returns:
+//   - The error representing the result of all attempts, or nil if all messages were delivered.
+func retryResult(err error, messages, last []*Msg, attempts int) error {
+	var errs []error
+	if err != nil {
+		attempted := false
+		for _, message := range last {
+			if message.sendError != nil || message.isDelivered {
+				attempted = true
+				break
+			}
+		}
+		var sendErr *SendError
+		switch {
+		case attempted:
+		case errors.As(err, &sendErr):
+			sendErr.attempts = attempts
+			errs = append(errs, err)
+		default:
+			errs = append(errs, fmt.Errorf("%w (after %d attempt(s))", err, attempts))
+		}
+	}
+
+	var msgErrs []error
+	for _, message := range messages {
+		if message.sendError != nil {
+			msgErrs = append(msgErrs, message.sendError)
+		}
+	}
+	if len(msgErrs) > 0 {
+		errs = append(errs, fmt.Errorf("send failed: %w", errors.Join(msgErrs...)))
+	}
+	return errors.Join(errs...)
+}
+
+// backoff returns the delay before the next attempt, based on the number of attempts that have
+// already been performed.
+//
+// Parameters:
+//   - attempt: The number of attempts that have already been performed.
+//
+// Returns:
+//   - The delay before the next attempt including a random jitter.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return delay
+	}
+	jitter := time.Duration(binary.BigEndian.Uint64(buf[:]) % uint64(half))
+	return half + jitter
+}
+
+// isRetryableError checks if the given error of a delivery attempt can be retried.
+//
+// An error can be retried if it is a SendError of a temporary nature, a SendError with the
+// reason ErrConnCheck or if it was caused by a dropped connection to the SMTP server.
+//
+// Parameters:
+//   - err: The error to check.
+//
+// Returns:
+//   - true if the delivery can be retried, false otherwise.
+func isRetryableError(err error) bool {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		if sendErr.IsTemp() || sendErr.Reason == ErrConnCheck {
+			return true
+		}
+		for _, listErr := range sendErr.errlist {
+			if isConnectionError(listErr) {
+				return true
+			}
+		}
+		return false
+	}
+	return isConnectionError(err)
+}
+
+// isConnectionError checks if the given error was caused by a failed or dropped connection to
+// the SMTP server.
+//
+// Parameters:
+//   - err: The error to check.
+//
+// Returns:
+//   - true if the error is connection related, false otherwise.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, ErrNoActiveConnection) || errors.As(err, &netErr)
+}
@@ -0,0 +1,250 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	t.Run("retry policy with defaults", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithRetryPolicy(&RetryPolicy{MaxAttempts: 3}))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if client.retryPolicy == nil {
+			t.Fatal("expected retry policy to be set")
+		}
+		if client.retryPolicy.MaxAttempts != 3 {
+			t.Errorf("expected max attempts to be 3, got %d", client.retryPolicy.MaxAttempts)
+		}
+		if client.retryPolicy.BaseDelay != DefaultRetryBaseDelay {
+			t.Errorf("expected base delay to be %s, got %s", DefaultRetryBaseDelay, client.retryPolicy.BaseDelay)
+		}
+		if client.retryPolicy.MaxDelay != DefaultRetryMaxDelay {
+			t.Errorf("expected max delay to be %s, got %s", DefaultRetryMaxDelay, client.retryPolicy.MaxDelay)
+		}
+	})
+	t.Run("retry policy is copied", func(t *testing.T) {
+		policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+		client, err := NewClient(DefaultHost, WithRetryPolicy(policy))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		policy.MaxAttempts = 10
+		if client.retryPolicy.MaxAttempts != 3 {
+			t.Errorf("expected max attempts to be 3, got %d", client.retryPolicy.MaxAttempts)
+		}
+	})
+	t.Run("invalid retry policies fail", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			policy *RetryPolicy
+		}{
+			{"nil policy", nil},
+			{"zero attempts", &RetryPolicy{}},
+			{"negative attempts", &RetryPolicy{MaxAttempts: -1}},
+			{"negative base delay", &RetryPolicy{MaxAttempts: 1, BaseDelay: -time.Second}},
+			{"negative max delay", &RetryPolicy{MaxAttempts: 1, MaxDelay: -time.Second}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := NewClient(DefaultHost, WithRetryPolicy(tt.policy))
+				if !errors.Is(err, ErrInvalidRetryPolicy) {
+					t.Errorf("expected error to be %s, got %s", ErrInvalidRetryPolicy, err)
+				}
+			})
+		}
+	})
+}
+
+func TestClient_DialAndSendWithContext_retry(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond * 5}
+	t.Run("temporary failure is retried until delivered", func(t *testing.T) {
+		props := &serverProps{}
+		props.FailTempCount.Store(2)
+		client, echoBuffer := testRetryClient(t, props, WithRetryPolicy(policy))
+		message := testMessage(t)
+		if err := client.DialAndSendWithContext(t.Context(), message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+		if message.HasSendError() {
+			t.Errorf("expected no send error on message, got %s", message.SendError())
+		}
+		if conns := echoBuffer.count("220 go-mail test server ready"); conns != 3 {
+			t.Errorf("expected 3 connections, got %d", conns)
+		}
+	})
+	t.Run("temporary failure is retried until max attempts", func(t *testing.T) {
+		client, echoBuffer := testRetryClient(t, &serverProps{FailTemp: true}, WithRetryPolicy(policy))
+		message := testMessage(t)
+		err := client.DialAndSendWithContext(t.Context(), message)
+		if err == nil {
+			t.Fatal("expected send to fail")
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got %T", err)
+		}
+		if !sendErr.IsTemp() {
+			t.Error("expected temporary SendError")
+		}
+		if sendErr.Attempts() != 3 {
+			t.Errorf("expected 3 attempts, got %d", sendErr.Attempts())
+		}
+		if conns := echoBuffer.count("220 go-mail test server ready"); conns != 3 {
+			t.Errorf("expected 3 connections, got %d", conns)
+		}
+	})
+	t.Run("permanent failure is not retried", func(t *testing.T) {
+		client, echoBuffer := testRetryClient(t, &serverProps{FailOnDataClose: true}, WithRetryPolicy(policy))
+		err := client.DialAndSendWithContext(t.Context(), testMessage(t))
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got %s", err)
+		}
+		if sendErr.IsTemp() {
+			t.Error("expected permanent SendError")
+		}
+		if sendErr.Attempts() != 1 {
+			t.Errorf("expected 1 attempt, got %d", sendErr.Attempts())
+		}
+		if conns := echoBuffer.count("220 go-mail test server ready"); conns != 1 {
+			t.Errorf("expected 1 connection, got %d", conns)
+		}
+	})
+	t.Run("only temporarily failed messages are retried", func(t *testing.T) {
+		props := &serverProps{}
+		props.FailTempCount.Store(1)
+		client, echoBuffer := testRetryClient(t, props, WithRetryPolicy(policy))
+		first := testMessage(t)
+		second := testMessage(t)
+		if err := client.DialAndSendWithContext(t.Context(), first, second); err != nil {
+			t.Fatalf("failed to send messages: %s", err)
+		}
+		if !first.IsDelivered() || !second.IsDelivered() {
+			t.Error("expected both messages to be delivered")
+		}
+		if queued := echoBuffer.count("queued as"); queued != 2 {
+			t.Errorf("expected 2 queued messages, got %d", queued)
+		}
+	})
+	t.Run("dial failure is retried", func(t *testing.T) {
+		PortAdder.Add(1)
+		serverPort := int(TestServerPortBase + PortAdder.Load())
+		client, err := NewClient(DefaultHost, WithPort(serverPort), WithTLSPolicy(NoTLS),
+			WithTimeout(time.Millisecond*200), WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		err = client.DialAndSendWithContext(t.Context(), testMessage(t))
+		if err == nil {
+			t.Fatal("expected dial to fail")
+		}
+		if !strings.Contains(err.Error(), "dial failed") || !strings.Contains(err.Error(), "after 2 attempt(s)") {
+			t.Errorf("expected dial error after 2 attempts, got %s", err)
+		}
+	})
+	t.Run("context cancellation stops the backoff", func(t *testing.T) {
+		client, _ := testRetryClient(t, &serverProps{FailTemp: true},
+			WithRetryPolicy(&RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second * 10}))
+		ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*200)
+		defer cancel()
+		err := client.DialAndSendWithContext(ctx, testMessage(t))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected error to be %s, got %s", context.DeadlineExceeded, err)
+		}
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got %s", err)
+		}
+		if sendErr.Attempts() != 1 {
+			t.Errorf("expected 1 attempt, got %d", sendErr.Attempts())
+		}
+	})
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 10, BaseDelay: time.Millisecond * 100, MaxDelay: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Millisecond * 100},
+		{2, time.Millisecond * 200},
+		{3, time.Millisecond * 400},
+		{4, time.Millisecond * 800},
+		{5, time.Second},
+		{9, time.Second},
+	}
+	for _, tt := range tests {
+		delay := policy.backoff(tt.attempt)
+		if delay < tt.max/2 || delay > tt.max {
+			t.Errorf("expected delay for attempt %d to be between %s and %s, got %s", tt.attempt,
+				tt.max/2, tt.max, delay)
+		}
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"temporary SendError", &SendError{Reason: ErrSMTPDataClose, isTemp: true}, true},
+		{"permanent SendError", &SendError{Reason: ErrSMTPDataClose}, false},
+		{"connection check SendError", &SendError{Reason: ErrConnCheck}, true},
+		{"dropped session SendError", &SendError{Reason: ErrSMTPMailFrom, errlist: []error{errors.New("EOF")}}, false},
+		{"dropped session SendError with EOF", &SendError{Reason: ErrSMTPMailFrom, errlist: []error{fmt.Errorf("read: %w", io.EOF)}}, true},
+		{"no active connection", ErrNoActiveConnection, true},
+		{"generic error", errors.New("generic error"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableError(tt.err); got != tt.want {
+				t.Errorf("expected isRetryableError to be %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+// testRetryClient starts a test server with the given properties and returns a Client configured
+// to connect to it, alongside the echo buffer of the test server.
+func testRetryClient(t *testing.T, props *serverProps, opts ...Option) (*Client, *countingEchoBuffer) {
+	t.Helper()
+	ctx := t.Context()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	buffer := bytes.NewBuffer(nil)
+	props.EchoBuffer = buffer
+	props.FeatureSet = "250-8BITMIME\r\n250-DSN\r\n250 SMTPUTF8"
+	props.ListenPort = serverPort
+	go func() {
+		if err := simpleSMTPServer(ctx, t, props); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+
+	opts = append([]Option{WithPort(serverPort), WithTLSPolicy(NoTLS)}, opts...)
+	client, err := NewClient(DefaultHost, opts...)
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client, &countingEchoBuffer{props: props, buffer: buffer}
+}
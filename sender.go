@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Sender is the interface that wraps the Send method of the transports for a Msg.
+//
+// Send delivers the provided messages via the transport. It marks each successfully delivered Msg
+// as delivered and returns an error if the delivery of any Msg fails. Implementations continue
+// with the remaining messages after a failed delivery, unless the context has been canceled.
+//
+// Sender allows applications and tests to switch between the SMTPSender, SendmailSender,
+// DirSender and CaptureSender transports, or custom transports, without code changes.
+type Sender interface {
+	Send(ctx context.Context, messages ...*Msg) error
+}
+
+var (
+	_ Sender = (*Client)(nil)
+	_ Sender = (*SMTPSender)(nil)
+	_ Sender = (*SendmailSender)(nil)
+	_ Sender = (*DirSender)(nil)
+	_ Sender = (*CaptureSender)(nil)
+)
+
+// SMTPSender is a Sender that delivers messages via SMTP using a Client.
+//
+// While the Client itself implements Sender via an already established connection, SMTPSender
+// establishes a new connection for each call of Send and closes it afterward.
+type SMTPSender struct {
+	client *Client
+}
+
+// NewSMTPSender returns a new SMTPSender that delivers messages via the provided Client.
+//
+// Parameters:
+//   - client: A pointer to the Client used for the delivery.
+//
+// Returns:
+//   - A pointer to the new SMTPSender.
+func NewSMTPSender(client *Client) *SMTPSender {
+	return &SMTPSender{client: client}
+}
+
+// Send connects to the SMTP server of the Client, delivers the provided messages and closes the
+// connection, using Client.DialAndSendWithContext.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - messages: A variadic list of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error if the connection fails or if sending any of the messages fails; otherwise,
+//     returns nil.
+func (s *SMTPSender) Send(ctx context.Context, messages ...*Msg) error {
+	if s.client == nil {
+		return errors.New("no Client set for SMTPSender")
+	}
+	return s.client.DialAndSendWithContext(ctx, messages...)
+}
+
+// SendmailSender is a Sender that delivers messages via the local sendmail binary.
+type SendmailSender struct {
+	path string
+	args []string
+}
+
+// NewSendmailSender returns a new SendmailSender that pipes messages to the sendmail binary at
+// the provided path, using Msg.WriteToSendmailWithContext.
+//
+// Parameters:
+//   - path: The path to the sendmail binary. If empty, SendmailPath is used.
+//   - args: Additional arguments for the sendmail binary.
+//
+// Returns:
+//   - A pointer to the new SendmailSender.
+func NewSendmailSender(path string, args ...string) *SendmailSender {
+	if path == "" {
+		path = SendmailPath
+	}
+	return &SendmailSender{path: path, args: args}
+}
+
+// Send pipes each of the provided messages to the sendmail binary.
+//
+// Parameters:
+//   - ctx: The context.Context to control the timeout and cancellation of the sendmail process.
+//   - messages: A variadic list of pointers to Msg objects to be sent.
+//
+// Returns:
+//   - An error if sending any of the messages fails; otherwise, returns nil.
+func (s *SendmailSender) Send(ctx context.Context, messages ...*Msg) error {
+	return sendEach(ctx, messages, func(message *Msg) error {
+		return message.WriteToSendmailWithContext(ctx, s.path, s.args...)
+	})
+}
+
+// DirSender is a Sender that writes messages as EML files into a directory, e.g. to inspect them
+// during development or to hand them over to another process.
+type DirSender struct {
+	dir string
+}
+
+// NewDirSender returns a new DirSender that writes messages into the provided directory. The
+// directory is created if it does not exist.
+//
+// Parameters:
+//   - dir: The path of the directory.
+//
+// Returns:
+//   - A pointer to the new DirSender.
+//   - An error if the directory cannot be created.
+func NewDirSender(dir string) (*DirSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return &DirSender{dir: dir}, nil
+}
+
+// Send writes each of the provided messages into a new file with a unique name and the ".eml"
+// extension in the directory of the DirSender. Each file is written under a temporary name first
+// and renamed once complete, so that other processes never see partially written messages.
+//
+// Parameters:
+//   - ctx: The context.Context to control the cancellation.
+//   - messages: A variadic list of pointers to Msg objects to be written.
+//
+// Returns:
+//   - An error if writing any of the messages fails; otherwise, returns nil.
+func (s *DirSender) Send(ctx context.Context, messages ...*Msg) error {
+	return sendEach(ctx, messages, func(message *Msg) error {
+		random, err := randomStringSecure(12)
+		if err != nil {
+			return fmt.Errorf("failed to generate file name: %w", err)
+		}
+		name := filepath.Join(s.dir, time.Now().UTC().Format("20060102T150405.000000000Z")+"-"+random)
+		if err = message.WriteToFile(name + ".tmp"); err != nil {
+			_ = os.Remove(name + ".tmp")
+			return err
+		}
+		if err = os.Rename(name+".tmp", name+".eml"); err != nil {
+			_ = os.Remove(name + ".tmp")
+			return fmt.Errorf("failed to rename output file: %w", err)
+		}
+		return nil
+	})
+}
+
+// CapturedMsg is a Msg that has been captured by a CaptureSender.
+type CapturedMsg struct {
+	// Msg is the captured Msg.
+	Msg *Msg
+
+	// Data is the Msg as rendered at the time of the capture.
+	Data []byte
+}
+
+// CaptureSender is a Sender that captures messages in memory instead of delivering them, e.g. for
+// assertions in tests. It is safe for concurrent use.
+type CaptureSender struct {
+	mutex    sync.RWMutex
+	messages []CapturedMsg
+}
+
+// NewCaptureSender returns a new, empty CaptureSender.
+//
+// Returns:
+//   - A pointer to the new CaptureSender.
+func NewCaptureSender() *CaptureSender {
+	return &CaptureSender{}
+}
+
+// Send renders each of the provided messages and captures it.
+//
+// Parameters:
+//   - ctx: The context.Context to control the cancellation.
+//   - messages: A variadic list of pointers to Msg objects to be captured.
+//
+// Returns:
+//   - An error if rendering any of the messages fails; otherwise, returns nil.
+func (s *CaptureSender) Send(ctx context.Context, messages ...*Msg) error {
+	return sendEach(ctx, messages, func(message *Msg) error {
+		var buffer bytes.Buffer
+		if _, err := message.WriteTo(&buffer); err != nil {
+			return fmt.Errorf("failed to render message: %w", err)
+		}
+		s.mutex.Lock()
+		s.messages = append(s.messages, CapturedMsg{Msg: message, Data: buffer.Bytes()})
+		s.mutex.Unlock()
+		return nil
+	})
+}
+
+// Messages returns the messages captured so far, in the order they have been sent.
+//
+// Returns:
+//   - A slice of the CapturedMsg.
+func (s *CaptureSender) Messages() []CapturedMsg {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.messages)
+}
+
+// Reset removes all captured messages from the CaptureSender.
+func (s *CaptureSender) Reset() {
+	s.mutex.Lock()
+	s.messages = nil
+	s.mutex.Unlock()
+}
+
+// sendEach calls the provided send function for each of the messages and marks the messages as
+// delivered on success. It stops when the context is canceled.
+//
+// Parameters:
+//   - ctx: The context.Context to control the cancellation.
+//   - messages: A slice of pointers to the Msg objects to be sent.
+//   - send: The function that sends a single Msg.
+//
+// Returns:
+//   - The joined errors of the failed messages and the context, or nil if all messages have been
+//     sent successfully.
+func sendEach(ctx context.Context, messages []*Msg, send func(*Msg) error) error {
+	var errs []error
+	for _, message := range messages {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if message == nil {
+			continue
+		}
+		message.isDelivered = false
+		if err := send(message); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		message.isDelivered = true
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wneessen/go-mail/smtptest"
+)
+
+func TestSender(t *testing.T) {
+	server, err := smtptest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start test server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+	client, err := NewClient(server.Host(), WithPort(server.Port()), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	dirSender, err := NewDirSender(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create DirSender: %s", err)
+	}
+	senders := map[string]Sender{
+		"SMTPSender":    NewSMTPSender(client),
+		"DirSender":     dirSender,
+		"CaptureSender": NewCaptureSender(),
+	}
+	for name, sender := range senders {
+		t.Run(name+" delivers messages", func(t *testing.T) {
+			messages := []*Msg{testMessage(t), testMessage(t)}
+			if err := sender.Send(t.Context(), messages...); err != nil {
+				t.Fatalf("failed to send messages: %s", err)
+			}
+			for _, message := range messages {
+				if !message.IsDelivered() {
+					t.Error("expected message to be delivered")
+				}
+			}
+		})
+		t.Run(name+" fails on canceled context", func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			cancel()
+			message := testMessage(t)
+			if err := sender.Send(ctx, message); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got: %s", err)
+			}
+			if message.IsDelivered() {
+				t.Error("expected message not to be delivered")
+			}
+		})
+	}
+	if len(server.Messages()) != 2 {
+		t.Errorf("expected 2 messages on the test server, got: %d", len(server.Messages()))
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	t.Run("SMTPSender without client fails", func(t *testing.T) {
+		if err := (&SMTPSender{}).Send(t.Context(), testMessage(t)); err == nil {
+			t.Error("expected SMTPSender without client to fail")
+		}
+	})
+}
+
+func TestNewSendmailSender(t *testing.T) {
+	t.Run("empty path uses SendmailPath", func(t *testing.T) {
+		sender := NewSendmailSender("")
+		if sender.path != SendmailPath {
+			t.Errorf("expected path %s, got: %s", SendmailPath, sender.path)
+		}
+	})
+	t.Run("custom path and arguments", func(t *testing.T) {
+		sender := NewSendmailSender("/usr/local/bin/sendmail", "-f", "toni.tester@domain.tld")
+		if sender.path != "/usr/local/bin/sendmail" || len(sender.args) != 2 {
+			t.Errorf("unexpected SendmailSender: %+v", sender)
+		}
+	})
+	t.Run("non-existing sendmail binary fails", func(t *testing.T) {
+		sender := NewSendmailSender(filepath.Join(t.TempDir(), "sendmail"))
+		message := testMessage(t)
+		if err := sender.Send(t.Context(), message); err == nil {
+			t.Error("expected non-existing sendmail binary to fail")
+		}
+		if message.IsDelivered() {
+			t.Error("expected message not to be delivered")
+		}
+	})
+}
+
+func TestDirSender_Send(t *testing.T) {
+	t.Run("messages are written to EML files", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "outbox")
+		sender, err := NewDirSender(dir)
+		if err != nil {
+			t.Fatalf("failed to create DirSender: %s", err)
+		}
+		if err = sender.Send(t.Context(), testMessage(t), testMessage(t)); err != nil {
+			t.Fatalf("failed to send messages: %s", err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("failed to read output directory: %s", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 files, got: %d", len(entries))
+		}
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), ".eml") {
+				t.Errorf("expected EML file, got: %s", entry.Name())
+			}
+			data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err != nil {
+				t.Fatalf("failed to read output file: %s", err)
+			}
+			if !bytes.Contains(data, []byte("Subject: Testmail")) {
+				t.Errorf("expected message in output file, got: %s", data)
+			}
+		}
+	})
+	t.Run("NewDirSender fails on file path", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(file, nil, 0o600); err != nil {
+			t.Fatalf("failed to create file: %s", err)
+		}
+		if _, err := NewDirSender(file); err == nil {
+			t.Error("expected NewDirSender to fail on file path")
+		}
+	})
+	t.Run("Send fails on removed directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "outbox")
+		sender, err := NewDirSender(dir)
+		if err != nil {
+			t.Fatalf("failed to create DirSender: %s", err)
+		}
+		if err = os.RemoveAll(dir); err != nil {
+			t.Fatalf("failed to remove output directory: %s", err)
+		}
+		message := testMessage(t)
+		if err = sender.Send(t.Context(), message); err == nil {
+			t.Error("expected Send to fail on removed directory")
+		}
+		if message.IsDelivered() {
+			t.Error("expected message not to be delivered")
+		}
+	})
+}
+
+func TestCaptureSender(t *testing.T) {
+	sender := NewCaptureSender()
+	message := testMessage(t)
+	if err := sender.Send(t.Context(), message, nil); err != nil {
+		t.Fatalf("failed to send message: %s", err)
+	}
+	message.Subject("Changed after sending")
+	captured := sender.Messages()
+	if len(captured) != 1 {
+		t.Fatalf("expected 1 captured message, got: %d", len(captured))
+	}
+	if captured[0].Msg != message {
+		t.Error("expected captured Msg to be the sent Msg")
+	}
+	if !bytes.Contains(captured[0].Data, []byte("Subject: Testmail")) {
+		t.Errorf("expected captured data to be rendered at send time, got: %s", captured[0].Data)
+	}
+	sender.Reset()
+	if len(sender.Messages()) != 0 {
+		t.Error("expected captured messages to be reset")
+	}
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+//go:build linux || freebsd
+
+package mail
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSendmailSender_Send_unixOnly(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output")
+	sendmail := filepath.Join(dir, "sendmail")
+	script := "#!/bin/sh\necho \"$@\" > " + output + ".args\ncat >> " + output + "\n"
+	if err := os.WriteFile(sendmail, []byte(script), 0o700); err != nil {
+		t.Fatalf("failed to create fake sendmail binary: %s", err)
+	}
+	sender := NewSendmailSender(sendmail, "-f", TestSenderValid)
+	messages := []*Msg{testMessage(t), testMessage(t)}
+	if err := sender.Send(t.Context(), messages...); err != nil {
+		t.Fatalf("failed to send messages: %s", err)
+	}
+	for _, message := range messages {
+		if !message.IsDelivered() {
+			t.Error("expected message to be delivered")
+		}
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("failed to read sendmail output: %s", err)
+	}
+	if count := bytes.Count(data, []byte("Subject: Testmail")); count != 2 {
+		t.Errorf("expected 2 messages piped to sendmail, got: %d", count)
+	}
+	args, err := os.ReadFile(output + ".args")
+	if err != nil {
+		t.Fatalf("failed to read sendmail arguments: %s", err)
+	}
+	if string(args) != "-oi -t -f "+TestSenderValid+"\n" {
+		t.Errorf("unexpected sendmail arguments: %q", args)
+	}
+}
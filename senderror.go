@@ -51,6 +51,19 @@ const (
 	// unencoded delivery but the server does not support this
 	ErrNoUnencoded
 
+	// ErrMessageTooLarge is returned if the Msg delivery failed because the size of the Msg
+	// exceeds the maximum message size advertised by the server with the SIZE extension
+	ErrMessageTooLarge
+
+	// ErrNoRequireTLS is returned if the Msg delivery failed because the Msg requires TLS, but
+	// the connection is not TLS protected or the server does not support REQUIRETLS
+	ErrNoRequireTLS
+
+	// ErrPartialDelivery is returned if the Msg has been delivered to some of the recipients
+	// only, while the delivery to the other recipients failed. Msg.RcptResults holds the
+	// outcome for each recipient
+	ErrPartialDelivery
+
 	// ErrAmbiguous is a generalized delivery error for the SendError type that is
 	// returned if the exact reason for the delivery failure is ambiguous
 	ErrAmbiguous
@@ -63,6 +76,7 @@ const (
 // the error is temporary or permanent. It also includes a reason code for the error.
 type SendError struct {
 	affectedMsg        *Msg
+	attempts           int
 	errcode            int
 	enhancedStatusCode string
 	errlist            []error
@@ -200,6 +214,24 @@ func (e *SendError) EnhancedStatusCode() string {
 	return e.enhancedStatusCode
 }
 
+// Attempts returns the number of delivery attempts that have been performed for the affected Msg.
+//
+// The number of attempts is recorded if the Client has been configured with a RetryPolicy via
+// WithRetryPolicy. Without a RetryPolicy, only a single attempt is performed and the method
+// returns 1.
+//
+// Returns:
+//   - The number of delivery attempts, or 0 if the SendError is nil.
+func (e *SendError) Attempts() int {
+	if e == nil {
+		return 0
+	}
+	if e.attempts == 0 {
+		return 1
+	}
+	return e.attempts
+}
+
 // ErrorCode returns the error code of the server response.
 //
 // This function retrieves the error code the error returned by the server. The error code will
@@ -245,6 +277,12 @@ func (r SendErrReason) String() string {
 		return "checking SMTP connection"
 	case ErrNoUnencoded:
 		return ErrServerNoUnencoded.Error()
+	case ErrMessageTooLarge:
+		return ErrServerMessageSize.Error()
+	case ErrNoRequireTLS:
+		return ErrServerNoRequireTLS.Error()
+	case ErrPartialDelivery:
+		return "delivery failed for some of the recipients"
 	case ErrAmbiguous:
 		return "ambiguous reason, check Msg.SendError for message specific reasons"
 	}
@@ -39,6 +39,12 @@ func TestSendError_Error(t *testing.T) {
 			{"ErrConnCheck/perm", ErrConnCheck, false},
 			{"ErrNoUnencoded/temp", ErrNoUnencoded, true},
 			{"ErrNoUnencoded/perm", ErrNoUnencoded, false},
+			{"ErrMessageTooLarge/temp", ErrMessageTooLarge, true},
+			{"ErrMessageTooLarge/perm", ErrMessageTooLarge, false},
+			{"ErrNoRequireTLS/temp", ErrNoRequireTLS, true},
+			{"ErrNoRequireTLS/perm", ErrNoRequireTLS, false},
+			{"ErrPartialDelivery/temp", ErrPartialDelivery, true},
+			{"ErrPartialDelivery/perm", ErrPartialDelivery, false},
 			{"ErrAmbiguous/temp", ErrAmbiguous, true},
 			{"ErrAmbiguous/perm", ErrAmbiguous, false},
 			{"Unknown/temp", 9999, true},
@@ -249,6 +255,27 @@ func TestSendError_EnhancedStatusCode(t *testing.T) {
 	})
 }
 
+func TestSendError_Attempts(t *testing.T) {
+	t.Run("SendError without recorded attempts", func(t *testing.T) {
+		err := &SendError{Reason: ErrSMTPDataClose}
+		if err.Attempts() != 1 {
+			t.Errorf("expected attempts: %d, got: %d", 1, err.Attempts())
+		}
+	})
+	t.Run("SendError with recorded attempts", func(t *testing.T) {
+		err := &SendError{Reason: ErrSMTPDataClose, attempts: 3}
+		if err.Attempts() != 3 {
+			t.Errorf("expected attempts: %d, got: %d", 3, err.Attempts())
+		}
+	})
+	t.Run("attempts on nil error should return 0", func(t *testing.T) {
+		var err *SendError
+		if err.Attempts() != 0 {
+			t.Error("expected 0 attempts on nil-senderror")
+		}
+	})
+}
+
 func TestSendError_ErrorCode(t *testing.T) {
 	t.Run("ErrorCode with a go-mail error should return 0", func(t *testing.T) {
 		err := &SendError{
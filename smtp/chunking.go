@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: Copyright (c) The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/wneessen/go-mail/log"
+)
+
+// DefaultChunkSize is the size of the chunks in bytes, that are transmitted with a single BDAT
+// command by the ChunkWriter.
+const DefaultChunkSize = 1 << 18
+
+var (
+	// ErrChunkingNotSupported is returned when a BDAT transfer is requested but the server does not
+	// advertise the CHUNKING extension.
+	ErrChunkingNotSupported = errors.New("smtp: server does not support CHUNKING")
+
+	// ErrChunkWriterClosed is returned when data is written to a ChunkWriter that has already been
+	// closed.
+	ErrChunkWriterClosed = errors.New("smtp: chunk writer is closed")
+)
+
+// ChunkWriter is an io.WriteCloser that transmits the message data to the server in chunks using
+// the BDAT command, as described in RFC 3030.
+//
+// Data written to the ChunkWriter is buffered and sent as a BDAT command, once DefaultChunkSize
+// bytes have been collected. Closing the ChunkWriter sends the remaining data with the final
+// "BDAT <size> LAST" command. Unlike the DATA command, BDAT does not require dot-stuffing.
+//
+// Unless the message is transmitted with BODY=BINARYMIME, the message data still has to consist of
+// CRLF terminated lines, so the ChunkWriter converts bare LF line endings into CRLF, like the
+// writer returned by [Client.Data] does. With BODY=BINARYMIME, the data is transmitted unaltered,
+// which allows to transmit binary message content.
+//
+// https://datatracker.ietf.org/doc/html/rfc3030
+type ChunkWriter struct {
+	buf       []byte
+	c         *Client
+	done      bool
+	lastCR    bool
+	normalize bool
+	response  string
+}
+
+// Bdat returns a ChunkWriter that can be used to write the mail headers and body using BDAT
+// commands. The caller should close the writer before calling any more methods on c. A call
+// to Bdat must be preceded by one or more calls to [Client.Rcpt].
+//
+// The server must advertise the CHUNKING extension.
+//
+// https://datatracker.ietf.org/doc/html/rfc3030
+func (c *Client) Bdat() (*ChunkWriter, error) {
+	if err := c.hello(); err != nil {
+		return nil, err
+	}
+	if ok, _ := c.Extension("CHUNKING"); !ok {
+		return nil, ErrChunkingNotSupported
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.newChunkWriter(), nil
+}
+
+// newChunkWriter returns a new ChunkWriter for the Client. Line endings are normalized to CRLF,
+// unless the message is transmitted with BODY=BINARYMIME. The caller must hold the mutex of the
+// Client.
+func (c *Client) newChunkWriter() *ChunkWriter {
+	_, hasBinaryMIME := c.ext["BINARYMIME"]
+	return &ChunkWriter{
+		c:         c,
+		buf:       make([]byte, 0, DefaultChunkSize),
+		normalize: !c.binaryMIME || !hasBinaryMIME,
+	}
+}
+
+// Write buffers the provided data and transmits it to the server in chunks of DefaultChunkSize.
+// Unless the message is transmitted with BODY=BINARYMIME, bare LF line endings are converted
+// into CRLF. The returned byte count refers to the provided data.
+func (w *ChunkWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, ErrChunkWriterClosed
+	}
+	if !w.normalize {
+		return w.write(p)
+	}
+	written := 0
+	for len(p) > 0 {
+		index := bytes.IndexByte(p, '\n')
+		if index < 0 {
+			n, err := w.write(p)
+			w.lastCR = p[len(p)-1] == '\r'
+			return written + n, err
+		}
+		precededByCR := w.lastCR
+		if index > 0 {
+			precededByCR = p[index-1] == '\r'
+		}
+		n, err := w.write(p[:index])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		if !precededByCR {
+			if _, err = w.write([]byte{'\r'}); err != nil {
+				return written, err
+			}
+		}
+		if _, err = w.write([]byte{'\n'}); err != nil {
+			return written, err
+		}
+		written++
+		w.lastCR = false
+		p = p[index+1:]
+	}
+	return written, nil
+}
+
+// write buffers the provided data unaltered and transmits it to the server in chunks of
+// DefaultChunkSize.
+func (w *ChunkWriter) write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.sendChunk(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close transmits the remaining buffered data with the final BDAT command and waits for the
+// response of the server.
+func (w *ChunkWriter) Close() error {
+	if w.done {
+		return ErrChunkWriterClosed
+	}
+	w.done = true
+	return w.sendChunk(true)
+}
+
+// ServerResponse returns the response that was returned by the server after the ChunkWriter
+// has been closed. If the ChunkWriter has not been closed yet, it will return an empty string.
+func (w *ChunkWriter) ServerResponse() string {
+	if !w.done {
+		return ""
+	}
+	return w.response
+}
+
+// sendChunk transmits the buffered data with a BDAT command and reads the response of the server.
+// If last is true, the BDAT command is flagged as the last chunk of the message.
+func (w *ChunkWriter) sendChunk(last bool) error {
+	w.c.mutex.Lock()
+	defer w.c.mutex.Unlock()
+
+	format := "BDAT %d"
+	if last {
+		format += " LAST"
+	}
+	w.c.debugLog(log.DirClientToServer, format, len(w.buf))
+	if _, err := fmt.Fprintf(w.c.Text.W, format+"\r\n", len(w.buf)); err != nil {
+		return err
+	}
+	if _, err := w.c.Text.W.Write(w.buf); err != nil {
+		return err
+	}
+	if err := w.c.Text.W.Flush(); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+
+	_, msg, err := w.c.readResponse(250, format)
+	if last {
+		w.response = msg
+	}
+	return err
+}
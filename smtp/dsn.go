@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: Copyright (c) The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtp
+
+import (
+	"maps"
+	"strings"
+)
+
+// SetDSNEnvelopeID sets the DSN envelope identifier for the Mail method. If the server supports
+// the DSN extension and the envelope identifier is not empty, the "MAIL FROM" command will carry
+// the xtext encoded envelope identifier in the ENVID parameter. The server includes it in any
+// DSN it issues for the mail transaction.
+//
+// https://datatracker.ietf.org/doc/html/rfc3461#section-4.4
+func (c *Client) SetDSNEnvelopeID(id string) {
+	c.mutex.Lock()
+	c.dsnenvid = id
+	c.mutex.Unlock()
+}
+
+// SetDSNOriginalRcpts sets the DSN original recipients for the Rcpt method. The keys of the map are
+// the recipient addresses as passed to [Client.Rcpt] or [Client.MailRcptPipelined], the values are the
+// original recipient addresses. If the server supports the DSN extension, the "RCPT TO" command for
+// a recipient in the map will carry the xtext encoded original recipient in the ORCPT parameter,
+// using the "rfc822" address type. Passing nil removes all original recipients.
+//
+// https://datatracker.ietf.org/doc/html/rfc3461#section-4.2
+func (c *Client) SetDSNOriginalRcpts(orcpts map[string]string) {
+	c.mutex.Lock()
+	c.dsnorcpts = maps.Clone(orcpts)
+	c.mutex.Unlock()
+}
+
+// xtextEncode encodes the given string as xtext. All characters outside the range of printable
+// US-ASCII characters, as well as "+" and "=", are replaced by a "+" followed by the two digit
+// uppercase hexadecimal value of the character.
+//
+// https://datatracker.ietf.org/doc/html/rfc3461#section-4
+func xtextEncode(value string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var builder strings.Builder
+	builder.Grow(len(value))
+	for i := 0; i < len(value); i++ {
+		char := value[i]
+		if char < '!' || char > '~' || char == '+' || char == '=' {
+			builder.WriteByte('+')
+			builder.WriteByte(hexDigits[char>>4])
+			builder.WriteByte(hexDigits[char&0x0f])
+			continue
+		}
+		builder.WriteByte(char)
+	}
+	return builder.String()
+}
+
+// escapeFormat escapes the percent signs in the given string, so that it can be used as part of
+// a format string.
+func escapeFormat(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: Copyright (c) The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+
+	"github.com/wneessen/go-mail/log"
+)
+
+// RcptResponse holds the response of the server for a single recipient, either for the RCPT command
+// or, in LMTP mode, after the message data has been transmitted.
+//
+// https://datatracker.ietf.org/doc/html/rfc2033#section-4.2
+type RcptResponse struct {
+	// Rcpt is the recipient address as passed to [Client.Rcpt] or [Client.MailRcptPipelined].
+	Rcpt string
+
+	// Code is the reply code of the server for the recipient.
+	Code int
+
+	// Msg is the reply text of the server for the recipient.
+	Msg string
+
+	// Err is the error returned by the server for the recipient, or nil if the recipient has been
+	// accepted or the message has been delivered to the recipient.
+	Err error
+}
+
+// newRcptResponse returns the RcptResponse for a recipient. If the response of the server could not
+// be read as a whole, the reply code and text are taken from the error.
+func newRcptResponse(rcpt string, code int, msg string, err error) RcptResponse {
+	var protoErr *textproto.Error
+	if code == 0 && errors.As(err, &protoErr) {
+		code, msg = protoErr.Code, protoErr.Msg
+	}
+	return RcptResponse{Rcpt: rcpt, Code: code, Msg: msg, Err: err}
+}
+
+// SetLMTP sets or unsets the LMTP mode of the Client.
+//
+// In LMTP mode, the Client greets the server with LHLO instead of EHLO and does not fall back to
+// HELO. After the message data has been transmitted, the server returns a response for each
+// recipient that has been accepted, which are available via [DataCloser.RcptResponses]. CHUNKING
+// is not used in pipelined mail transactions in LMTP mode.
+//
+// SetLMTP must be called before any other command is sent to the server.
+//
+// https://datatracker.ietf.org/doc/html/rfc2033
+func (c *Client) SetLMTP(v bool) {
+	c.mutex.Lock()
+	c.lmtp = v
+	c.mutex.Unlock()
+}
+
+// isLMTP reports whether the Client is in LMTP mode.
+func (c *Client) isLMTP() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.lmtp
+}
+
+// RcptResponses returns the per-recipient responses of the LMTP server after the DataCloser has
+// been closed. The responses have the same order as the accepted recipients. If the Client is not
+// in LMTP mode or the DataCloser has not been closed yet, it returns nil.
+func (d *DataCloser) RcptResponses() []RcptResponse {
+	if !d.done {
+		return nil
+	}
+	return d.rcptResponses
+}
+
+// readRcptResponses reads a response for each recipient that has been accepted in the current
+// mail transaction, as sent by an LMTP server after the message data.
+//
+// The caller must hold the Client's mutex.
+//
+// Returns:
+//   - A RcptResponse for each accepted recipient.
+//   - An error joining the errors of the rejected recipients, or an I/O error if reading the
+//     responses failed.
+func (c *Client) readRcptResponses() ([]RcptResponse, error) {
+	responses := make([]RcptResponse, 0, len(c.rcpts))
+	var errs []error
+	for _, rcpt := range c.rcpts {
+		code, msg, err := c.Text.ReadResponse(250)
+		c.debugLog(log.DirServerToClient, "%d %s", code, msg)
+		if err != nil && !isProtocolError(err) {
+			return responses, err
+		}
+		responses = append(responses, RcptResponse{Rcpt: rcpt, Code: code, Msg: msg, Err: err})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rcpt, err))
+		}
+	}
+	c.rcpts = nil
+	return responses, errors.Join(errs...)
+}
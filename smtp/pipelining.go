@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: Copyright (c) The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+
+	"github.com/wneessen/go-mail/log"
+)
+
+// ErrPipeliningNotSupported is returned when a pipelined mail transaction is requested but the
+// server does not advertise the PIPELINING extension.
+var ErrPipeliningNotSupported = errors.New("smtp: server does not support PIPELINING")
+
+// PipelineResult holds the server responses of a pipelined mail transaction as sent by
+// [Client.MailRcptPipelined].
+type PipelineResult struct {
+	// MailErr is the error returned by the server for the MAIL command, or nil if the sender
+	// was accepted.
+	MailErr error
+
+	// RcptErrs holds the errors returned by the server for the RCPT commands. The slice has
+	// the same order and length as the recipients passed to [Client.MailRcptPipelined]. An entry
+	// is nil if the corresponding recipient was accepted.
+	RcptErrs []error
+
+	// RcptResponses holds the responses of the server for the RCPT commands, in the same order
+	// and length as the recipients passed to [Client.MailRcptPipelined].
+	RcptResponses []RcptResponse
+}
+
+// HasRcptErr returns true if any of the RCPT commands of the pipelined mail transaction
+// has been rejected by the server.
+func (r *PipelineResult) HasRcptErr() bool {
+	for _, err := range r.RcptErrs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// MailRcptPipelined sends the MAIL command and a RCPT command for each of the provided
+// recipients to the server in a single batch, as described in RFC 2920, and reads the responses
+// afterward in the same order. The DATA command is not part of the batch, so the caller can
+// decide whether to proceed with [Client.Data] or [Client.Bdat], or to abort the mail transaction
+// with [Client.Reset], after checking the responses.
+//
+// The server must advertise the PIPELINING extension. Errors returned by the server for the
+// individual commands are reported in the returned PipelineResult, while the returned error is
+// only set if the commands could not be sent or the responses could not be read.
+//
+// https://datatracker.ietf.org/doc/html/rfc2920
+func (c *Client) MailRcptPipelined(from string, to []string) (*PipelineResult, error) {
+	if err := validateLine(from); err != nil {
+		return nil, err
+	}
+	for _, rcpt := range to {
+		if err := validateLine(rcpt); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.hello(); err != nil {
+		return nil, err
+	}
+	if ok, _ := c.Extension("PIPELINING"); !ok {
+		return nil, ErrPipeliningNotSupported
+	}
+
+	mailFormat := c.mailCmd()
+	rcptFormats := make([]string, len(to))
+	for i, rcpt := range to {
+		rcptFormats[i] = c.rcptCmd(rcpt)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rcpts = nil
+
+	c.debugLog(log.DirClientToServer, mailFormat, from)
+	var batch strings.Builder
+	batch.WriteString(fmt.Sprintf(mailFormat, from) + "\r\n")
+	for i, rcpt := range to {
+		c.debugLog(log.DirClientToServer, rcptFormats[i], rcpt)
+		batch.WriteString(fmt.Sprintf(rcptFormats[i], rcpt) + "\r\n")
+	}
+	if _, err := c.Text.W.WriteString(batch.String()); err != nil {
+		return nil, err
+	}
+	if err := c.Text.W.Flush(); err != nil {
+		return nil, err
+	}
+
+	result := &PipelineResult{RcptErrs: make([]error, len(to)), RcptResponses: make([]RcptResponse, len(to))}
+	if _, _, err := c.readResponse(250, mailFormat); err != nil {
+		if !isProtocolError(err) {
+			return nil, err
+		}
+		result.MailErr = err
+	}
+	for i, rcpt := range to {
+		code, msg, rerr := c.readResponse(25, rcptFormats[i])
+		result.RcptResponses[i] = newRcptResponse(rcpt, code, msg, rerr)
+		if rerr != nil {
+			if !isProtocolError(rerr) {
+				return nil, rerr
+			}
+			result.RcptErrs[i] = rerr
+			continue
+		}
+		c.rcpts = append(c.rcpts, rcpt)
+	}
+	return result, nil
+}
+
+// isProtocolError returns true if the provided error is an error response returned by the
+// server, as opposed to an I/O error on the connection.
+func isProtocolError(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr)
+}
@@ -14,10 +14,17 @@
 // Package smtp implements the Simple Mail Transfer Protocol as defined in RFC 5321.
 // It also implements the following extensions:
 //
-//	8BITMIME  RFC 1652
-//	AUTH      RFC 2554
-//	STARTTLS  RFC 3207
-//	DSN       RFC 1891
+//	8BITMIME    RFC 1652
+//	AUTH        RFC 2554
+//	STARTTLS    RFC 3207
+//	DSN         RFC 1891, RFC 3461
+//	PIPELINING  RFC 2920
+//	CHUNKING    RFC 3030
+//	BINARYMIME  RFC 3030
+//	SIZE        RFC 1870
+//	REQUIRETLS  RFC 8689
+//
+// The Local Mail Transfer Protocol as defined in RFC 2033 is supported via [Client.SetLMTP].
 package smtp
 
 import (
@@ -29,6 +36,7 @@ import (
 	"net"
 	"net/textproto"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -60,6 +68,13 @@ type Client struct {
 	// authIsActive indicates that the Client is currently during SMTP authentication
 	authIsActive bool
 
+	// authMech is the SASL mechanism of the successful SMTP authentication
+	authMech string
+
+	// binaryMIME indicates that the Client should request BODY=BINARYMIME in the "MAIL FROM"
+	// command, if the server supports it.
+	binaryMIME bool
+
 	// keep a reference to the connection so it can be used to create a TLS connection later
 	conn net.Conn
 
@@ -69,9 +84,15 @@ type Client struct {
 	// didHello indicates whether we've said HELO/EHLO
 	didHello bool
 
+	// dsnenvid defines the envelope identifier in case DSN is enabled
+	dsnenvid string
+
 	// dsnmrtype defines the mail return option in case DSN is enabled
 	dsnmrtype string
 
+	// dsnorcpts maps the recipients to their original recipients in case DSN is enabled
+	dsnorcpts map[string]string
+
 	// dsnrntype defines the recipient notify option in case DSN is enabled
 	dsnrntype string
 
@@ -87,6 +108,9 @@ type Client struct {
 	// isConnected indicates if the Client has an active connection
 	isConnected bool
 
+	// lmtp indicates that the Client speaks LMTP instead of SMTP
+	lmtp bool
+
 	// logAuthData indicates if the Client should include SMTP authentication data in the logs
 	logAuthData bool
 
@@ -96,10 +120,23 @@ type Client struct {
 	// logger will be used for debug logging
 	logger log.Logger
 
+	// msgSize is the size of the message in bytes that is announced with the SIZE parameter in the
+	// "MAIL FROM" command, if the server supports it.
+	msgSize int64
+
 	// mutex is used to synchronize access to shared resources, ensuring that only one goroutine can access
 	// the resource at a time.
 	mutex sync.RWMutex
 
+	// rcpts holds the recipients that have been accepted by the server in the current mail
+	// transaction. It is used to read the per-recipient responses after the message data in
+	// LMTP mode.
+	rcpts []string
+
+	// requireTLS indicates that the Client should add the REQUIRETLS parameter to the "MAIL FROM"
+	// command, if the server supports it.
+	requireTLS bool
+
 	// skipUTF8 indicates whether the Client should skip SMTPUTF8 in "MAIL FROM" commands, even if the
 	// server advertises support for SMTPUTF8.
 	skipUTF8 bool
@@ -160,7 +197,10 @@ func (c *Client) hello() error {
 	if !c.didHello {
 		c.didHello = true
 		err := c.ehlo()
-		if err != nil {
+		if err != nil && c.isLMTP() {
+			c.helloError = fmt.Errorf("smtp: LHLO exchange failed: %w", err)
+		}
+		if err != nil && !c.isLMTP() {
 			if heloErr := c.helo(); heloErr != nil {
 				c.helloError = fmt.Errorf("smtp: EHLO/HELO exchange failed. EHLO response: %w, HELO response: %w",
 					err, heloErr)
@@ -224,6 +264,18 @@ func (c *Client) cmd(expectCode int, format string, args ...any) (int, string, e
 	}
 	c.Text.StartResponse(id)
 	defer c.Text.EndResponse(id)
+	code, msg, err := c.readResponse(expectCode, format)
+
+	c.mutex.Unlock()
+	return code, msg, err
+}
+
+// readResponse reads the response to a previously sent command from the server. If reading the
+// response fails, the error is passed to the ResponseErrorHandler that is registered for the
+// command, and the response is read again if the handler recovered from the error.
+//
+// The caller must hold the Client's mutex.
+func (c *Client) readResponse(expectCode int, format string) (int, string, error) {
 	code, msg, err := c.Text.ReadResponse(expectCode)
 	if err != nil {
 		fmtValues := strings.Split(format, " ")
@@ -231,7 +283,6 @@ func (c *Client) cmd(expectCode int, format string, args ...any) (int, string, e
 		handler := c.ErrorHandlerRegistry.GetHandler(c.serverName, currentCmd)
 		handledErr := handler.HandleError(c.serverName, currentCmd, c.Text, err)
 		if handledErr != nil {
-			c.mutex.Unlock()
 			return 0, "", handledErr
 		}
 
@@ -240,13 +291,12 @@ func (c *Client) cmd(expectCode int, format string, args ...any) (int, string, e
 		code, msg, err = c.Text.ReadResponse(expectCode)
 	}
 
-	logMsg = []any{code, msg}
+	logMsg := []any{code, msg}
 	if c.authIsActive && code >= 300 && code <= 400 {
 		logMsg = []any{code, "<SMTP auth data redacted>"}
 	}
 	c.debugLog(log.DirServerToClient, "%d %s", logMsg...)
 
-	c.mutex.Unlock()
 	return code, msg, err
 }
 
@@ -378,13 +428,24 @@ func (c *Client) Auth(a Auth) error {
 		encoding.Encode(resp64, resp)
 		code, msg64, err = c.cmd(0, "%s", resp64)
 	}
+	if err == nil {
+		c.mutex.Lock()
+		c.authMech = mech
+		c.mutex.Unlock()
+	}
 	return err
 }
 
 // Mail issues a MAIL command to the server using the provided email address.
 // If the server supports the 8BITMIME extension, Mail adds the BODY=8BITMIME
-// parameter. If the server supports the SMTPUTF8 extension, Mail adds the
-// SMTPUTF8 parameter.
+// parameter. If BINARYMIME has been requested via [Client.SetBinaryMIME] and the
+// server supports the BINARYMIME and CHUNKING extensions, Mail adds the
+// BODY=BINARYMIME parameter instead. If the server supports the SMTPUTF8
+// extension, Mail adds the SMTPUTF8 parameter. If the server supports the SIZE
+// extension and a message size has been set via [Client.SetMessageSize], Mail
+// adds the SIZE parameter. If the server supports the REQUIRETLS extension and
+// it has been requested via [Client.SetRequireTLS], Mail adds the REQUIRETLS
+// parameter.
 // This initiates a mail transaction and is followed by one or more [Client.Rcpt] calls.
 func (c *Client) Mail(from string) error {
 	if err := validateLine(from); err != nil {
@@ -393,62 +454,119 @@ func (c *Client) Mail(from string) error {
 	if err := c.hello(); err != nil {
 		return err
 	}
+	c.mutex.Lock()
+	c.rcpts = nil
+	c.mutex.Unlock()
+	_, _, err := c.cmd(250, c.mailCmd(), from)
+	return err
+}
+
+// mailCmd returns the format string for the MAIL command, including the parameters for the
+// extensions supported by the server.
+func (c *Client) mailCmd() string {
 	cmdStr := "MAIL FROM:%s"
 
 	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	if c.ext != nil {
-		if _, ok := c.ext["8BITMIME"]; ok {
+		_, hasBinaryMIME := c.ext["BINARYMIME"]
+		_, hasChunking := c.ext["CHUNKING"]
+		_, has8BitMIME := c.ext["8BITMIME"]
+		switch {
+		case c.binaryMIME && hasBinaryMIME && hasChunking:
+			cmdStr += " BODY=BINARYMIME"
+		case has8BitMIME:
 			cmdStr += " BODY=8BITMIME"
 		}
 		if _, ok := c.ext["SMTPUTF8"]; ok && !c.skipUTF8 {
 			cmdStr += " SMTPUTF8"
 		}
+		if _, ok := c.ext["SIZE"]; ok && c.msgSize > 0 {
+			cmdStr += fmt.Sprintf(" SIZE=%d", c.msgSize)
+		}
+		if _, ok := c.ext["REQUIRETLS"]; ok && c.requireTLS {
+			cmdStr += " REQUIRETLS"
+		}
 		_, ok := c.ext["DSN"]
 		if ok && c.dsnmrtype != "" {
 			cmdStr += fmt.Sprintf(" RET=%s", c.dsnmrtype)
 		}
+		if ok && c.dsnenvid != "" {
+			cmdStr += " ENVID=" + escapeFormat(xtextEncode(c.dsnenvid))
+		}
 	}
-	c.mutex.RUnlock()
-
-	_, _, err := c.cmd(250, cmdStr, from)
-	return err
+	return cmdStr
 }
 
 // Rcpt issues a RCPT command to the server using the provided email address.
 // A call to Rcpt must be preceded by a call to [Client.Mail] and may be followed by
 // a [Client.Data] call or another Rcpt call.
 func (c *Client) Rcpt(to string) error {
+	_, err := c.RcptWithResponse(to)
+	return err
+}
+
+// RcptWithResponse issues a RCPT command to the server using the provided email address, like
+// [Client.Rcpt], and additionally returns the response of the server for the recipient.
+func (c *Client) RcptWithResponse(to string) (RcptResponse, error) {
 	if err := validateLine(to); err != nil {
-		return err
+		return RcptResponse{Rcpt: to, Err: err}, err
 	}
 
+	code, msg, err := c.cmd(25, c.rcptCmd(to), to)
+	response := newRcptResponse(to, code, msg, err)
+	if err == nil {
+		c.mutex.Lock()
+		c.rcpts = append(c.rcpts, to)
+		c.mutex.Unlock()
+	}
+	return response, err
+}
+
+// rcptCmd returns the format string for the RCPT command, including the parameters for the
+// extensions supported by the server, for the given recipient.
+func (c *Client) rcptCmd(to string) string {
+	cmdStr := "RCPT TO:%s"
+
 	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 	_, ok := c.ext["DSN"]
-	c.mutex.RUnlock()
-
 	if ok && c.dsnrntype != "" {
-		_, _, err := c.cmd(25, "RCPT TO:%s NOTIFY=%s", to, c.dsnrntype)
-		return err
+		cmdStr += fmt.Sprintf(" NOTIFY=%s", c.dsnrntype)
 	}
-	_, _, err := c.cmd(25, "RCPT TO:%s", to)
-	return err
+	if orcpt := c.dsnorcpts[to]; ok && orcpt != "" {
+		cmdStr += " ORCPT=rfc822;" + escapeFormat(xtextEncode(orcpt))
+	}
+	return cmdStr
 }
 
 type DataCloser struct {
 	c    *Client
 	done bool
 	io.WriteCloser
-	response string
+	response      string
+	rcptResponses []RcptResponse
 }
 
 // Close releases the lock, closes the WriteCloser, waits for a response, and then returns any error encountered.
+//
+// In LMTP mode, a response is read for each recipient that has been accepted by the server. The
+// responses are available via [DataCloser.RcptResponses].
 func (d *DataCloser) Close() error {
 	d.c.mutex.Lock()
+	defer d.c.mutex.Unlock()
 	_ = d.WriteCloser.Close()
+	d.done = true
+	if d.c.lmtp {
+		var err error
+		d.rcptResponses, err = d.c.readRcptResponses()
+		if len(d.rcptResponses) > 0 {
+			d.response = d.rcptResponses[0].Msg
+		}
+		return err
+	}
 	_, resp, err := d.c.Text.ReadResponse(250)
 	d.response = resp
-	d.done = true
-	d.c.mutex.Unlock()
 	return err
 }
 
@@ -588,12 +706,32 @@ func (c *Client) Extension(ext string) (bool, string) {
 	return ok, param
 }
 
+// MaxMessageSize returns the maximum message size in bytes that the server accepts, as
+// advertised with the SIZE extension. If the server does not support the SIZE extension or
+// does not announce a fixed maximum message size, ok is false.
+//
+// https://datatracker.ietf.org/doc/html/rfc1870
+func (c *Client) MaxMessageSize() (size int64, ok bool) {
+	supported, param := c.Extension("SIZE")
+	if !supported || param == "" {
+		return 0, false
+	}
+	size, err := strconv.ParseInt(param, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, false
+	}
+	return size, true
+}
+
 // Reset sends the RSET command to the server, aborting the current mail
 // transaction.
 func (c *Client) Reset() error {
 	if err := c.hello(); err != nil {
 		return err
 	}
+	c.mutex.Lock()
+	c.rcpts = nil
+	c.mutex.Unlock()
 	_, _, err := c.cmd(250, "RSET")
 	return err
 }
@@ -669,6 +807,40 @@ func (c *Client) SetDSNRcptNotifyOption(d string) {
 	c.mutex.Unlock()
 }
 
+// SetBinaryMIME sets the BINARYMIME option for the Mail method. If enabled and the server supports
+// the BINARYMIME and CHUNKING extensions, the "MAIL FROM" command will request BODY=BINARYMIME. The
+// message data must then be transmitted with [Client.Bdat].
+//
+// https://datatracker.ietf.org/doc/html/rfc3030#section-3
+func (c *Client) SetBinaryMIME(v bool) {
+	c.mutex.Lock()
+	c.binaryMIME = v
+	c.mutex.Unlock()
+}
+
+// SetMessageSize sets the size of the message in bytes, that is announced with the SIZE
+// parameter of the Mail method, if the server supports the SIZE extension. A size of 0
+// omits the SIZE parameter.
+//
+// https://datatracker.ietf.org/doc/html/rfc1870
+func (c *Client) SetMessageSize(size int64) {
+	c.mutex.Lock()
+	c.msgSize = size
+	c.mutex.Unlock()
+}
+
+// SetRequireTLS sets the REQUIRETLS option for the Mail method. If enabled and the server
+// supports the REQUIRETLS extension, the "MAIL FROM" command will include the REQUIRETLS
+// parameter. It is the responsibility of the caller to make sure, that the connection is
+// TLS protected.
+//
+// https://datatracker.ietf.org/doc/html/rfc8689
+func (c *Client) SetRequireTLS(v bool) {
+	c.mutex.Lock()
+	c.requireTLS = v
+	c.mutex.Unlock()
+}
+
 // HasConnection checks if the client has an active connection.
 // Returns true if the `conn` field is not nil, indicating an active connection.
 func (c *Client) HasConnection() bool {
@@ -678,6 +850,19 @@ func (c *Client) HasConnection() bool {
 	return isConn
 }
 
+// AuthMechanism returns the SASL mechanism of the successful authentication via [Client.Auth], or
+// an empty string if the Client has not been authenticated.
+func (c *Client) AuthMechanism() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.authMech
+}
+
+// ServerName returns the name of the server as provided to NewClient.
+func (c *Client) ServerName() string {
+	return c.serverName
+}
+
 // UpdateDeadline sets a new deadline on the SMTP connection with the specified timeout duration.
 func (c *Client) UpdateDeadline(timeout time.Duration) error {
 	c.mutex.Lock()
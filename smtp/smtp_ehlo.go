@@ -17,8 +17,14 @@ import "strings"
 
 // ehlo sends the EHLO (extended hello) greeting to the server. It
 // should be the preferred greeting for servers that support it.
+//
+// In LMTP mode, the LHLO greeting is sent instead.
 func (c *Client) ehlo() error {
-	_, msg, err := c.cmd(250, "EHLO %s", c.localName)
+	command := "EHLO %s"
+	if c.isLMTP() {
+		command = "LHLO %s"
+	}
+	_, msg, err := c.cmd(250, command, c.localName)
 	if err != nil {
 		return err
 	}
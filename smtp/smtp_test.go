@@ -2935,6 +2935,191 @@ func TestClient_Data(t *testing.T) {
 	})
 }
 
+func TestClient_RcptWithResponse(t *testing.T) {
+	server := strings.Join([]string{
+		"220 server ready",
+		"250-localhost",
+		"250 ENHANCEDSTATUSCODES",
+		"250 2.1.0 Sender ok",
+		"250 2.1.5 Recipient ok",
+		"550 5.1.1 Unknown user",
+		"",
+	}, "\r\n")
+	client := newPipelineTestClient(t, server, &writeCounter{})
+	if err := client.Mail("<valid-from@domain.tld>"); err != nil {
+		t.Fatalf("failed to send MAIL command: %s", err)
+	}
+	t.Run("accepted recipient returns the response", func(t *testing.T) {
+		response, err := client.RcptWithResponse("<valid-to@domain.tld>")
+		if err != nil {
+			t.Fatalf("failed to send RCPT command: %s", err)
+		}
+		if response.Rcpt != "<valid-to@domain.tld>" || response.Code != 250 || response.Msg != "2.1.5 Recipient ok" {
+			t.Errorf("unexpected response: %+v", response)
+		}
+	})
+	t.Run("rejected recipient returns the response and the error", func(t *testing.T) {
+		response, err := client.RcptWithResponse("<invalid@domain.tld>")
+		if err == nil {
+			t.Fatal("expected RCPT command to fail")
+		}
+		if response.Code != 550 || response.Msg != "5.1.1 Unknown user" || !errors.Is(response.Err, err) {
+			t.Errorf("unexpected response: %+v", response)
+		}
+	})
+	t.Run("invalid recipient address fails", func(t *testing.T) {
+		response, err := client.RcptWithResponse("<invalid\r\n@domain.tld>")
+		if err == nil {
+			t.Fatal("expected RCPT command to fail")
+		}
+		if response.Code != 0 || response.Err == nil {
+			t.Errorf("unexpected response: %+v", response)
+		}
+	})
+}
+
+func TestClient_MailRcptPipelined(t *testing.T) {
+	t.Run("pipelined transaction is sent in a single write", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 PIPELINING",
+			"250 2.1.0 Sender ok",
+			"250 2.1.5 Recipient ok",
+			"250 2.1.5 Recipient ok",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		result, err := client.MailRcptPipelined("<valid-from@domain.tld>",
+			[]string{"<valid-to@domain.tld>", "<other-to@domain.tld>"})
+		if err != nil {
+			t.Fatalf("failed to send pipelined transaction: %s", err)
+		}
+		if result.MailErr != nil || result.HasRcptErr() {
+			t.Errorf("expected no errors in pipeline result, got: %+v", result)
+		}
+		// The first write is the EHLO, the second one the pipelined batch
+		if commands.writes != 2 {
+			t.Errorf("expected 2 writes, got: %d", commands.writes)
+		}
+		expected := "EHLO localhost\r\nMAIL FROM:<valid-from@domain.tld>\r\n" +
+			"RCPT TO:<valid-to@domain.tld>\r\nRCPT TO:<other-to@domain.tld>\r\n"
+		if commands.String() != expected {
+			t.Errorf("expected commands to be %q, got: %q", expected, commands.String())
+		}
+	})
+	t.Run("rejected recipient is mapped to the correct index", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250-DSN",
+			"250 PIPELINING",
+			"250 2.1.0 Sender ok",
+			"250 2.1.5 Recipient ok",
+			"550 5.1.1 Unknown user",
+			"250 2.1.5 Recipient ok",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetDSNRcptNotifyOption("FAILURE")
+		result, err := client.MailRcptPipelined("<valid-from@domain.tld>",
+			[]string{"<valid-to@domain.tld>", "<invalid@domain.tld>", "<other-to@domain.tld>"})
+		if err != nil {
+			t.Fatalf("failed to send pipelined transaction: %s", err)
+		}
+		if !result.HasRcptErr() {
+			t.Fatal("expected recipient error in pipeline result")
+		}
+		for i, rcptErr := range result.RcptErrs {
+			if i == 1 && rcptErr == nil {
+				t.Error("expected second recipient to be rejected")
+			}
+			if i != 1 && rcptErr != nil {
+				t.Errorf("expected recipient %d to be accepted, got: %s", i, rcptErr)
+			}
+		}
+		if !strings.Contains(commands.String(), "RCPT TO:<invalid@domain.tld> NOTIFY=FAILURE\r\n") {
+			t.Errorf("expected RCPT command with NOTIFY parameter, got: %q", commands.String())
+		}
+		if len(result.RcptResponses) != 3 {
+			t.Fatalf("expected 3 recipient responses, got: %d", len(result.RcptResponses))
+		}
+		rejected := result.RcptResponses[1]
+		if rejected.Rcpt != "<invalid@domain.tld>" || rejected.Code != 550 || rejected.Err == nil {
+			t.Errorf("unexpected response for rejected recipient: %+v", rejected)
+		}
+		accepted := result.RcptResponses[2]
+		if accepted.Code != 250 || accepted.Msg != "2.1.5 Recipient ok" || accepted.Err != nil {
+			t.Errorf("unexpected response for accepted recipient: %+v", accepted)
+		}
+	})
+	t.Run("rejected sender is reported", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 PIPELINING",
+			"553 5.1.8 Sender rejected",
+			"503 5.5.1 Need MAIL command",
+			"",
+		}, "\r\n")
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		result, err := client.MailRcptPipelined("<invalid@domain.tld>", []string{"<valid-to@domain.tld>"})
+		if err != nil {
+			t.Fatalf("failed to send pipelined transaction: %s", err)
+		}
+		if result.MailErr == nil {
+			t.Error("expected MAIL error in pipeline result")
+		}
+		if !result.HasRcptErr() {
+			t.Error("expected RCPT error in pipeline result")
+		}
+	})
+	t.Run("pipelining fails if not supported by the server", func(t *testing.T) {
+		server := "220 server ready\r\n250 localhost\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		_, err := client.MailRcptPipelined("<valid-from@domain.tld>", []string{"<valid-to@domain.tld>"})
+		if !errors.Is(err, ErrPipeliningNotSupported) {
+			t.Errorf("expected error to be %s, got: %s", ErrPipeliningNotSupported, err)
+		}
+	})
+	t.Run("pipelining fails with newline in address", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 PIPELINING\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if _, err := client.MailRcptPipelined("<valid-from@domain.tld>", []string{"<valid-to@domain.tld>\r\n"}); err == nil {
+			t.Error("expected pipelining to fail with newline in recipient address")
+		}
+	})
+	t.Run("pipelining fails on dropped connection", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 PIPELINING\r\n250 2.1.0 Sender ok\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if _, err := client.MailRcptPipelined("<valid-from@domain.tld>", []string{"<valid-to@domain.tld>"}); err == nil {
+			t.Error("expected pipelining to fail on dropped connection")
+		}
+	})
+	t.Run("pipelining fails on broken writer", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 PIPELINING\r\n"
+		var fake faker
+		fake.ReadWriter = struct {
+			io.Reader
+			io.Writer
+		}{
+			strings.NewReader(server),
+			&failWriter{},
+		}
+		client, err := NewClient(fake, "faker.host")
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		client.didHello = true
+		client.ext = map[string]string{"PIPELINING": ""}
+		if _, err = client.MailRcptPipelined("<valid-from@domain.tld>", []string{"<valid-to@domain.tld>"}); err == nil {
+			t.Error("expected pipelining to fail on broken writer")
+		}
+	})
+}
+
 func TestDataCloser_ServerResponse(t *testing.T) {
 	t.Run("successful delivery returns server response", func(t *testing.T) {
 		ctx := t.Context()
@@ -3695,6 +3880,658 @@ func TestClient_SetDSNMailReturnOption(t *testing.T) {
 	}
 }
 
+func TestClient_SetDSNEnvelopeID(t *testing.T) {
+	t.Run("ENVID parameter is added if DSN is supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"DSN": ""}}
+		client.SetDSNEnvelopeID("QQ314159")
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s ENVID=QQ314159" {
+			t.Errorf("expected MAIL command with ENVID, got: %s", cmd)
+		}
+	})
+	t.Run("ENVID parameter is xtext encoded", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"DSN": ""}}
+		client.SetDSNEnvelopeID("id=1+2 100%")
+		cmd := client.mailCmd()
+		if cmd != "MAIL FROM:%s ENVID=id+3D1+2B2+20100%%" {
+			t.Errorf("expected MAIL command with encoded ENVID, got: %s", cmd)
+		}
+		if formatted := fmt.Sprintf(cmd, "<valid-from@domain.tld>"); !strings.HasSuffix(formatted, "100%") {
+			t.Errorf("expected percent sign to be preserved, got: %s", formatted)
+		}
+	})
+	t.Run("ENVID parameter is omitted if DSN is not supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{}}
+		client.SetDSNEnvelopeID("QQ314159")
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s" {
+			t.Errorf("expected MAIL command without ENVID, got: %s", cmd)
+		}
+	})
+}
+
+func TestClient_SetDSNOriginalRcpts(t *testing.T) {
+	orcpts := map[string]string{"<toni.tester@domain.tld>": "toni+alias@domain.tld"}
+	t.Run("ORCPT parameter is added if DSN is supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"DSN": ""}}
+		client.SetDSNRcptNotifyOption("FAILURE")
+		client.SetDSNOriginalRcpts(orcpts)
+		cmd := client.rcptCmd("<toni.tester@domain.tld>")
+		if cmd != "RCPT TO:%s NOTIFY=FAILURE ORCPT=rfc822;toni+2Balias@domain.tld" {
+			t.Errorf("expected RCPT command with ORCPT, got: %s", cmd)
+		}
+	})
+	t.Run("ORCPT parameter is omitted for other recipients", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"DSN": ""}}
+		client.SetDSNOriginalRcpts(orcpts)
+		if cmd := client.rcptCmd("<tina.tester@domain.tld>"); cmd != "RCPT TO:%s" {
+			t.Errorf("expected RCPT command without ORCPT, got: %s", cmd)
+		}
+	})
+	t.Run("ORCPT parameter is omitted if DSN is not supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{}}
+		client.SetDSNOriginalRcpts(orcpts)
+		if cmd := client.rcptCmd("<toni.tester@domain.tld>"); cmd != "RCPT TO:%s" {
+			t.Errorf("expected RCPT command without ORCPT, got: %s", cmd)
+		}
+	})
+	t.Run("ORCPT parameter is sent in pipelined mail transactions", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250-DSN",
+			"250 PIPELINING",
+			"250 2.1.0 Sender ok",
+			"250 2.1.5 Recipient ok",
+			"250 2.1.5 Recipient ok",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetDSNOriginalRcpts(orcpts)
+		_, err := client.MailRcptPipelined("<valid-from@domain.tld>",
+			[]string{"<toni.tester@domain.tld>", "<tina.tester@domain.tld>"})
+		if err != nil {
+			t.Fatalf("failed to send pipelined mail transaction: %s", err)
+		}
+		want := "RCPT TO:<toni.tester@domain.tld> ORCPT=rfc822;toni+2Balias@domain.tld\r\n" +
+			"RCPT TO:<tina.tester@domain.tld>\r\n"
+		if !strings.Contains(commands.String(), want) {
+			t.Errorf("expected ORCPT for first recipient only, got: %q", commands.String())
+		}
+	})
+}
+
+func TestXtextEncode(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"toni.tester@domain.tld", "toni.tester@domain.tld"},
+		{"a+b=c", "a+2Bb+3Dc"},
+		{"with space", "with+20space"},
+		{"t\u00e4st", "t+C3+A4st"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := xtextEncode(tt.value); got != tt.want {
+				t.Errorf("expected %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_XClient(t *testing.T) {
+	t.Run("XCLIENT is sent and followed by a new EHLO", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250-AUTH PLAIN",
+			"250 XCLIENT NAME ADDR HELO LOGIN",
+			"220 server ready",
+			"250-localhost",
+			"250 8BITMIME",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		err := client.XClient(map[string]string{
+			"ADDR":  "192.0.2.1",
+			"HELO":  "client.example.com",
+			"LOGIN": "toni+tester=1",
+		})
+		if err != nil {
+			t.Fatalf("failed to send XCLIENT command: %s", err)
+		}
+		expected := "EHLO localhost\r\n" +
+			"XCLIENT ADDR=192.0.2.1 HELO=client.example.com LOGIN=toni+2Btester+3D1\r\n" +
+			"EHLO client.example.com\r\n"
+		if commands.String() != expected {
+			t.Errorf("unexpected commands, want: %q, got: %q", expected, commands.String())
+		}
+		if ok, _ := client.Extension("8BITMIME"); !ok {
+			t.Error("expected extensions of the new EHLO response")
+		}
+		if ok, _ := client.Extension("XCLIENT"); ok {
+			t.Error("expected extensions of the first EHLO response to be replaced")
+		}
+		if ok, _ := client.Extension("AUTH"); ok || len(client.auth) != 0 {
+			t.Error("expected auth mechanisms of the first EHLO response to be replaced")
+		}
+	})
+	t.Run("special HELO value keeps the local name", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 XCLIENT NAME HELO",
+			"220 server ready",
+			"250 localhost",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		if err := client.XClient(map[string]string{"HELO": "[UNAVAILABLE]"}); err != nil {
+			t.Fatalf("failed to send XCLIENT command: %s", err)
+		}
+		if !strings.HasSuffix(commands.String(), "XCLIENT HELO=[UNAVAILABLE]\r\nEHLO localhost\r\n") {
+			t.Errorf("unexpected commands: %q", commands.String())
+		}
+	})
+	t.Run("XCLIENT fails if not supported by the server", func(t *testing.T) {
+		server := "220 server ready\r\n250 localhost\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		err := client.XClient(map[string]string{"ADDR": "192.0.2.1"})
+		if !errors.Is(err, ErrXClientNotSupported) {
+			t.Errorf("expected ErrXClientNotSupported, got: %s", err)
+		}
+	})
+	t.Run("XCLIENT fails without attributes", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 XCLIENT ADDR\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if err := client.XClient(nil); !errors.Is(err, ErrNoXAttributes) {
+			t.Errorf("expected ErrNoXAttributes, got: %s", err)
+		}
+	})
+	t.Run("XCLIENT fails on invalid attribute name", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 XCLIENT ADDR\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if err := client.XClient(map[string]string{"ADDR\r\nRSET": "192.0.2.1"}); err == nil {
+			t.Error("expected XCLIENT command with invalid attribute name to fail")
+		}
+	})
+	t.Run("XCLIENT fails on rejection by the server", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 XCLIENT ADDR",
+			"550 5.7.0 Error: insufficient authorization",
+			"",
+		}, "\r\n")
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if err := client.XClient(map[string]string{"ADDR": "192.0.2.1"}); err == nil {
+			t.Error("expected rejected XCLIENT command to fail")
+		}
+	})
+}
+
+func TestClient_XForward(t *testing.T) {
+	t.Run("XFORWARD is sent", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 XFORWARD NAME ADDR PROTO HELO SOURCE",
+			"250 2.0.0 Ok",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		err := client.XForward(map[string]string{
+			"NAME":  "client.example.com",
+			"ADDR":  "IPV6:2001:db8::1",
+			"PROTO": "ESMTP",
+		})
+		if err != nil {
+			t.Fatalf("failed to send XFORWARD command: %s", err)
+		}
+		expected := "EHLO localhost\r\n" +
+			"XFORWARD ADDR=IPV6:2001:db8::1 NAME=client.example.com PROTO=ESMTP\r\n"
+		if commands.String() != expected {
+			t.Errorf("unexpected commands, want: %q, got: %q", expected, commands.String())
+		}
+	})
+	t.Run("XFORWARD fails if not supported by the server", func(t *testing.T) {
+		server := "220 server ready\r\n250 localhost\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		err := client.XForward(map[string]string{"ADDR": "192.0.2.1"})
+		if !errors.Is(err, ErrXForwardNotSupported) {
+			t.Errorf("expected ErrXForwardNotSupported, got: %s", err)
+		}
+	})
+	t.Run("XFORWARD fails on rejection by the server", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 XFORWARD ADDR",
+			"550 5.7.0 Error: insufficient authorization",
+			"",
+		}, "\r\n")
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if err := client.XForward(map[string]string{"ADDR": "192.0.2.1"}); err == nil {
+			t.Error("expected rejected XFORWARD command to fail")
+		}
+	})
+}
+
+func TestClient_SetBinaryMIME(t *testing.T) {
+	t.Run("BODY=BINARYMIME is requested if supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"8BITMIME": "", "BINARYMIME": "", "CHUNKING": ""}}
+		client.SetBinaryMIME(true)
+		if !client.binaryMIME {
+			t.Error("expected binaryMIME to be set")
+		}
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s BODY=BINARYMIME" {
+			t.Errorf("expected MAIL command with BODY=BINARYMIME, got: %s", cmd)
+		}
+	})
+	t.Run("BODY=8BITMIME is requested without CHUNKING", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"8BITMIME": "", "BINARYMIME": ""}}
+		client.SetBinaryMIME(true)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s BODY=8BITMIME" {
+			t.Errorf("expected MAIL command with BODY=8BITMIME, got: %s", cmd)
+		}
+	})
+	t.Run("BODY=8BITMIME is requested if BINARYMIME is disabled", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"8BITMIME": "", "BINARYMIME": "", "CHUNKING": ""}}
+		client.SetBinaryMIME(false)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s BODY=8BITMIME" {
+			t.Errorf("expected MAIL command with BODY=8BITMIME, got: %s", cmd)
+		}
+	})
+}
+
+func TestClient_SetMessageSize(t *testing.T) {
+	t.Run("SIZE parameter is added if supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"SIZE": "10485760"}}
+		client.SetMessageSize(1234)
+		if client.msgSize != 1234 {
+			t.Errorf("expected message size to be 1234, got: %d", client.msgSize)
+		}
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s SIZE=1234" {
+			t.Errorf("expected MAIL command with SIZE=1234, got: %s", cmd)
+		}
+	})
+	t.Run("SIZE parameter is omitted if not supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{}}
+		client.SetMessageSize(1234)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s" {
+			t.Errorf("expected MAIL command without SIZE, got: %s", cmd)
+		}
+	})
+	t.Run("SIZE parameter is omitted for zero size", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"SIZE": "10485760"}}
+		client.SetMessageSize(0)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s" {
+			t.Errorf("expected MAIL command without SIZE, got: %s", cmd)
+		}
+	})
+}
+
+func TestClient_SetLMTP(t *testing.T) {
+	t.Run("LHLO is sent and per-recipient responses are read", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 ENHANCEDSTATUSCODES",
+			"250 2.1.0 Sender ok",
+			"250 2.1.5 Recipient ok",
+			"550 5.1.1 Unknown user",
+			"250 2.1.5 Recipient ok",
+			"354 End data with <CR><LF>.<CR><LF>",
+			"250 2.0.0 <valid-to@domain.tld> delivered",
+			"452 4.2.2 <other-to@domain.tld> mailbox full",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetLMTP(true)
+		if err := client.Mail("<valid-from@domain.tld>"); err != nil {
+			t.Fatalf("failed to send MAIL FROM: %s", err)
+		}
+		if err := client.Rcpt("<valid-to@domain.tld>"); err != nil {
+			t.Fatalf("failed to send RCPT TO: %s", err)
+		}
+		if err := client.Rcpt("<invalid@domain.tld>"); err == nil {
+			t.Fatal("expected RCPT TO to fail for invalid recipient")
+		}
+		if err := client.Rcpt("<other-to@domain.tld>"); err != nil {
+			t.Fatalf("failed to send RCPT TO: %s", err)
+		}
+		writer, err := client.Data()
+		if err != nil {
+			t.Fatalf("failed to send DATA: %s", err)
+		}
+		if _, err = writer.Write([]byte("test message")); err != nil {
+			t.Fatalf("failed to write message data: %s", err)
+		}
+		err = writer.Close()
+		if err == nil {
+			t.Fatal("expected close to fail for rejected recipient")
+		}
+		if !strings.Contains(err.Error(), "<other-to@domain.tld>: 452") {
+			t.Errorf("expected error to contain the rejected recipient, got: %s", err)
+		}
+		if !strings.HasPrefix(commands.String(), "LHLO localhost\r\n") {
+			t.Errorf("expected LHLO greeting, got: %q", commands.String())
+		}
+		dataCloser, ok := writer.(*DataCloser)
+		if !ok {
+			t.Fatalf("expected writer to be a DataCloser, got: %T", writer)
+		}
+		responses := dataCloser.RcptResponses()
+		if len(responses) != 2 {
+			t.Fatalf("expected 2 recipient responses, got: %d", len(responses))
+		}
+		if responses[0].Rcpt != "<valid-to@domain.tld>" || responses[0].Code != 250 || responses[0].Err != nil {
+			t.Errorf("unexpected response for first recipient: %+v", responses[0])
+		}
+		if responses[1].Rcpt != "<other-to@domain.tld>" || responses[1].Code != 452 || responses[1].Err == nil {
+			t.Errorf("unexpected response for second recipient: %+v", responses[1])
+		}
+		if dataCloser.ServerResponse() != "2.0.0 <valid-to@domain.tld> delivered" {
+			t.Errorf("unexpected server response: %s", dataCloser.ServerResponse())
+		}
+	})
+	t.Run("LHLO failure does not fall back to HELO", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"500 5.5.1 Unknown command",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetLMTP(true)
+		if err := client.Hello("localhost"); err == nil {
+			t.Fatal("expected LHLO to fail")
+		}
+		if strings.Contains(commands.String(), "HELO") {
+			t.Errorf("expected no HELO fallback, got: %q", commands.String())
+		}
+	})
+	t.Run("pipelined transaction reads per-recipient responses", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 PIPELINING",
+			"250 2.1.0 Sender ok",
+			"250 2.1.5 Recipient ok",
+			"250 2.1.5 Recipient ok",
+			"354 End data with <CR><LF>.<CR><LF>",
+			"250 2.0.0 delivered",
+			"250 2.0.0 delivered",
+			"",
+		}, "\r\n")
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetLMTP(true)
+		result, err := client.MailRcptPipelined("<valid-from@domain.tld>",
+			[]string{"<valid-to@domain.tld>", "<other-to@domain.tld>"})
+		if err != nil {
+			t.Fatalf("failed to send pipelined transaction: %s", err)
+		}
+		if result.MailErr != nil || result.HasRcptErr() {
+			t.Fatalf("expected no errors in pipeline result, got: %+v", result)
+		}
+		writer, err := client.Data()
+		if err != nil {
+			t.Fatalf("failed to send DATA command: %s", err)
+		}
+		if _, err = writer.Write([]byte("test message")); err != nil {
+			t.Fatalf("failed to write message data: %s", err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Fatalf("failed to close data writer: %s", err)
+		}
+		if responses := writer.(*DataCloser).RcptResponses(); len(responses) != 2 {
+			t.Errorf("expected 2 recipient responses, got: %d", len(responses))
+		}
+	})
+	t.Run("no recipient responses without LMTP", func(t *testing.T) {
+		dataCloser := &DataCloser{done: true}
+		if responses := dataCloser.RcptResponses(); responses != nil {
+			t.Errorf("expected no recipient responses, got: %+v", responses)
+		}
+	})
+}
+
+func TestClient_SetRequireTLS(t *testing.T) {
+	t.Run("REQUIRETLS parameter is added if supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"REQUIRETLS": ""}}
+		client.SetRequireTLS(true)
+		if !client.requireTLS {
+			t.Error("expected requireTLS to be set")
+		}
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s REQUIRETLS" {
+			t.Errorf("expected MAIL command with REQUIRETLS, got: %s", cmd)
+		}
+	})
+	t.Run("REQUIRETLS parameter is omitted if not supported", func(t *testing.T) {
+		client := &Client{ext: map[string]string{}}
+		client.SetRequireTLS(true)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s" {
+			t.Errorf("expected MAIL command without REQUIRETLS, got: %s", cmd)
+		}
+	})
+	t.Run("REQUIRETLS parameter is omitted if not requested", func(t *testing.T) {
+		client := &Client{ext: map[string]string{"REQUIRETLS": ""}}
+		client.SetRequireTLS(false)
+		if cmd := client.mailCmd(); cmd != "MAIL FROM:%s" {
+			t.Errorf("expected MAIL command without REQUIRETLS, got: %s", cmd)
+		}
+	})
+}
+
+func TestClient_MaxMessageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		features string
+		size     int64
+		ok       bool
+	}{
+		{"SIZE with limit", "250-localhost\r\n250 SIZE 10485760\r\n", 10485760, true},
+		{"SIZE without limit", "250-localhost\r\n250 SIZE\r\n", 0, false},
+		{"SIZE with zero limit", "250-localhost\r\n250 SIZE 0\r\n", 0, false},
+		{"SIZE with invalid limit", "250-localhost\r\n250 SIZE unlimited\r\n", 0, false},
+		{"SIZE not supported", "250 localhost\r\n", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newPipelineTestClient(t, "220 server ready\r\n"+tt.features, &writeCounter{})
+			size, ok := client.MaxMessageSize()
+			if ok != tt.ok {
+				t.Errorf("expected ok to be %t, got: %t", tt.ok, ok)
+			}
+			if size != tt.size {
+				t.Errorf("expected size to be %d, got: %d", tt.size, size)
+			}
+		})
+	}
+}
+
+func TestClient_Bdat(t *testing.T) {
+	t.Run("message is transmitted with a single BDAT LAST", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n250 2.0.0 Ok: queued\r\n"
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if _, err = writer.Write([]byte("test\x00message")); err != nil {
+			t.Errorf("failed to write message data: %s", err)
+		}
+		if writer.ServerResponse() != "" {
+			t.Errorf("expected empty server response before close, got: %s", writer.ServerResponse())
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		expected := "EHLO localhost\r\nBDAT 12 LAST\r\ntest\x00message"
+		if commands.String() != expected {
+			t.Errorf("expected commands to be %q, got: %q", expected, commands.String())
+		}
+		if writer.ServerResponse() != "2.0.0 Ok: queued" {
+			t.Errorf("expected server response to be %q, got: %q", "2.0.0 Ok: queued", writer.ServerResponse())
+		}
+	})
+	t.Run("large message is transmitted in multiple chunks", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n250 2.0.0 OK\r\n" +
+			"250 2.0.0 OK\r\n250 2.0.0 Ok: queued\r\n"
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		data := bytes.Repeat([]byte("a"), DefaultChunkSize*2+10)
+		n, err := writer.Write(data)
+		if err != nil {
+			t.Errorf("failed to write message data: %s", err)
+		}
+		if n != len(data) {
+			t.Errorf("expected %d bytes to be written, got: %d", len(data), n)
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		output := commands.String()
+		if count := strings.Count(output, fmt.Sprintf("BDAT %d\r\n", DefaultChunkSize)); count != 2 {
+			t.Errorf("expected 2 full chunks, got: %d", count)
+		}
+		if !strings.Contains(output, "BDAT 10 LAST\r\n") {
+			t.Error("expected last chunk with remaining data")
+		}
+	})
+	t.Run("bare LF line endings are converted to CRLF", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n250 2.0.0 Ok: queued\r\n"
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		for _, data := range []string{"line one\n", "line two\r", "\nline three\r\n", "\n"} {
+			n, err := writer.Write([]byte(data))
+			if err != nil {
+				t.Errorf("failed to write message data: %s", err)
+			}
+			if n != len(data) {
+				t.Errorf("expected %d bytes to be written, got: %d", len(data), n)
+			}
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		expected := "EHLO localhost\r\nBDAT 34 LAST\r\nline one\r\nline two\r\nline three\r\n\r\n"
+		if commands.String() != expected {
+			t.Errorf("expected commands to be %q, got: %q", expected, commands.String())
+		}
+	})
+	t.Run("line endings are not converted with BINARYMIME", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250-CHUNKING\r\n250 BINARYMIME\r\n" +
+			"250 2.0.0 Ok: queued\r\n"
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		client.SetBinaryMIME(true)
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if _, err = writer.Write([]byte("binary\ndata\n")); err != nil {
+			t.Errorf("failed to write message data: %s", err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		expected := "EHLO localhost\r\nBDAT 12 LAST\r\nbinary\ndata\n"
+		if commands.String() != expected {
+			t.Errorf("expected commands to be %q, got: %q", expected, commands.String())
+		}
+	})
+	t.Run("empty message sends BDAT 0 LAST", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n250 2.0.0 Ok: queued\r\n"
+		commands := &writeCounter{}
+		client := newPipelineTestClient(t, server, commands)
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		if !strings.HasSuffix(commands.String(), "BDAT 0 LAST\r\n") {
+			t.Errorf("expected BDAT 0 LAST, got: %q", commands.String())
+		}
+	})
+	t.Run("write and close fail after close", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n250 2.0.0 Ok: queued\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if err = writer.Close(); err != nil {
+			t.Errorf("failed to close chunk writer: %s", err)
+		}
+		if _, err = writer.Write([]byte("test")); !errors.Is(err, ErrChunkWriterClosed) {
+			t.Errorf("expected error to be %s, got: %s", ErrChunkWriterClosed, err)
+		}
+		if err = writer.Close(); !errors.Is(err, ErrChunkWriterClosed) {
+			t.Errorf("expected error to be %s, got: %s", ErrChunkWriterClosed, err)
+		}
+	})
+	t.Run("rejected chunk returns error", func(t *testing.T) {
+		server := "220 server ready\r\n250-localhost\r\n250 CHUNKING\r\n552 5.3.4 Message too big\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if err = writer.Close(); err == nil {
+			t.Error("expected close to fail on rejected chunk")
+		}
+	})
+	t.Run("BDAT fails if not supported by the server", func(t *testing.T) {
+		server := "220 server ready\r\n250 localhost\r\n"
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if _, err := client.Bdat(); !errors.Is(err, ErrChunkingNotSupported) {
+			t.Errorf("expected error to be %s, got: %s", ErrChunkingNotSupported, err)
+		}
+	})
+	t.Run("BDAT fails on broken writer", func(t *testing.T) {
+		server := "220 server ready\r\n"
+		var fake faker
+		fake.ReadWriter = struct {
+			io.Reader
+			io.Writer
+		}{
+			strings.NewReader(server),
+			&failWriter{},
+		}
+		client, err := NewClient(fake, "faker.host")
+		if err != nil {
+			t.Fatalf("failed to create client: %s", err)
+		}
+		client.didHello = true
+		client.ext = map[string]string{"CHUNKING": ""}
+		writer, err := client.Bdat()
+		if err != nil {
+			t.Fatalf("failed to create chunk writer: %s", err)
+		}
+		if err = writer.Close(); err == nil {
+			t.Error("expected close to fail on broken writer")
+		}
+	})
+}
+
 func TestClient_HasConnection(t *testing.T) {
 	t.Run("client has connection", func(t *testing.T) {
 		ctx := t.Context()
@@ -3790,6 +4627,74 @@ func TestClient_HasConnection(t *testing.T) {
 	})
 }
 
+func TestClient_AuthMechanism(t *testing.T) {
+	t.Run("mechanism of successful authentication is returned", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 AUTH PLAIN",
+			"235 2.7.0 Authentication successful",
+			"",
+		}, "\r\n")
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if mech := client.AuthMechanism(); mech != "" {
+			t.Errorf("expected no auth mechanism before authentication, got: %s", mech)
+		}
+		if err := client.Auth(PlainAuth("", "user", "pass", "faker.host", true)); err != nil {
+			t.Fatalf("failed to authenticate: %s", err)
+		}
+		if mech := client.AuthMechanism(); mech != "PLAIN" {
+			t.Errorf("expected auth mechanism PLAIN, got: %s", mech)
+		}
+	})
+	t.Run("mechanism of failed authentication is not returned", func(t *testing.T) {
+		server := strings.Join([]string{
+			"220 server ready",
+			"250-localhost",
+			"250 AUTH PLAIN",
+			"535 5.7.8 Authentication failed",
+			"221 Bye",
+			"",
+		}, "\r\n")
+		client := newPipelineTestClient(t, server, &writeCounter{})
+		if err := client.Auth(PlainAuth("", "user", "pass", "faker.host", true)); err == nil {
+			t.Fatal("expected authentication to fail")
+		}
+		if mech := client.AuthMechanism(); mech != "" {
+			t.Errorf("expected no auth mechanism, got: %s", mech)
+		}
+	})
+}
+
+func TestClient_ServerName(t *testing.T) {
+	ctx := t.Context()
+	PortAdder.Add(1)
+	serverPort := int(TestServerPortBase + PortAdder.Load())
+	featureSet := "250-DSN\r\n250 STARTTLS"
+	go func() {
+		if err := simpleSMTPServer(ctx, t, &serverProps{
+			FeatureSet: featureSet,
+			ListenPort: serverPort,
+		}); err != nil {
+			t.Errorf("failed to start test server: %s", err)
+			return
+		}
+	}()
+	time.Sleep(time.Millisecond * 30)
+	client, err := Dial(fmt.Sprintf("%s:%d", TestServerAddr, serverPort))
+	if err != nil {
+		t.Fatalf("failed to dial to test server: %s", err)
+	}
+	t.Cleanup(func() {
+		if err = client.Close(); err != nil {
+			t.Errorf("failed to close client: %s", err)
+		}
+	})
+	if client.ServerName() != TestServerAddr {
+		t.Errorf("expected server name to be %s, got: %s", TestServerAddr, client.ServerName())
+	}
+}
+
 func TestClient_UpdateDeadline(t *testing.T) {
 	t.Run("update deadline on sane client succeeds", func(t *testing.T) {
 		ctx := t.Context()
@@ -4756,6 +5661,36 @@ func (toServerEmptyAuth) Next(_ []byte, _ bool) (toServer []byte, err error) {
 }
 
 // failWriter is a struct type that implements the io.Writer interface, but always returns an error on Write.
+// writeCounter is an io.Writer that records the written data and counts the number of writes.
+type writeCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+// newPipelineTestClient returns a Client on a faked connection, that replies with the given
+// server responses and writes the client commands to the given writer.
+func newPipelineTestClient(t *testing.T, server string, commands io.Writer) *Client {
+	t.Helper()
+	var fake faker
+	fake.ReadWriter = struct {
+		io.Reader
+		io.Writer
+	}{
+		strings.NewReader(server),
+		commands,
+	}
+	client, err := NewClient(fake, "faker.host")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	return client
+}
+
 type failWriter struct{}
 
 func (w *failWriter) Write([]byte) (int, error) {
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: Copyright (c) The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrXClientNotSupported is returned when an XCLIENT command is requested but the server does
+	// not advertise the XCLIENT extension.
+	ErrXClientNotSupported = errors.New("smtp: server does not support XCLIENT")
+
+	// ErrXForwardNotSupported is returned when an XFORWARD command is requested but the server does
+	// not advertise the XFORWARD extension.
+	ErrXForwardNotSupported = errors.New("smtp: server does not support XFORWARD")
+
+	// ErrNoXAttributes is returned when an XCLIENT or XFORWARD command is requested without any
+	// attributes.
+	ErrNoXAttributes = errors.New("smtp: no attributes given")
+)
+
+// XClient sends the Postfix XCLIENT command with the given attributes to the server. XCLIENT
+// allows an authorized SMTP proxy to override the client information of the SMTP session, like
+// the client address (ADDR), its reverse DNS name (NAME), the HELO name (HELO), the protocol
+// (PROTO) or the SASL login name (LOGIN). The attribute values are xtext encoded. Only servers
+// that advertise the XCLIENT extension support this function. The attribute names supported by
+// the server are listed in the EHLO response and can be retrieved via [Client.Extension].
+//
+// On success, the server resets the SMTP session and greets the client again, so XClient sends
+// a new EHLO. If the HELO attribute holds a host name, it is used for the new EHLO, otherwise
+// the local name of the Client is used.
+//
+// https://www.postfix.org/XCLIENT_README.html
+func (c *Client) XClient(attrs map[string]string) error {
+	if err := c.hello(); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("XCLIENT"); !ok {
+		return ErrXClientNotSupported
+	}
+	command, err := xattrCmd("XCLIENT", attrs)
+	if err != nil {
+		return err
+	}
+	if _, _, err = c.cmd(220, "%s", command); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	if helo, ok := attrs["HELO"]; ok && helo != "" && !strings.HasPrefix(helo, "[") &&
+		validateLine(helo) == nil {
+		c.localName = helo
+	}
+	c.auth = nil
+	c.mutex.Unlock()
+
+	return c.ehlo()
+}
+
+// XForward sends the Postfix XFORWARD command with the given attributes to the server. XFORWARD
+// allows an authorized SMTP client to forward the information of the original client, like its
+// address (ADDR), its reverse DNS name (NAME), the HELO name (HELO), the protocol (PROTO) or the
+// message origin (SOURCE), for logging purposes. The attribute values are xtext encoded. Unlike
+// XCLIENT, the forwarded information only applies to the current mail transaction, so XForward
+// must be called before [Client.Mail] for each message. Only servers that advertise the XFORWARD
+// extension support this function.
+//
+// https://www.postfix.org/XFORWARD_README.html
+func (c *Client) XForward(attrs map[string]string) error {
+	if err := c.hello(); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("XFORWARD"); !ok {
+		return ErrXForwardNotSupported
+	}
+	command, err := xattrCmd("XFORWARD", attrs)
+	if err != nil {
+		return err
+	}
+	_, _, err = c.cmd(250, "%s", command)
+	return err
+}
+
+// xattrCmd returns the given XCLIENT or XFORWARD command verb followed by the given attributes
+// in "NAME=value" form, sorted by the attribute name. The attribute values are xtext encoded.
+func xattrCmd(verb string, attrs map[string]string) (string, error) {
+	if len(attrs) == 0 {
+		return "", ErrNoXAttributes
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		if name == "" || strings.ContainsAny(name, " =\r\n") {
+			return "", fmt.Errorf("smtp: invalid %s attribute name: %q", verb, name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var builder strings.Builder
+	builder.WriteString(verb)
+	for _, name := range names {
+		builder.WriteByte(' ')
+		builder.WriteString(strings.ToUpper(name))
+		builder.WriteByte('=')
+		builder.WriteString(xtextEncode(attrs[name]))
+	}
+	return builder.String(), nil
+}
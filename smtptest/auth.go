@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtptest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/secure/precis"
+)
+
+// scramIterations is the iteration count the Server uses for the SCRAM mechanisms.
+const scramIterations = 4096
+
+var (
+	// errAuthCancelled is returned when the client cancels the SMTP authentication exchange.
+	errAuthCancelled = errors.New("authentication cancelled")
+
+	// errAuthInvalid is returned when the client sends invalid credentials or an invalid response.
+	errAuthInvalid = errors.New("authentication credentials invalid")
+)
+
+// saslMechanism is a function that performs the server side of an SMTP authentication mechanism
+// for the given session and initial response. It returns the name of the authenticated user.
+type saslMechanism func(s *session, initial []byte) (string, error)
+
+// saslMechanisms holds the SMTP authentication mechanisms supported by the Server.
+var saslMechanisms = map[string]saslMechanism{
+	"PLAIN":              authPlain,
+	"LOGIN":              authLogin,
+	"SCRAM-SHA-1":        authScram(sha1.New, false),
+	"SCRAM-SHA-1-PLUS":   authScram(sha1.New, true),
+	"SCRAM-SHA-256":      authScram(sha256.New, false),
+	"SCRAM-SHA-256-PLUS": authScram(sha256.New, true),
+}
+
+// handleAuth processes the AUTH command and performs the SMTP authentication exchange.
+func (s *session) handleAuth(arg, reply string) error {
+	switch {
+	case len(s.server.auth) == 0:
+		return s.write("502 5.5.1 Error: authentication not enabled")
+	case s.helo == "":
+		return s.write("503 5.5.1 Error: send HELO/EHLO first")
+	case s.authUser != "":
+		return s.write("503 5.5.1 Error: already authenticated")
+	case s.envelope != nil:
+		return s.write("503 5.5.1 Error: MAIL transaction in progress")
+	}
+
+	name, initial64, hasInitial := strings.Cut(arg, " ")
+	name = strings.ToUpper(name)
+	mechanism, ok := saslMechanisms[name]
+	if !ok || !containsFold(s.authMechanisms(), name) {
+		return s.write("504 5.5.4 Unrecognized authentication type")
+	}
+	var initial []byte
+	if hasInitial && initial64 != "=" {
+		var err error
+		if initial, err = base64.StdEncoding.DecodeString(initial64); err != nil {
+			return s.write("501 5.5.2 Cannot decode response")
+		}
+	}
+
+	username, err := mechanism(s, initial)
+	switch {
+	case errors.Is(err, errConnClosed):
+		return err
+	case errors.Is(err, errAuthCancelled):
+		return s.write("501 5.7.0 Authentication cancelled")
+	case err != nil:
+		return s.write("535 5.7.8 Error: authentication failed")
+	}
+	s.authUser, s.authMech = username, name
+	return s.write(replyOr(reply, "235 2.7.0 Authentication successful"))
+}
+
+// challenge sends the given challenge to the client and returns the decoded response.
+func (s *session) challenge(data []byte) ([]byte, error) {
+	if err := s.write("334 " + base64.StdEncoding.EncodeToString(data)); err != nil {
+		return nil, errConnClosed
+	}
+	line, err := s.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "*" {
+		return nil, errAuthCancelled
+	}
+	response, err := base64.StdEncoding.DecodeString(line)
+	if err != nil {
+		return nil, errAuthInvalid
+	}
+	return response, nil
+}
+
+// checkPassword returns true if the given credentials match a user of the Server.
+func (s *session) checkPassword(username, password string) bool {
+	expected, ok := s.server.auth[username]
+	return ok && subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+}
+
+// authPlain implements the PLAIN mechanism.
+//
+// https://datatracker.ietf.org/doc/html/rfc4616
+func authPlain(s *session, initial []byte) (string, error) {
+	response := initial
+	if response == nil {
+		var err error
+		if response, err = s.challenge(nil); err != nil {
+			return "", err
+		}
+	}
+	parts := bytes.Split(response, []byte{0})
+	if len(parts) != 3 {
+		return "", errAuthInvalid
+	}
+	username, password := string(parts[1]), string(parts[2])
+	if !s.checkPassword(username, password) {
+		return "", errAuthInvalid
+	}
+	return username, nil
+}
+
+// authLogin implements the LOGIN mechanism.
+//
+// https://datatracker.ietf.org/doc/html/draft-murchison-sasl-login-00
+func authLogin(s *session, initial []byte) (string, error) {
+	username := initial
+	if username == nil {
+		var err error
+		if username, err = s.challenge([]byte("Username:")); err != nil {
+			return "", err
+		}
+	}
+	password, err := s.challenge([]byte("Password:"))
+	if err != nil {
+		return "", err
+	}
+	if !s.checkPassword(string(username), string(password)) {
+		return "", errAuthInvalid
+	}
+	return string(username), nil
+}
+
+// authScram returns the implementation of a SCRAM mechanism with the given hash function. If plus
+// is true, the client must use channel binding.
+//
+// https://datatracker.ietf.org/doc/html/rfc5802
+func authScram(hashFunc func() hash.Hash, plus bool) saslMechanism {
+	return func(s *session, initial []byte) (string, error) {
+		clientFirst := initial
+		if len(clientFirst) == 0 {
+			var err error
+			if clientFirst, err = s.challenge(nil); err != nil {
+				return "", err
+			}
+		}
+
+		// client-first-message = gs2-header client-first-message-bare
+		fields := strings.SplitN(string(clientFirst), ",", 3)
+		if len(fields) != 3 {
+			return "", errAuthInvalid
+		}
+		gs2Header, clientFirstBare := fields[0]+","+fields[1]+",", fields[2]
+		bindType, isPlus := strings.CutPrefix(fields[0], "p=")
+		if isPlus != plus {
+			return "", errAuthInvalid
+		}
+		bindData, err := s.channelBinding(bindType, plus)
+		if err != nil {
+			return "", err
+		}
+		attrs := scramAttributes(clientFirstBare)
+		username := strings.NewReplacer("=2C", ",", "=3D", "=").Replace(attrs["n"])
+		clientNonce := attrs["r"]
+		password, ok := s.server.auth[username]
+		if !ok || clientNonce == "" {
+			return "", errAuthInvalid
+		}
+
+		salt := make([]byte, 16)
+		nonce := make([]byte, 18)
+		_, _ = rand.Read(salt)
+		_, _ = rand.Read(nonce)
+		combinedNonce := clientNonce + base64.StdEncoding.EncodeToString(nonce)
+		serverFirst := "r=" + combinedNonce + ",s=" + base64.StdEncoding.EncodeToString(salt) +
+			",i=" + strconv.Itoa(scramIterations)
+		clientFinal, err := s.challenge([]byte(serverFirst))
+		if err != nil {
+			return "", err
+		}
+
+		// client-final-message = client-final-message-without-proof ",p=" proof
+		withoutProof, proof64, ok := strings.Cut(string(clientFinal), ",p=")
+		if !ok {
+			return "", errAuthInvalid
+		}
+		attrs = scramAttributes(withoutProof)
+		expectedBinding := base64.StdEncoding.EncodeToString(append([]byte(gs2Header), bindData...))
+		if attrs["c"] != expectedBinding || attrs["r"] != combinedNonce {
+			return "", errAuthInvalid
+		}
+		proof, err := base64.StdEncoding.DecodeString(proof64)
+		if err != nil {
+			return "", errAuthInvalid
+		}
+
+		password, err = precis.OpaqueString.String(password)
+		if err != nil {
+			return "", errAuthInvalid
+		}
+		saltedPassword, err := pbkdf2.Key(hashFunc, password, salt, scramIterations, hashFunc().Size())
+		if err != nil {
+			return "", errAuthInvalid
+		}
+		authMessage := []byte(clientFirstBare + "," + serverFirst + "," + withoutProof)
+		clientKey := scramHMAC(hashFunc, saltedPassword, []byte("Client Key"))
+		storedKey := hashFunc()
+		storedKey.Write(clientKey)
+		clientSignature := scramHMAC(hashFunc, storedKey.Sum(nil), authMessage)
+		if len(proof) != len(clientSignature) {
+			return "", errAuthInvalid
+		}
+		for i := range proof {
+			proof[i] ^= clientSignature[i]
+		}
+		if !hmac.Equal(proof, clientKey) {
+			return "", errAuthInvalid
+		}
+
+		serverKey := scramHMAC(hashFunc, saltedPassword, []byte("Server Key"))
+		serverSignature := scramHMAC(hashFunc, serverKey, authMessage)
+		if _, err = s.challenge([]byte("v=" + base64.StdEncoding.EncodeToString(serverSignature))); err != nil {
+			return "", err
+		}
+		return username, nil
+	}
+}
+
+// channelBinding returns the channel binding data of the given type for the TLS connection of
+// the session. If plus is false, no channel binding data is returned.
+func (s *session) channelBinding(bindType string, plus bool) ([]byte, error) {
+	if !plus {
+		return nil, nil
+	}
+	tlsConn, ok := s.conn.(*tls.Conn)
+	if !ok {
+		return nil, errAuthInvalid
+	}
+	state := tlsConn.ConnectionState()
+	switch bindType {
+	case "tls-unique":
+		if state.TLSUnique == nil {
+			return nil, errAuthInvalid
+		}
+		return state.TLSUnique, nil
+	case "tls-exporter":
+		data, err := state.ExportKeyingMaterial("EXPORTER-Channel-Binding", []byte{}, 32)
+		if err != nil {
+			return nil, errAuthInvalid
+		}
+		return data, nil
+	}
+	return nil, errAuthInvalid
+}
+
+// scramAttributes parses the comma separated attributes of a SCRAM message.
+func scramAttributes(message string) map[string]string {
+	attrs := make(map[string]string)
+	for _, field := range strings.Split(message, ",") {
+		name, value, ok := strings.Cut(field, "=")
+		if ok {
+			attrs[name] = value
+		}
+	}
+	return attrs
+}
+
+// scramHMAC returns the HMAC of the given message with the given key.
+func scramHMAC(hashFunc func() hash.Hash, key, message []byte) []byte {
+	mac := hmac.New(hashFunc, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// containsFold returns true if the given list contains the given value, ignoring the case.
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
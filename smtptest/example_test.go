@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtptest_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/wneessen/go-mail"
+	"github.com/wneessen/go-mail/smtptest"
+)
+
+func Example() {
+	// Start the test server with SMTP authentication and STARTTLS.
+	server, err := smtptest.NewServer(
+		smtptest.WithAuth("toni", "V3ryS3cr3t+"),
+		smtptest.WithSTARTTLS(),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+
+	// Send a message via the go-mail Client.
+	client, err := mail.NewClient(server.Host(), mail.WithPort(server.Port()),
+		mail.WithTLSConfig(server.ClientTLSConfig()), mail.WithSMTPAuth(mail.SMTPAuthSCRAMSHA256),
+		mail.WithUsername("toni"), mail.WithPassword("V3ryS3cr3t+"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	message := mail.NewMsg()
+	if err = message.From("toni.tester@example.com"); err != nil {
+		log.Fatal(err)
+	}
+	if err = message.To("tina.tester@example.com"); err != nil {
+		log.Fatal(err)
+	}
+	message.Subject("Test")
+	message.SetBodyString(mail.TypeTextPlain, "This is a test.")
+	if err = client.DialAndSend(message); err != nil {
+		log.Fatal(err)
+	}
+
+	// Inspect the received message.
+	for _, received := range server.Messages() {
+		fmt.Println(received.From, received.RcptAddresses(), received.AuthUser, received.TLS)
+	}
+	// Output: toni.tester@example.com [tina.tester@example.com] toni true
+}
+
+func ExampleServer_SetReplyFunc() {
+	server, err := smtptest.NewServer()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		_ = server.Close()
+	}()
+
+	// Reject a single recipient.
+	server.SetReplyFunc("RCPT", func(arg string) string {
+		if arg == "TO:<unknown@example.com>" {
+			return "550 5.1.1 User unknown"
+		}
+		return ""
+	})
+
+	client, err := mail.NewClient(server.Host(), mail.WithPort(server.Port()),
+		mail.WithTLSPolicy(mail.NoTLS))
+	if err != nil {
+		log.Fatal(err)
+	}
+	message := mail.NewMsg()
+	if err = message.From("toni.tester@example.com"); err != nil {
+		log.Fatal(err)
+	}
+	if err = message.To("unknown@example.com"); err != nil {
+		log.Fatal(err)
+	}
+	err = client.DialAndSend(message)
+	fmt.Println(err != nil, len(server.Messages()))
+	// Output: true 0
+}
@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtptest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strings"
+)
+
+// errConnClosed is returned when the connection to the client has been closed during a session.
+var errConnClosed = errors.New("smtptest: connection closed")
+
+// session represents a single SMTP session of a client with the Server.
+type session struct {
+	server   *Server
+	conn     net.Conn
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	helo     string
+	authUser string
+	authMech string
+	envelope *Envelope
+	tls      bool
+}
+
+// newSession returns a new session of the Server for the given connection.
+func newSession(server *Server, conn net.Conn) *session {
+	return &session{
+		server: server,
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+// serve greets the client and processes its commands until the client quits or the connection
+// is closed.
+func (s *session) serve() {
+	defer func() {
+		_ = s.conn.Close()
+	}()
+	if err := s.write("220 " + s.server.hostname + " ESMTP go-mail smtptest"); err != nil {
+		return
+	}
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		s.server.record(line)
+		command, arg, _ := strings.Cut(line, " ")
+		command = strings.ToUpper(command)
+
+		reply := s.server.reply(command, arg)
+		if isNegative(reply) {
+			if err = s.write(reply); err != nil {
+				return
+			}
+			continue
+		}
+
+		switch command {
+		case "EHLO", "HELO":
+			err = s.handleHello(command, arg, reply)
+		case "STARTTLS":
+			err = s.handleStartTLS(reply)
+		case "AUTH":
+			err = s.handleAuth(arg, reply)
+		case "MAIL":
+			err = s.handleMail(arg, reply)
+		case "RCPT":
+			err = s.handleRcpt(arg, reply)
+		case "DATA":
+			err = s.handleData(reply)
+		case "RSET":
+			s.envelope = nil
+			err = s.write(replyOr(reply, "250 2.0.0 Ok"))
+		case "NOOP":
+			err = s.write(replyOr(reply, "250 2.0.0 Ok"))
+		case "VRFY":
+			err = s.write(replyOr(reply, "252 2.0.0 Cannot VRFY user, but will accept message"))
+		case "QUIT":
+			_ = s.write(replyOr(reply, "221 2.0.0 Bye"))
+			return
+		default:
+			err = s.write(replyOr(reply, "502 5.5.2 Error: command not recognized"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleHello processes the EHLO and HELO commands.
+func (s *session) handleHello(command, arg, reply string) error {
+	if arg == "" {
+		return s.write("501 5.5.4 Syntax: " + command + " hostname")
+	}
+	s.helo = arg
+	s.envelope = nil
+	if command == "HELO" {
+		return s.write(replyOr(reply, "250 "+s.server.hostname))
+	}
+
+	lines := []string{s.server.hostname}
+	lines = append(lines, s.server.extensions...)
+	if s.server.pipelining {
+		lines = append(lines, "PIPELINING")
+	}
+	if s.server.dsn {
+		lines = append(lines, "DSN")
+	}
+	if s.server.tlsConfig != nil && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+	if mechanisms := s.authMechanisms(); len(mechanisms) > 0 {
+		lines = append(lines, "AUTH "+strings.Join(mechanisms, " "))
+	}
+	for i := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		lines[i] = "250" + separator + lines[i]
+	}
+	return s.write(replyOr(reply, strings.Join(lines, "\r\n")))
+}
+
+// handleStartTLS processes the STARTTLS command and performs the TLS handshake. The session is
+// reset to its initial state afterward, as required by RFC 3207.
+func (s *session) handleStartTLS(reply string) error {
+	switch {
+	case s.server.tlsConfig == nil:
+		return s.write("502 5.5.1 Error: command not implemented")
+	case s.tls:
+		return s.write("503 5.5.1 Error: TLS already active")
+	}
+	if err := s.write(replyOr(reply, "220 2.0.0 Ready to start TLS")); err != nil {
+		return err
+	}
+	tlsConn := tls.Server(s.conn, s.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	s.conn = tlsConn
+	s.reader = bufio.NewReader(tlsConn)
+	s.writer = bufio.NewWriter(tlsConn)
+	s.tls = true
+	s.helo, s.authUser, s.authMech, s.envelope = "", "", "", nil
+	return nil
+}
+
+// handleMail processes the "MAIL FROM" command.
+func (s *session) handleMail(arg, reply string) error {
+	switch {
+	case s.helo == "":
+		return s.write("503 5.5.1 Error: send HELO/EHLO first")
+	case len(s.server.auth) > 0 && s.authUser == "":
+		return s.write("530 5.7.0 Authentication required")
+	case s.envelope != nil:
+		return s.write("503 5.5.1 Error: nested MAIL command")
+	}
+	address, params, err := parsePath(arg, "FROM:")
+	if err != nil {
+		return s.write("501 5.5.4 Syntax: MAIL FROM:<address>")
+	}
+	for name := range params {
+		if !s.mailParamSupported(name) {
+			return s.write("555 5.5.4 Unsupported option: " + name)
+		}
+	}
+	s.envelope = &Envelope{From: address, MailParams: params}
+	return s.write(replyOr(reply, "250 2.1.0 Ok"))
+}
+
+// handleRcpt processes the "RCPT TO" command.
+func (s *session) handleRcpt(arg, reply string) error {
+	if s.envelope == nil {
+		return s.write("503 5.5.1 Error: need MAIL command")
+	}
+	address, params, err := parsePath(arg, "TO:")
+	if err != nil || address == "" {
+		return s.write("501 5.5.4 Syntax: RCPT TO:<address>")
+	}
+	for name := range params {
+		if !s.server.dsn || (name != "NOTIFY" && name != "ORCPT") {
+			return s.write("555 5.5.4 Unsupported option: " + name)
+		}
+	}
+	s.envelope.Rcpts = append(s.envelope.Rcpts, Rcpt{Address: address, Params: params})
+	return s.write(replyOr(reply, "250 2.1.5 Ok"))
+}
+
+// handleData processes the DATA command, reads the message data and stores the Message.
+func (s *session) handleData(reply string) error {
+	if s.envelope == nil || len(s.envelope.Rcpts) == 0 {
+		return s.write("503 5.5.1 Error: need RCPT command")
+	}
+	if err := s.write(replyOr(reply, "354 End data with <CR><LF>.<CR><LF>")); err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return errConnClosed
+		}
+		if line == ".\r\n" || line == ".\n" {
+			break
+		}
+		line = strings.TrimPrefix(line, ".")
+		data.WriteString(strings.TrimRight(line, "\r\n") + "\r\n")
+	}
+	envelope := *s.envelope
+	s.envelope = nil
+
+	reply = s.server.reply(EndOfData, "")
+	if isNegative(reply) {
+		return s.write(reply)
+	}
+	message := s.server.deliver(Message{
+		Envelope:      envelope,
+		Data:          data.Bytes(),
+		Helo:          s.helo,
+		AuthUser:      s.authUser,
+		AuthMechanism: s.authMech,
+		TLS:           s.tls,
+	})
+	return s.write(replyOr(reply, "250 2.0.0 Ok: queued as "+message.QueueID))
+}
+
+// mailParamSupported returns true if the given parameter of the "MAIL FROM" command is supported
+// by the extensions of the Server.
+func (s *session) mailParamSupported(name string) bool {
+	switch name {
+	case "BODY":
+		return s.hasExtension("8BITMIME") || s.hasExtension("BINARYMIME")
+	case "SIZE", "SMTPUTF8", "REQUIRETLS":
+		return s.hasExtension(name)
+	case "RET", "ENVID":
+		return s.server.dsn
+	}
+	return false
+}
+
+// hasExtension returns true if the Server advertises the given extension.
+func (s *session) hasExtension(name string) bool {
+	for _, extension := range s.server.extensions {
+		keyword, _, _ := strings.Cut(extension, " ")
+		if strings.EqualFold(keyword, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// authMechanisms returns the SMTP authentication mechanisms the Server advertises for the session.
+// The SCRAM-PLUS mechanisms require channel binding and are therefore only advertised on TLS
+// encrypted connections.
+func (s *session) authMechanisms() []string {
+	mechanisms := make([]string, 0, len(s.server.authMechs))
+	for _, mechanism := range s.server.authMechs {
+		if strings.HasSuffix(mechanism, "-PLUS") && !s.tls {
+			continue
+		}
+		mechanisms = append(mechanisms, mechanism)
+	}
+	return mechanisms
+}
+
+// readLine reads a single line from the client and returns it without the line ending.
+func (s *session) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", errConnClosed
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// write sends the given reply to the client. If PIPELINING is enabled, the reply is only flushed
+// once the client has no more pipelined commands pending.
+func (s *session) write(reply string) error {
+	if _, err := s.writer.WriteString(reply + "\r\n"); err != nil {
+		return err
+	}
+	if s.server.pipelining && s.reader.Buffered() > 0 {
+		return nil
+	}
+	return s.writer.Flush()
+}
+
+// parsePath parses the path and parameters of a "MAIL FROM" or "RCPT TO" command argument with
+// the given prefix. It returns the address without the angle brackets and the parameters keyed
+// by their upper case name.
+func parsePath(arg, prefix string) (string, map[string]string, error) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, errors.New("missing prefix")
+	}
+	arg = strings.TrimLeft(arg[len(prefix):], " ")
+	if !strings.HasPrefix(arg, "<") {
+		return "", nil, errors.New("missing opening angle bracket")
+	}
+	end := strings.Index(arg, ">")
+	if end < 0 {
+		return "", nil, errors.New("missing closing angle bracket")
+	}
+	address := arg[1:end]
+
+	params := make(map[string]string)
+	for _, param := range strings.Fields(arg[end+1:]) {
+		name, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(name)] = value
+	}
+	return address, params, nil
+}
+
+// isNegative returns true if the given reply is a transient or permanent negative reply.
+func isNegative(reply string) bool {
+	return reply != "" && (reply[0] == '4' || reply[0] == '5')
+}
+
+// replyOr returns the given reply if it is set, or the fallback reply otherwise.
+func replyOr(reply, fallback string) string {
+	if reply != "" {
+		return reply
+	}
+	return fallback
+}
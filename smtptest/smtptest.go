@@ -0,0 +1,577 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+// Package smtptest provides an in-process SMTP server for testing code that sends mail.
+//
+// The Server listens on a local TCP port or a UNIX domain socket and implements the parts of the
+// SMTP protocol that are commonly needed in tests: EHLO/HELO, STARTTLS with a generated
+// certificate, SMTP authentication via PLAIN, LOGIN and SCRAM, the DSN and PIPELINING extensions,
+// and the usual mail transaction commands. All received envelopes and raw messages are recorded
+// for assertions, and the reply to any command can be replaced to simulate server errors.
+//
+// The package does not depend on the mail package, so it can be used for testing any SMTP client.
+package smtptest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultHostname is the host name the Server uses in its greeting and EHLO reply if no other
+	// host name has been set via WithHostname.
+	DefaultHostname = "localhost.localdomain"
+
+	// EndOfData is the command name for the reply the Server sends after it has received the
+	// message data, i.e. the line with the single ".", for use with Server.SetReply.
+	EndOfData = "."
+)
+
+var (
+	// ErrNoUsers is returned when SMTP authentication mechanisms are set without any user
+	// credentials.
+	ErrNoUsers = errors.New("smtptest: authentication mechanisms set without users")
+
+	// ErrUnsupportedMechanism is returned when an unsupported SMTP authentication mechanism is set.
+	ErrUnsupportedMechanism = errors.New("smtptest: unsupported authentication mechanism")
+)
+
+// ReplyFunc is a function that returns the reply of the Server to a command. The argument holds
+// the arguments of the command as sent by the client, e.g. "TO:<toni.tester@example.com>" for
+// a "RCPT TO:<toni.tester@example.com>" command. The reply must be a complete SMTP reply,
+// including the reply code, e.g. "550 5.1.1 User unknown". Multiline replies are separated by
+// CRLF. If ReplyFunc returns an empty string, the Server replies as usual.
+type ReplyFunc func(arg string) string
+
+// Server is an in-process SMTP server for testing.
+type Server struct {
+	auth       map[string]string
+	authMechs  []string
+	commands   []string
+	conns      map[net.Conn]struct{}
+	extensions []string
+	hostname   string
+	listener   net.Listener
+	messages   chan<- Message
+	mutex      sync.RWMutex
+	queueID    int
+	received   []Message
+	replies    map[string]ReplyFunc
+	socketPath string
+	tlsConfig  *tls.Config
+	tlsCert    *x509.Certificate
+	wg         sync.WaitGroup
+	dsn        bool
+	pipelining bool
+	closed     bool
+}
+
+// Envelope represents the SMTP envelope of a mail transaction.
+type Envelope struct {
+	// From is the reverse-path of the "MAIL FROM" command without the angle brackets. It is empty
+	// for the null sender.
+	From string
+
+	// MailParams holds the parameters of the "MAIL FROM" command, e.g. "RET" or "ENVID", keyed by
+	// their upper case name. The values are not decoded.
+	MailParams map[string]string
+
+	// Rcpts holds the recipients of the accepted "RCPT TO" commands.
+	Rcpts []Rcpt
+}
+
+// Rcpt represents a single recipient of an Envelope.
+type Rcpt struct {
+	// Address is the forward-path of the "RCPT TO" command without the angle brackets.
+	Address string
+
+	// Params holds the parameters of the "RCPT TO" command, e.g. "NOTIFY" or "ORCPT", keyed by
+	// their upper case name. The values are not decoded.
+	Params map[string]string
+}
+
+// Message represents a message that has been received by the Server.
+type Message struct {
+	// Envelope is the SMTP envelope of the message.
+	Envelope
+
+	// Data holds the raw message data as received after the DATA command, with the dot-stuffing
+	// removed and CRLF line endings.
+	Data []byte
+
+	// Helo is the host name the client introduced itself with via EHLO or HELO.
+	Helo string
+
+	// AuthUser is the name of the authenticated user, or an empty string if the client did not
+	// authenticate.
+	AuthUser string
+
+	// AuthMechanism is the SMTP authentication mechanism used by the client, or an empty string
+	// if the client did not authenticate.
+	AuthMechanism string
+
+	// TLS indicates whether the message has been received over a TLS encrypted connection.
+	TLS bool
+
+	// QueueID is the queue ID the Server returned for the message.
+	QueueID string
+}
+
+// RcptAddresses returns the addresses of the recipients of the Envelope.
+func (e Envelope) RcptAddresses() []string {
+	addresses := make([]string, len(e.Rcpts))
+	for i, rcpt := range e.Rcpts {
+		addresses[i] = rcpt.Address
+	}
+	return addresses
+}
+
+// Option is a function type that modifies the configuration of a Server.
+type Option func(*Server) error
+
+// NewServer starts a new Server with the provided options. By default, the Server listens on a
+// random free port on the loopback interface and advertises the 8BITMIME and ENHANCEDSTATUSCODES
+// extensions. The Server must be stopped via Close.
+//
+// Parameters:
+//   - opts: Optional parameters for customizing the Server.
+//
+// Returns:
+//   - A pointer to the running Server.
+//   - An error if any option fails or the Server cannot listen.
+func NewServer(opts ...Option) (*Server, error) {
+	server := &Server{
+		auth:       make(map[string]string),
+		conns:      make(map[net.Conn]struct{}),
+		extensions: []string{"8BITMIME", "ENHANCEDSTATUSCODES"},
+		hostname:   DefaultHostname,
+		replies:    make(map[string]ReplyFunc),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(server); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	if len(server.auth) > 0 && len(server.authMechs) == 0 {
+		server.authMechs = []string{
+			"PLAIN", "LOGIN", "SCRAM-SHA-1", "SCRAM-SHA-1-PLUS", "SCRAM-SHA-256", "SCRAM-SHA-256-PLUS",
+		}
+	}
+	if len(server.auth) == 0 && len(server.authMechs) > 0 {
+		return nil, ErrNoUsers
+	}
+
+	var err error
+	if server.socketPath != "" {
+		server.listener, err = net.Listen("unix", server.socketPath)
+	} else {
+		server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	server.wg.Add(1)
+	go server.serve()
+	return server, nil
+}
+
+// WithHostname sets the host name the Server uses in its greeting and EHLO reply.
+//
+// Parameters:
+//   - hostname: The host name of the Server.
+//
+// Returns:
+//   - An Option function that sets the host name for the Server.
+func WithHostname(hostname string) Option {
+	return func(s *Server) error {
+		if hostname == "" {
+			return errors.New("smtptest: hostname must not be empty")
+		}
+		s.hostname = hostname
+		return nil
+	}
+}
+
+// WithUnixSocket makes the Server listen on a UNIX domain socket at the given path instead of a
+// TCP port. The socket file is removed when the Server is closed.
+//
+// Parameters:
+//   - path: The path of the UNIX domain socket.
+//
+// Returns:
+//   - An Option function that sets the UNIX domain socket for the Server.
+func WithUnixSocket(path string) Option {
+	return func(s *Server) error {
+		if path == "" {
+			return errors.New("smtptest: socket path must not be empty")
+		}
+		s.socketPath = path
+		return nil
+	}
+}
+
+// WithSTARTTLS enables the STARTTLS extension of the Server with a self-signed certificate that is
+// generated for "localhost", "127.0.0.1" and "::1". A matching client configuration is available
+// via Server.ClientTLSConfig.
+//
+// Returns:
+//   - An Option function that enables STARTTLS for the Server.
+func WithSTARTTLS() Option {
+	return func(s *Server) error {
+		certificate, parsed, err := generateCertificate()
+		if err != nil {
+			return err
+		}
+		s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+		s.tlsCert = parsed
+		return nil
+	}
+}
+
+// WithTLSConfig enables the STARTTLS extension of the Server with the given TLS configuration.
+//
+// Parameters:
+//   - config: The TLS configuration of the Server, holding at least one certificate.
+//
+// Returns:
+//   - An Option function that enables STARTTLS with the given configuration for the Server.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(s *Server) error {
+		if config == nil {
+			return errors.New("smtptest: TLS config must not be nil")
+		}
+		s.tlsConfig = config
+		return nil
+	}
+}
+
+// WithAuth adds a user with the given credentials to the Server and enables the AUTH extension.
+// It can be used multiple times to add multiple users. Once a user has been added, the Server
+// requires SMTP authentication before accepting a "MAIL FROM" command. Unless set otherwise via
+// WithAuthMechanisms, the Server supports the PLAIN, LOGIN, SCRAM-SHA-1, SCRAM-SHA-1-PLUS,
+// SCRAM-SHA-256 and SCRAM-SHA-256-PLUS mechanisms. The SCRAM-PLUS mechanisms are only advertised
+// on TLS encrypted connections.
+//
+// Parameters:
+//   - username: The name of the user.
+//   - password: The password of the user.
+//
+// Returns:
+//   - An Option function that adds the user to the Server.
+func WithAuth(username, password string) Option {
+	return func(s *Server) error {
+		if username == "" {
+			return errors.New("smtptest: username must not be empty")
+		}
+		s.auth[username] = password
+		return nil
+	}
+}
+
+// WithAuthMechanisms sets the SMTP authentication mechanisms the Server supports. Supported are
+// PLAIN, LOGIN, SCRAM-SHA-1, SCRAM-SHA-1-PLUS, SCRAM-SHA-256 and SCRAM-SHA-256-PLUS. At least one
+// user must be added via WithAuth.
+//
+// Parameters:
+//   - mechanisms: The names of the supported mechanisms.
+//
+// Returns:
+//   - An Option function that sets the authentication mechanisms for the Server.
+func WithAuthMechanisms(mechanisms ...string) Option {
+	return func(s *Server) error {
+		s.authMechs = s.authMechs[:0]
+		for _, mechanism := range mechanisms {
+			mechanism = strings.ToUpper(mechanism)
+			if _, ok := saslMechanisms[mechanism]; !ok {
+				return fmt.Errorf("%w: %s", ErrUnsupportedMechanism, mechanism)
+			}
+			s.authMechs = append(s.authMechs, mechanism)
+		}
+		return nil
+	}
+}
+
+// WithDSN enables the DSN extension of the Server. The Server then accepts the "RET" and "ENVID"
+// parameters of the "MAIL FROM" command and the "NOTIFY" and "ORCPT" parameters of the "RCPT TO"
+// command, which are recorded in the Envelope. Without the DSN extension, these parameters are
+// rejected.
+//
+// Returns:
+//   - An Option function that enables the DSN extension for the Server.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3461
+func WithDSN() Option {
+	return func(s *Server) error {
+		s.dsn = true
+		return nil
+	}
+}
+
+// WithPipelining enables the PIPELINING extension of the Server. The Server then flushes its
+// replies only once it has processed all the commands the client has sent in a batch.
+//
+// Returns:
+//   - An Option function that enables the PIPELINING extension for the Server.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc2920
+func WithPipelining() Option {
+	return func(s *Server) error {
+		s.pipelining = true
+		return nil
+	}
+}
+
+// WithExtensions adds the given extensions to the EHLO reply of the Server, e.g. "SMTPUTF8" or
+// "SIZE 10240000". The Server only advertises these extensions, but does not implement them,
+// except for accepting the "SIZE", "SMTPUTF8" and "REQUIRETLS" parameters of the "MAIL FROM"
+// command if the corresponding extension is advertised.
+//
+// Parameters:
+//   - extensions: The extensions to advertise.
+//
+// Returns:
+//   - An Option function that adds the extensions to the Server.
+func WithExtensions(extensions ...string) Option {
+	return func(s *Server) error {
+		s.extensions = append(s.extensions, extensions...)
+		return nil
+	}
+}
+
+// WithReply sets the reply of the Server to the given command. See Server.SetReply for details.
+//
+// Parameters:
+//   - command: The command name, e.g. "MAIL" or "RCPT", or EndOfData.
+//   - reply: The complete SMTP reply, e.g. "451 4.3.0 Temporary failure".
+//
+// Returns:
+//   - An Option function that sets the reply for the Server.
+func WithReply(command, reply string) Option {
+	return func(s *Server) error {
+		s.SetReply(command, reply)
+		return nil
+	}
+}
+
+// WithMessageChannel makes the Server send a copy of each received Message to the given channel.
+// The Server blocks until the Message has been received from the channel, so the channel should
+// be buffered or read concurrently.
+//
+// Parameters:
+//   - messages: The channel for the received messages.
+//
+// Returns:
+//   - An Option function that sets the message channel for the Server.
+func WithMessageChannel(messages chan<- Message) Option {
+	return func(s *Server) error {
+		s.messages = messages
+		return nil
+	}
+}
+
+// SetReply sets the reply of the Server to the given command, replacing its usual reply. The
+// command name is case-insensitive, e.g. "MAIL", "RCPT" or "DATA". The reply to the message data
+// is set via EndOfData. If the reply is a positive reply (2xx or 3xx), the Server processes the
+// command as usual and only replaces its reply. If the reply is a negative reply (4xx or 5xx),
+// the Server rejects the command without processing it. An empty reply restores the usual reply.
+//
+// Parameters:
+//   - command: The command name.
+//   - reply: The complete SMTP reply, e.g. "550 5.1.1 User unknown".
+func (s *Server) SetReply(command, reply string) {
+	if reply == "" {
+		s.SetReplyFunc(command, nil)
+		return
+	}
+	s.SetReplyFunc(command, func(string) string { return reply })
+}
+
+// SetReplyFunc sets a ReplyFunc that determines the reply of the Server to the given command, e.g.
+// to reject specific recipients only. The semantics of the reply are the same as for SetReply.
+// A nil ReplyFunc restores the usual reply.
+//
+// Parameters:
+//   - command: The command name.
+//   - replyFunc: The ReplyFunc for the command.
+func (s *Server) SetReplyFunc(command string, replyFunc ReplyFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	command = strings.ToUpper(command)
+	if replyFunc == nil {
+		delete(s.replies, command)
+		return
+	}
+	s.replies[command] = replyFunc
+}
+
+// Addr returns the network address the Server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Host returns the host the Server listens on. For a UNIX domain socket, Host returns the path
+// of the socket with the "unix://" prefix, as expected by the go-mail Client.
+func (s *Server) Host() string {
+	if s.socketPath != "" {
+		return "unix://" + s.socketPath
+	}
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the TCP port the Server listens on, or 0 for a UNIX domain socket.
+func (s *Server) Port() int {
+	if s.socketPath != "" {
+		return 0
+	}
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	value, _ := strconv.Atoi(port)
+	return value
+}
+
+// Certificate returns the certificate the Server uses for STARTTLS, if it has been generated via
+// WithSTARTTLS; otherwise, returns nil.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.tlsCert
+}
+
+// ClientTLSConfig returns a TLS configuration for a client that trusts the certificate generated
+// via WithSTARTTLS. If the Server uses a custom TLS configuration, the returned configuration
+// skips the certificate verification.
+func (s *Server) ClientTLSConfig() *tls.Config {
+	host := "localhost"
+	if s.socketPath == "" {
+		host = s.Host()
+	}
+	if s.tlsCert == nil {
+		// nolint:gosec // The certificate of a custom TLS configuration is unknown
+		return &tls.Config{ServerName: host, InsecureSkipVerify: true, MinVersion: tls.VersionTLS12}
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(s.tlsCert)
+	return &tls.Config{ServerName: host, RootCAs: pool, MinVersion: tls.VersionTLS12}
+}
+
+// Messages returns the messages the Server has received so far, in the order of their receipt.
+func (s *Server) Messages() []Message {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.received)
+}
+
+// Commands returns the commands the Server has received so far over all connections, in the order
+// of their receipt. The message data and the data of the SMTP authentication exchanges are not
+// included.
+func (s *Server) Commands() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return slices.Clone(s.commands)
+}
+
+// Reset clears the messages and commands the Server has recorded so far.
+func (s *Server) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.received = nil
+	s.commands = nil
+}
+
+// Close stops the Server, closes all open connections and waits for them to be finished.
+//
+// Returns:
+//   - An error if closing the listener fails; otherwise, returns nil.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	if s.socketPath != "" {
+		_ = os.Remove(s.socketPath)
+	}
+	return err
+}
+
+// serve accepts connections until the listener is closed.
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mutex.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			newSession(s, conn).serve()
+			s.mutex.Lock()
+			delete(s.conns, conn)
+			s.mutex.Unlock()
+		}()
+	}
+}
+
+// reply returns the reply that has been set for the given command and arguments, or an empty
+// string if the usual reply should be used.
+func (s *Server) reply(command, arg string) string {
+	s.mutex.RLock()
+	replyFunc, ok := s.replies[command]
+	s.mutex.RUnlock()
+	if !ok {
+		return ""
+	}
+	return replyFunc(arg)
+}
+
+// record adds the given command to the commands of the Server.
+func (s *Server) record(command string) {
+	s.mutex.Lock()
+	s.commands = append(s.commands, command)
+	s.mutex.Unlock()
+}
+
+// deliver stores the given Message with a new queue ID and sends it to the message channel, if
+// set.
+func (s *Server) deliver(message Message) Message {
+	s.mutex.Lock()
+	s.queueID++
+	message.QueueID = fmt.Sprintf("%010X", s.queueID)
+	s.received = append(s.received, message)
+	s.mutex.Unlock()
+
+	if s.messages != nil {
+		s.messages <- message
+	}
+	return message
+}
@@ -0,0 +1,576 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package smtptest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+const (
+	testSender    = "toni.tester@example.com"
+	testRcpt      = "tina.tester@example.com"
+	testMessage   = "Subject: Test\r\n\r\nThis is a test.\r\n.hidden line\r\n"
+	testUser      = "toni"
+	testPassword  = "V3ryS3cr3t+"
+	testHeloLocal = "localhost"
+)
+
+func TestNewServer(t *testing.T) {
+	t.Run("new server with defaults", func(t *testing.T) {
+		server := testServer(t)
+		if server.Host() != "127.0.0.1" {
+			t.Errorf("expected server to listen on 127.0.0.1, got: %s", server.Host())
+		}
+		if server.Port() == 0 {
+			t.Error("expected server to listen on a port")
+		}
+		client := testClient(t, server)
+		for _, extension := range []string{"8BITMIME", "ENHANCEDSTATUSCODES"} {
+			if ok, _ := client.Extension(extension); !ok {
+				t.Errorf("expected extension %s to be advertised", extension)
+			}
+		}
+		for _, extension := range []string{"PIPELINING", "DSN", "STARTTLS", "AUTH"} {
+			if ok, _ := client.Extension(extension); ok {
+				t.Errorf("expected extension %s not to be advertised", extension)
+			}
+		}
+	})
+	t.Run("new server with custom hostname and extensions", func(t *testing.T) {
+		server := testServer(t, WithHostname("mx.example.com"), WithExtensions("SMTPUTF8", "SIZE 1024"))
+		client := testClient(t, server)
+		if ok, _ := client.Extension("SMTPUTF8"); !ok {
+			t.Error("expected SMTPUTF8 to be advertised")
+		}
+		if _, size := client.Extension("SIZE"); size != "1024" {
+			t.Errorf("expected SIZE 1024 to be advertised, got: %s", size)
+		}
+		if !strings.HasPrefix(client.HelloResponse(), "mx.example.com") {
+			t.Errorf("expected custom hostname in EHLO reply, got: %s", client.HelloResponse())
+		}
+	})
+	t.Run("new server with nil option", func(t *testing.T) {
+		testServer(t, nil)
+	})
+	t.Run("new server fails on invalid options", func(t *testing.T) {
+		tests := []struct {
+			name string
+			opt  Option
+		}{
+			{"empty hostname", WithHostname("")},
+			{"empty socket path", WithUnixSocket("")},
+			{"nil TLS config", WithTLSConfig(nil)},
+			{"empty username", WithAuth("", testPassword)},
+			{"unsupported mechanism", WithAuthMechanisms("CRAM-MD5")},
+			{"mechanisms without users", WithAuthMechanisms("PLAIN")},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := NewServer(tt.opt); err == nil {
+					t.Error("expected server creation to fail")
+				}
+			})
+		}
+	})
+}
+
+func TestServer_Messages(t *testing.T) {
+	t.Run("message is recorded", func(t *testing.T) {
+		server := testServer(t)
+		client := testClient(t, server)
+		testSend(t, client, testRcpt, "other.tester@example.com")
+		messages := server.Messages()
+		if len(messages) != 1 {
+			t.Fatalf("expected 1 message, got: %d", len(messages))
+		}
+		message := messages[0]
+		if message.From != testSender {
+			t.Errorf("expected sender %s, got: %s", testSender, message.From)
+		}
+		rcpts := message.RcptAddresses()
+		if len(rcpts) != 2 || rcpts[0] != testRcpt || rcpts[1] != "other.tester@example.com" {
+			t.Errorf("unexpected recipients: %v", rcpts)
+		}
+		if string(message.Data) != testMessage {
+			t.Errorf("unexpected message data, want: %q, got: %q", testMessage, message.Data)
+		}
+		if message.Helo != testHeloLocal || message.TLS || message.AuthUser != "" || message.QueueID == "" {
+			t.Errorf("unexpected message details: %+v", message)
+		}
+		if message.MailParams["BODY"] != "8BITMIME" {
+			t.Errorf("expected BODY parameter, got: %v", message.MailParams)
+		}
+	})
+	t.Run("messages are sent to the message channel", func(t *testing.T) {
+		messages := make(chan Message, 1)
+		server := testServer(t, WithMessageChannel(messages))
+		client := testClient(t, server)
+		testSend(t, client, testRcpt)
+		message := <-messages
+		if message.From != testSender || message.QueueID != server.Messages()[0].QueueID {
+			t.Errorf("unexpected message on channel: %+v", message)
+		}
+	})
+	t.Run("commands are recorded and reset", func(t *testing.T) {
+		server := testServer(t)
+		client := testClient(t, server)
+		testSend(t, client, testRcpt)
+		if err := client.Quit(); err != nil {
+			t.Fatalf("failed to quit: %s", err)
+		}
+		want := []string{"EHLO localhost", "MAIL FROM:<" + testSender + "> BODY=8BITMIME",
+			"RCPT TO:<" + testRcpt + ">", "DATA", "QUIT"}
+		commands := server.Commands()
+		if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+			t.Errorf("unexpected commands, want: %q, got: %q", want, commands)
+		}
+		server.Reset()
+		if len(server.Commands()) != 0 || len(server.Messages()) != 0 {
+			t.Error("expected commands and messages to be reset")
+		}
+	})
+	t.Run("null sender is accepted", func(t *testing.T) {
+		server := testServer(t)
+		client := testClient(t, server)
+		if err := client.Mail("<>"); err != nil {
+			t.Fatalf("failed to send MAIL FROM: %s", err)
+		}
+	})
+}
+
+func TestServer_protocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		code     int
+	}{
+		{"MAIL before EHLO", []string{"MAIL FROM:<" + testSender + ">"}, 503},
+		{"EHLO without host name", []string{"EHLO"}, 501},
+		{"RCPT before MAIL", []string{"EHLO localhost", "RCPT TO:<" + testRcpt + ">"}, 503},
+		{"DATA before RCPT", []string{"EHLO localhost", "MAIL FROM:<" + testSender + ">", "DATA"}, 503},
+		{"nested MAIL", []string{
+			"EHLO localhost", "MAIL FROM:<" + testSender + ">", "MAIL FROM:<" + testSender + ">",
+		}, 503},
+		{"invalid MAIL syntax", []string{"EHLO localhost", "MAIL FROM:" + testSender}, 501},
+		{"invalid RCPT syntax", []string{"EHLO localhost", "MAIL FROM:<" + testSender + ">", "RCPT TO:<>"}, 501},
+		{"DSN parameter without DSN", []string{"EHLO localhost", "MAIL FROM:<" + testSender + "> RET=FULL"}, 555},
+		{"unknown MAIL parameter", []string{"EHLO localhost", "MAIL FROM:<" + testSender + "> FOO=BAR"}, 555},
+		{"unknown RCPT parameter", []string{
+			"EHLO localhost", "MAIL FROM:<" + testSender + ">", "RCPT TO:<" + testRcpt + "> NOTIFY=NEVER",
+		}, 555},
+		{"STARTTLS without TLS", []string{"EHLO localhost", "STARTTLS"}, 502},
+		{"AUTH without users", []string{"EHLO localhost", "AUTH PLAIN"}, 502},
+		{"unknown command", []string{"EHLO localhost", "FOO"}, 502},
+		{"RSET", []string{"EHLO localhost", "MAIL FROM:<" + testSender + ">", "RSET"}, 250},
+		{"NOOP", []string{"NOOP"}, 250},
+		{"VRFY", []string{"VRFY toni"}, 252},
+		{"HELO", []string{"HELO localhost"}, 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testServer(t)
+			code := testRawCommands(t, server, tt.commands...)
+			if code != tt.code {
+				t.Errorf("expected reply code %d, got: %d", tt.code, code)
+			}
+		})
+	}
+}
+
+func TestServer_STARTTLS(t *testing.T) {
+	t.Run("STARTTLS with generated certificate", func(t *testing.T) {
+		server := testServer(t, WithSTARTTLS())
+		if server.Certificate() == nil {
+			t.Fatal("expected certificate to be generated")
+		}
+		client := testClient(t, server)
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			t.Fatal("expected STARTTLS to be advertised")
+		}
+		if err := client.StartTLS(server.ClientTLSConfig()); err != nil {
+			t.Fatalf("failed to start TLS: %s", err)
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			t.Error("expected STARTTLS not to be advertised after TLS has been started")
+		}
+		testSend(t, client, testRcpt)
+		if messages := server.Messages(); len(messages) != 1 || !messages[0].TLS {
+			t.Errorf("expected message to be received over TLS, got: %+v", messages)
+		}
+	})
+	t.Run("STARTTLS with custom TLS config", func(t *testing.T) {
+		certificate, _, err := generateCertificate()
+		if err != nil {
+			t.Fatalf("failed to generate certificate: %s", err)
+		}
+		config := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+		server := testServer(t, WithTLSConfig(config))
+		if server.Certificate() != nil {
+			t.Error("expected no generated certificate")
+		}
+		client := testClient(t, server)
+		if err = client.StartTLS(server.ClientTLSConfig()); err != nil {
+			t.Fatalf("failed to start TLS: %s", err)
+		}
+	})
+	t.Run("STARTTLS twice fails", func(t *testing.T) {
+		server := testServer(t, WithSTARTTLS())
+		client := testClient(t, server)
+		if err := client.StartTLS(server.ClientTLSConfig()); err != nil {
+			t.Fatalf("failed to start TLS: %s", err)
+		}
+		_, err := client.Text.Cmd("STARTTLS")
+		if err != nil {
+			t.Fatalf("failed to send STARTTLS: %s", err)
+		}
+		if _, _, err = client.Text.ReadResponse(220); !isCode(err, 503) {
+			t.Errorf("expected 503 reply, got: %s", err)
+		}
+	})
+}
+
+func TestServer_Auth(t *testing.T) {
+	tests := []struct {
+		name string
+		auth func(state *tls.ConnectionState) smtp.Auth
+		tls  bool
+	}{
+		{"PLAIN", func(*tls.ConnectionState) smtp.Auth {
+			return smtp.PlainAuth("", testUser, testPassword, "127.0.0.1", false)
+		}, false},
+		{"LOGIN", func(*tls.ConnectionState) smtp.Auth {
+			return smtp.LoginAuth(testUser, testPassword, "127.0.0.1", false)
+		}, false},
+		{"SCRAM-SHA-1", func(*tls.ConnectionState) smtp.Auth {
+			return smtp.ScramSHA1Auth(testUser, testPassword)
+		}, false},
+		{"SCRAM-SHA-256", func(*tls.ConnectionState) smtp.Auth {
+			return smtp.ScramSHA256Auth(testUser, testPassword)
+		}, false},
+		{"SCRAM-SHA-1-PLUS", func(state *tls.ConnectionState) smtp.Auth {
+			return smtp.ScramSHA1PlusAuth(testUser, testPassword, state)
+		}, true},
+		{"SCRAM-SHA-256-PLUS", func(state *tls.ConnectionState) smtp.Auth {
+			return smtp.ScramSHA256PlusAuth(testUser, testPassword, state)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" succeeds", func(t *testing.T) {
+			server := testServer(t, WithAuth(testUser, testPassword), WithSTARTTLS())
+			client := testClient(t, server)
+			var state *tls.ConnectionState
+			if tt.tls {
+				if err := client.StartTLS(server.ClientTLSConfig()); err != nil {
+					t.Fatalf("failed to start TLS: %s", err)
+				}
+				connState, _ := client.TLSConnectionState()
+				state = &connState
+			}
+			if err := client.Auth(tt.auth(state)); err != nil {
+				t.Fatalf("failed to authenticate: %s", err)
+			}
+			testSend(t, client, testRcpt)
+			messages := server.Messages()
+			if len(messages) != 1 || messages[0].AuthUser != testUser || messages[0].AuthMechanism != tt.name {
+				t.Errorf("expected authenticated message, got: %+v", messages)
+			}
+		})
+		t.Run(tt.name+" fails with wrong password", func(t *testing.T) {
+			server := testServer(t, WithAuth(testUser, "wrong"), WithSTARTTLS())
+			client := testClient(t, server)
+			var state *tls.ConnectionState
+			if tt.tls {
+				if err := client.StartTLS(server.ClientTLSConfig()); err != nil {
+					t.Fatalf("failed to start TLS: %s", err)
+				}
+				connState, _ := client.TLSConnectionState()
+				state = &connState
+			}
+			if err := client.Auth(tt.auth(state)); err == nil {
+				t.Error("expected authentication to fail")
+			}
+		})
+	}
+	t.Run("MAIL FROM without authentication fails", func(t *testing.T) {
+		server := testServer(t, WithAuth(testUser, testPassword))
+		client := testClient(t, server)
+		if err := client.Mail("<" + testSender + ">"); !isCode(err, 530) {
+			t.Errorf("expected 530 reply, got: %s", err)
+		}
+	})
+	t.Run("PLUS mechanisms are only advertised with TLS", func(t *testing.T) {
+		server := testServer(t, WithAuth(testUser, testPassword))
+		client := testClient(t, server)
+		if _, mechanisms := client.Extension("AUTH"); strings.Contains(mechanisms, "PLUS") {
+			t.Errorf("expected no PLUS mechanisms without TLS, got: %s", mechanisms)
+		}
+	})
+	t.Run("restricted mechanisms", func(t *testing.T) {
+		server := testServer(t, WithAuth(testUser, testPassword), WithAuthMechanisms("login"))
+		client := testClient(t, server)
+		if _, mechanisms := client.Extension("AUTH"); mechanisms != "LOGIN" {
+			t.Errorf("expected only LOGIN to be advertised, got: %s", mechanisms)
+		}
+		if err := client.Auth(smtp.PlainAuth("", testUser, testPassword, "127.0.0.1", false)); !isCode(err, 504) {
+			t.Errorf("expected 504 reply, got: %s", err)
+		}
+	})
+	t.Run("cancelled authentication", func(t *testing.T) {
+		server := testServer(t, WithAuth(testUser, testPassword))
+		code := testRawCommands(t, server, "EHLO localhost", "AUTH LOGIN", "*")
+		if code != 501 {
+			t.Errorf("expected 501 reply, got: %d", code)
+		}
+	})
+}
+
+func TestServer_DSN(t *testing.T) {
+	server := testServer(t, WithDSN())
+	client := testClient(t, server)
+	if ok, _ := client.Extension("DSN"); !ok {
+		t.Fatal("expected DSN to be advertised")
+	}
+	client.SetDSNMailReturnOption("HDRS")
+	client.SetDSNRcptNotifyOption("SUCCESS,FAILURE")
+	client.SetDSNEnvelopeID("QQ314159")
+	client.SetDSNOriginalRcpts(map[string]string{"<" + testRcpt + ">": "tina@example.com"})
+	testSend(t, client, testRcpt)
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got: %d", len(messages))
+	}
+	if params := messages[0].MailParams; params["RET"] != "HDRS" || params["ENVID"] != "QQ314159" {
+		t.Errorf("unexpected MAIL FROM parameters: %v", params)
+	}
+	params := messages[0].Rcpts[0].Params
+	if params["NOTIFY"] != "SUCCESS,FAILURE" || params["ORCPT"] != "rfc822;tina@example.com" {
+		t.Errorf("unexpected RCPT TO parameters: %v", params)
+	}
+}
+
+func TestServer_Pipelining(t *testing.T) {
+	server := testServer(t, WithPipelining())
+	server.SetReplyFunc("RCPT", func(arg string) string {
+		if strings.Contains(arg, "invalid") {
+			return "550 5.1.1 User unknown"
+		}
+		return ""
+	})
+	client := testClient(t, server)
+	if ok, _ := client.Extension("PIPELINING"); !ok {
+		t.Fatal("expected PIPELINING to be advertised")
+	}
+	result, err := client.MailRcptPipelined("<"+testSender+">",
+		[]string{"<" + testRcpt + ">", "<invalid@example.com>"})
+	if err != nil {
+		t.Fatalf("failed to send pipelined commands: %s", err)
+	}
+	if result.MailErr != nil || result.RcptErrs[0] != nil || !isCode(result.RcptErrs[1], 550) {
+		t.Fatalf("unexpected pipeline result: %+v", result)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		t.Fatalf("failed to send DATA command: %s", err)
+	}
+	if _, err = writer.Write([]byte(testMessage)); err != nil {
+		t.Fatalf("failed to write message: %s", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatalf("failed to close data writer: %s", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || len(messages[0].Rcpts) != 1 || messages[0].Rcpts[0].Address != testRcpt {
+		t.Errorf("expected message to accepted recipient only, got: %+v", messages)
+	}
+}
+
+func TestServer_SetReply(t *testing.T) {
+	t.Run("negative reply rejects the command", func(t *testing.T) {
+		server := testServer(t, WithReply("mail", "451 4.3.0 Try again later"))
+		client := testClient(t, server)
+		if err := client.Mail("<" + testSender + ">"); !isCode(err, 451) {
+			t.Errorf("expected 451 reply, got: %s", err)
+		}
+		server.SetReply("MAIL", "")
+		testSend(t, client, testRcpt)
+	})
+	t.Run("negative reply to end of data rejects the message", func(t *testing.T) {
+		server := testServer(t)
+		server.SetReply(EndOfData, "554 5.7.1 Message rejected as spam")
+		client := testClient(t, server)
+		if err := client.Mail("<" + testSender + ">"); err != nil {
+			t.Fatalf("failed to send MAIL FROM: %s", err)
+		}
+		if err := client.Rcpt("<" + testRcpt + ">"); err != nil {
+			t.Fatalf("failed to send RCPT TO: %s", err)
+		}
+		writer, err := client.Data()
+		if err != nil {
+			t.Fatalf("failed to send DATA: %s", err)
+		}
+		if _, err = writer.Write([]byte(testMessage)); err != nil {
+			t.Fatalf("failed to write message: %s", err)
+		}
+		if err = writer.Close(); !isCode(err, 554) {
+			t.Errorf("expected 554 reply, got: %s", err)
+		}
+		if len(server.Messages()) != 0 {
+			t.Error("expected rejected message not to be recorded")
+		}
+	})
+	t.Run("positive reply replaces the usual reply", func(t *testing.T) {
+		server := testServer(t, WithReply(EndOfData, "250 2.0.0 Ok: queued as CUSTOM"))
+		client := testClient(t, server)
+		testSend(t, client, testRcpt)
+		if len(server.Messages()) != 1 {
+			t.Error("expected message to be recorded")
+		}
+	})
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "go-mail-smtptest-*")
+	if err != nil {
+		t.Fatalf("failed to create temp directory: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "smtp.sock")
+	server := testServer(t, WithUnixSocket(path))
+	if server.Host() != "unix://"+path || server.Port() != 0 {
+		t.Errorf("unexpected host and port: %s, %d", server.Host(), server.Port())
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to connect to UNIX domain socket: %s", err)
+	}
+	client, err := smtp.NewClient(conn, "localhost")
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	testSend(t, client, testRcpt)
+	if len(server.Messages()) != 1 {
+		t.Error("expected message to be recorded")
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("failed to create server: %s", err)
+	}
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to server: %s", err)
+	}
+	if err = server.Close(); err != nil {
+		t.Fatalf("failed to close server: %s", err)
+	}
+	if err = server.Close(); err != nil {
+		t.Errorf("expected second close to succeed, got: %s", err)
+	}
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	if _, _, err = reader.ReadResponse(220); err != nil {
+		t.Fatalf("failed to read greeting: %s", err)
+	}
+	if _, err = reader.ReadLine(); err == nil {
+		t.Error("expected connection to be closed")
+	}
+}
+
+// testServer starts a Server with the given options that is closed when the test finishes.
+func testServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	server, err := NewServer(opts...)
+	if err != nil {
+		t.Fatalf("failed to create server: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := server.Close(); err != nil {
+			t.Errorf("failed to close server: %s", err)
+		}
+	})
+	return server
+}
+
+// testClient returns an smtp.Client that is connected to the given Server and has sent the EHLO.
+func testClient(t *testing.T, server *Server) *smtp.Client {
+	t.Helper()
+	client, err := smtp.Dial(server.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	if err = client.Hello(testHeloLocal); err != nil {
+		t.Fatalf("failed to send EHLO: %s", err)
+	}
+	return client
+}
+
+// testSend sends the test message to the given recipients with the given smtp.Client.
+func testSend(t *testing.T, client *smtp.Client, rcpts ...string) {
+	t.Helper()
+	if err := client.Mail("<" + testSender + ">"); err != nil {
+		t.Fatalf("failed to send MAIL FROM: %s", err)
+	}
+	for _, rcpt := range rcpts {
+		if err := client.Rcpt("<" + rcpt + ">"); err != nil {
+			t.Fatalf("failed to send RCPT TO: %s", err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		t.Fatalf("failed to send DATA: %s", err)
+	}
+	if _, err = writer.Write([]byte(testMessage)); err != nil {
+		t.Fatalf("failed to write message: %s", err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatalf("failed to close data writer: %s", err)
+	}
+}
+
+// testRawCommands sends the given commands to the Server on a new connection and returns the
+// reply code of the last command.
+func testRawCommands(t *testing.T, server *Server, commands ...string) int {
+	t.Helper()
+	conn, err := textproto.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect to server: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	if _, _, err = conn.ReadResponse(220); err != nil {
+		t.Fatalf("failed to read greeting: %s", err)
+	}
+	var code int
+	for _, command := range commands {
+		if err = conn.PrintfLine("%s", command); err != nil {
+			t.Fatalf("failed to send command: %s", err)
+		}
+		code, _, _ = conn.ReadResponse(0)
+	}
+	return code
+}
+
+// isCode returns true if the given error is a textproto.Error with the given reply code.
+func isCode(err error, code int) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code == code
+}
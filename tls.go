@@ -20,6 +20,21 @@ const (
 
 	// NoTLS forces the transaction to be not encrypted.
 	NoTLS
+
+	// TLSMTASTS enforces the MTA-STS policy of the domain set with WithMTASTS. If the policy is
+	// enforced, the connection must be encrypted using STARTTLS and the hostname of the server must
+	// match the policy. If the domain does not publish an enforced policy, it behaves like
+	// TLSOpportunistic.
+	//
+	// https://datatracker.ietf.org/doc/html/rfc8461
+	TLSMTASTS
+
+	// TLSDANE verifies the certificate of the server against the DNSSEC validated TLSA records
+	// provided by the TLSAResolver set with WithDANE. If usable TLSA records exist, the connection
+	// must be encrypted using STARTTLS. Otherwise, the fallback TLSPolicy of WithDANE is used.
+	//
+	// https://datatracker.ietf.org/doc/html/rfc7672
+	TLSDANE
 )
 
 // String satisfies the fmt.Stringer interface for the TLSPolicy type.
@@ -38,6 +53,10 @@ func (p TLSPolicy) String() string {
 		return "TLSOpportunistic"
 	case NoTLS:
 		return "NoTLS"
+	case TLSMTASTS:
+		return "TLSMTASTS"
+	case TLSDANE:
+		return "TLSDANE"
 	default:
 		return "UnknownPolicy"
 	}
@@ -16,7 +16,9 @@ func TestTLSPolicy_String(t *testing.T) {
 		{"TLSPolicy is Mandatory", TLSMandatory, 0},
 		{"TLSPolicy is Opportunistic", TLSOpportunistic, 1},
 		{"TLSPolicy is NoTLS", NoTLS, 2},
-		{"TLSPolicy is Unknown", 3, 3},
+		{"TLSPolicy is MTA-STS", TLSMTASTS, 3},
+		{"TLSPolicy is DANE", TLSDANE, 4},
+		{"TLSPolicy is Unknown", 5, 5},
 	}
 
 	for _, tt := range tests {
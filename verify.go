@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+const (
+	// VerifyUnknown indicates that the existence of the address could not be determined, e.g.
+	// because the server rejected the probe for policy reasons or does not verify addresses.
+	VerifyUnknown VerifyStatus = iota
+
+	// VerifyAccepted indicates that the server accepted the address.
+	VerifyAccepted
+
+	// VerifyRejected indicates that the server rejected the address permanently, e.g. because the
+	// mailbox does not exist.
+	VerifyRejected
+
+	// VerifyTemporary indicates that the server rejected the address temporarily, e.g. because of
+	// greylisting or a full mailbox.
+	VerifyTemporary
+)
+
+// ErrVerifyNullSender is returned by Client.VerifyRecipients if the server rejects the null sender
+// of the probes.
+var ErrVerifyNullSender = errors.New("server rejected the null sender")
+
+// VerifyStatus is the classification of an address by Client.VerifyRecipients.
+type VerifyStatus int
+
+// VerifyResult represents the result of the verification of a single address by
+// Client.VerifyRecipients.
+type VerifyResult struct {
+	// Address is the address as passed to Client.VerifyRecipients.
+	Address string
+
+	// Status is the classification of the address.
+	Status VerifyStatus
+
+	// Code is the reply code of the server for the "RCPT TO" probe, or zero if the probe has not
+	// been sent.
+	Code int
+
+	// EnhancedStatusCode is the enhanced status code of the server reply, if the server supports
+	// the ENHANCEDSTATUSCODES extension.
+	EnhancedStatusCode string
+
+	// Response is the reply text of the server for the "RCPT TO" probe.
+	Response string
+
+	// Err is the error of the probe, or nil if the server accepted the address.
+	Err error
+}
+
+// String satisfies the fmt.Stringer interface for the VerifyStatus type.
+func (s VerifyStatus) String() string {
+	switch s {
+	case VerifyAccepted:
+		return "accepted"
+	case VerifyRejected:
+		return "rejected"
+	case VerifyTemporary:
+		return "temporary"
+	default:
+		return "unknown"
+	}
+}
+
+// VerifyRecipients verifies the provided recipient addresses with SMTP callouts.
+//
+// Since the VRFY command is disabled on most servers, the addresses are verified by probing them
+// in a mail transaction. The Client connects to the server and sends a "MAIL FROM:<>" command,
+// followed by a "RCPT TO" command for each address. The mail transaction is reset with the "RSET"
+// command afterward, so that no message data is ever sent. Each address is classified based on
+// the reply code and the enhanced status code of the server for its "RCPT TO" command:
+//   - Addresses that have been accepted are classified as VerifyAccepted.
+//   - Addresses that have been rejected permanently because of the address itself, e.g. with
+//     5.1.1 (bad destination mailbox address), are classified as VerifyRejected, as are
+//     addresses that cannot be parsed.
+//   - Addresses that have been rejected temporarily are classified as VerifyTemporary.
+//   - All other addresses, e.g. rejected for policy reasons or accepted with 252 (cannot verify
+//     the user), are classified as VerifyUnknown.
+//
+// Note that many servers accept any address of their domains, so an accepted address does not
+// guarantee that the address exists.
+//
+// Parameters:
+//   - ctx: The context.Context to control the connection timeout and cancellation.
+//   - addrs: The recipient addresses to verify.
+//
+// Returns:
+//   - A VerifyResult for each of the provided addresses, in the same order.
+//   - An error if the connection to the server failed, the server rejected the null sender, or
+//     the connection broke during the verification. Addresses that could not be probed are
+//     classified as VerifyUnknown.
+func (c *Client) VerifyRecipients(ctx context.Context, addrs ...string) ([]VerifyResult, error) {
+	results := make([]VerifyResult, len(addrs))
+	for i, addr := range addrs {
+		results[i] = VerifyResult{Address: addr, Status: VerifyUnknown}
+	}
+	if len(addrs) == 0 {
+		return results, nil
+	}
+
+	client, err := c.DialToSMTPClientWithContext(ctx)
+	if err != nil {
+		return results, fmt.Errorf("dial failed: %w", err)
+	}
+	defer func() {
+		_ = c.closeWithSMTPClient(ctx, client)
+	}()
+	escSupport, _ := client.Extension("ENHANCEDSTATUSCODES")
+
+	if err = client.Mail("<>"); err != nil {
+		return results, fmt.Errorf("%w: %w", ErrVerifyNullSender, err)
+	}
+	for i, addr := range addrs {
+		address, perr := mail.ParseAddress(addr)
+		if perr != nil {
+			results[i].Status = VerifyRejected
+			results[i].Err = fmt.Errorf(errParseMailAddr, addr, perr)
+			continue
+		}
+		response, rerr := client.RcptWithResponse(mailAddressStringWithoutName(*address))
+		result := newRcptResult(response, escSupport)
+		results[i].Code = result.Code
+		results[i].EnhancedStatusCode = result.EnhancedStatusCode
+		results[i].Response = result.Response
+		results[i].Err = rerr
+		if rerr != nil && response.Code == 0 {
+			// The connection broke, so the remaining addresses cannot be probed
+			return results, fmt.Errorf("failed to verify %s: %w", addr, rerr)
+		}
+		results[i].Status = verifyStatus(result.Code, result.EnhancedStatusCode)
+	}
+	if err = client.Reset(); err != nil {
+		return results, fmt.Errorf("failed to reset mail transaction: %w", err)
+	}
+	return results, nil
+}
+
+// verifyStatus classifies an address based on the reply of the server for its "RCPT TO" probe.
+//
+// Parameters:
+//   - code: The reply code of the server.
+//   - enhancedCode: The enhanced status code of the server reply, or an empty string if the
+//     server does not support ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - The VerifyStatus of the address.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/html/rfc3463
+func verifyStatus(code int, enhancedCode string) VerifyStatus {
+	switch {
+	case code == 250 || code == 251:
+		return VerifyAccepted
+	case code >= 400 && code < 500:
+		return VerifyTemporary
+	case code >= 500 && code < 600:
+		if enhancedCode != "" {
+			// X.1.X are addressing status codes, 5.2.1 indicates a disabled mailbox
+			if strings.HasPrefix(enhancedCode, "5.1.") || enhancedCode == "5.2.1" {
+				return VerifyRejected
+			}
+			return VerifyUnknown
+		}
+		if code == 550 || code == 551 || code == 553 {
+			return VerifyRejected
+		}
+	}
+	return VerifyUnknown
+}
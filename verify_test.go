@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVerifyStatus_String(t *testing.T) {
+	tests := []struct {
+		status VerifyStatus
+		want   string
+	}{
+		{VerifyUnknown, "unknown"},
+		{VerifyAccepted, "accepted"},
+		{VerifyRejected, "rejected"},
+		{VerifyTemporary, "temporary"},
+		{VerifyStatus(99), "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.status.String(); got != tt.want {
+				t.Errorf("expected %q, got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVerifyStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         int
+		enhancedCode string
+		want         VerifyStatus
+	}{
+		{"accepted", 250, "2.1.5", VerifyAccepted},
+		{"accepted for forwarding", 251, "", VerifyAccepted},
+		{"cannot verify", 252, "2.5.0", VerifyUnknown},
+		{"greylisted", 450, "4.7.1", VerifyTemporary},
+		{"mailbox full", 452, "4.2.2", VerifyTemporary},
+		{"bad mailbox", 550, "5.1.1", VerifyRejected},
+		{"disabled mailbox", 550, "5.2.1", VerifyRejected},
+		{"policy rejection", 550, "5.7.1", VerifyUnknown},
+		{"bad mailbox without enhanced code", 550, "", VerifyRejected},
+		{"transaction failed without enhanced code", 554, "", VerifyUnknown},
+		{"no reply", 0, "", VerifyUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyStatus(tt.code, tt.enhancedCode); got != tt.want {
+				t.Errorf("expected %s, got: %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClient_VerifyRecipients(t *testing.T) {
+	replies := map[string]string{
+		"<valid@domain.tld>":   "250 2.1.5 Ok",
+		"<unknown@domain.tld>": "550 5.1.1 <unknown@domain.tld>: Recipient address rejected",
+		"<grey@domain.tld>":    "450 4.7.1 <grey@domain.tld>: Greylisted, try again later",
+		"<policy@domain.tld>":  "550 5.7.1 <policy@domain.tld>: Relay access denied",
+	}
+	t.Run("addresses are classified", func(t *testing.T) {
+		server := testVerifyServer(t, "250 2.1.0 Ok", replies)
+		client := testVerifyClient(t, server)
+		addrs := []string{
+			"valid@domain.tld", "Toni Tester <unknown@domain.tld>", "grey@domain.tld",
+			"policy@domain.tld", "invalid",
+		}
+		results, err := client.VerifyRecipients(t.Context(), addrs...)
+		if err != nil {
+			t.Fatalf("failed to verify recipients: %s", err)
+		}
+		want := []VerifyStatus{VerifyAccepted, VerifyRejected, VerifyTemporary, VerifyUnknown, VerifyRejected}
+		if len(results) != len(want) {
+			t.Fatalf("expected %d results, got: %d", len(want), len(results))
+		}
+		for i, result := range results {
+			if result.Address != addrs[i] {
+				t.Errorf("expected address %s, got: %s", addrs[i], result.Address)
+			}
+			if result.Status != want[i] {
+				t.Errorf("expected %s to be %s, got: %s", addrs[i], want[i], result.Status)
+			}
+		}
+		if results[1].Code != 550 || results[1].EnhancedStatusCode != "5.1.1" || results[1].Err == nil {
+			t.Errorf("unexpected result for rejected address: %+v", results[1])
+		}
+		if results[4].Code != 0 || results[4].Err == nil {
+			t.Errorf("expected parse error for invalid address, got: %+v", results[4])
+		}
+		commands := server.commands()
+		if !strings.Contains(commands, "MAIL FROM:<>") {
+			t.Errorf("expected null sender, got: %s", commands)
+		}
+		if !strings.Contains(commands, "\nRSET\n") {
+			t.Errorf("expected RSET command, got: %s", commands)
+		}
+		if strings.Contains(commands, "DATA") || strings.Contains(commands, "BDAT") {
+			t.Errorf("expected no message data to be sent, got: %s", commands)
+		}
+	})
+	t.Run("rejected null sender fails", func(t *testing.T) {
+		server := testVerifyServer(t, "550 5.7.1 Null sender not allowed", replies)
+		client := testVerifyClient(t, server)
+		results, err := client.VerifyRecipients(t.Context(), "valid@domain.tld")
+		if !errors.Is(err, ErrVerifyNullSender) {
+			t.Errorf("expected ErrVerifyNullSender, got: %s", err)
+		}
+		if len(results) != 1 || results[0].Status != VerifyUnknown {
+			t.Errorf("expected unknown result, got: %+v", results)
+		}
+	})
+	t.Run("no addresses", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithPort(1))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		results, err := client.VerifyRecipients(t.Context())
+		if err != nil || len(results) != 0 {
+			t.Errorf("expected no results and no error, got: %v, %s", results, err)
+		}
+	})
+	t.Run("failed dial", func(t *testing.T) {
+		client, err := NewClient(DefaultHost, WithPort(1), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		results, err := client.VerifyRecipients(t.Context(), "valid@domain.tld")
+		if err == nil {
+			t.Error("expected dial to fail")
+		}
+		if len(results) != 1 || results[0].Status != VerifyUnknown {
+			t.Errorf("expected unknown result, got: %+v", results)
+		}
+	})
+}
+
+// testVerify is a minimal SMTP server for the verification tests, that replies to the "MAIL FROM"
+// command with the configured reply and to each "RCPT TO" command with the reply for the recipient.
+type testVerify struct {
+	mutex    sync.Mutex
+	port     int
+	log      strings.Builder
+	mailFrom string
+	replies  map[string]string
+}
+
+// commands returns the commands received by the server.
+func (s *testVerify) commands() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.log.String()
+}
+
+// testVerifyServer starts a testVerify server with the given replies.
+func testVerifyServer(t *testing.T, mailFrom string, replies map[string]string) *testVerify {
+	t.Helper()
+	PortAdder.Add(1)
+	server := &testVerify{
+		port:     int(TestServerPortBase + PortAdder.Load()),
+		mailFrom: mailFrom,
+		replies:  replies,
+	}
+	listener, err := net.Listen(TestServerProto, fmt.Sprintf("%s:%d", TestServerAddr, server.port))
+	if err != nil {
+		t.Fatalf("failed to listen on test server port: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, aerr := listener.Accept()
+			if aerr != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+// handle serves a single SMTP connection.
+func (s *testVerify) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	writeLine := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	writeLine("220 go-mail test server ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		s.mutex.Lock()
+		s.log.WriteString(line + "\n")
+		s.mutex.Unlock()
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			writeLine("250-localhost.localdomain\r\n250-8BITMIME\r\n250 ENHANCEDSTATUSCODES")
+		case strings.HasPrefix(line, "MAIL FROM:"):
+			writeLine(s.mailFrom)
+		case strings.HasPrefix(line, "RCPT TO:"):
+			reply, ok := s.replies[strings.TrimPrefix(line, "RCPT TO:")]
+			if !ok {
+				reply = "550 5.1.1 Unknown user"
+			}
+			writeLine(reply)
+		case line == "NOOP", line == "RSET":
+			writeLine("250 2.0.0 Ok")
+		case line == "QUIT":
+			writeLine("221 2.0.0 Bye")
+			return
+		default:
+			writeLine("500 5.5.2 Error: bad syntax")
+		}
+	}
+}
+
+// testVerifyClient returns a Client that connects to the given testVerify server.
+func testVerifyClient(t *testing.T, server *testVerify) *Client {
+	t.Helper()
+	client, err := NewClient(DefaultHost, WithPort(server.port), WithTLSPolicy(NoTLS))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}
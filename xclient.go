@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/wneessen/go-mail/smtp"
+)
+
+// OriginalClient holds the information about the original SMTP client on whose behalf the Client
+// submits messages, e.g. when go-mail is used in a submission proxy in front of a Postfix MTA.
+// Empty fields are not forwarded to the server.
+type OriginalClient struct {
+	// Addr is the IP address of the original client. IPv6 addresses are prefixed with "IPV6:"
+	// automatically.
+	Addr string
+
+	// Port is the TCP port of the original client.
+	Port int
+
+	// Name is the verified reverse DNS host name of the original client.
+	Name string
+
+	// Helo is the HELO or EHLO name the original client introduced itself with.
+	Helo string
+
+	// Proto is the protocol the original client used, i.e. "SMTP" or "ESMTP".
+	Proto string
+
+	// Login is the SASL login name of the original client. It is only forwarded via XCLIENT.
+	Login string
+}
+
+// WithOriginalClient sets the information about the original SMTP client for the Client.
+//
+// If the server advertises the XCLIENT extension, the Client sends the XCLIENT command right after
+// the TLS negotiation, so that the server treats the SMTP session as if it came from the original
+// client. The server then greets the Client again and the Client repeats the EHLO. When the Login
+// of the original client is forwarded, the server considers the session as authenticated, so SMTP
+// authentication of the Client is usually not needed. If the server advertises the XFORWARD
+// extension, the Client sends the XFORWARD command before each mail transaction, so that the
+// server logs the information of the original client. Only the attributes that are advertised
+// by the server are sent. If neither extension is advertised, the information is ignored. The
+// server must be configured to authorize the Client for these commands, e.g. via the
+// "smtpd_authorized_xclient_hosts" and "smtpd_authorized_xforward_hosts" settings of Postfix.
+//
+// Parameters:
+//   - original: The OriginalClient holding the information about the original SMTP client.
+//
+// Returns:
+//   - An Option function that sets the original client information for the Client.
+//
+// References:
+//   - https://www.postfix.org/XCLIENT_README.html
+//   - https://www.postfix.org/XFORWARD_README.html
+func WithOriginalClient(original OriginalClient) Option {
+	return func(c *Client) error {
+		c.originalClient = &original
+		return nil
+	}
+}
+
+// xclient sends the XCLIENT command with the original client information to the server, if the
+// Client has been configured with an OriginalClient and the server advertises the XCLIENT
+// extension.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that is connected to the server.
+//
+// Returns:
+//   - An error if the XCLIENT command fails; otherwise, returns nil.
+func (c *Client) xclient(client *smtp.Client) error {
+	if c.originalClient == nil {
+		return nil
+	}
+	ok, supported := client.Extension("XCLIENT")
+	if !ok {
+		return nil
+	}
+	attrs := c.originalClient.attributes(supported)
+	if len(attrs) == 0 {
+		return nil
+	}
+	return client.XClient(attrs)
+}
+
+// xforward sends the XFORWARD command with the original client information to the server before
+// the mail transaction for the provided Msg, if the Client has been configured with an
+// OriginalClient and the server advertises the XFORWARD extension.
+//
+// Parameters:
+//   - client: A pointer to the smtp.Client that is connected to the server.
+//   - message: A pointer to the Msg that is about to be sent.
+//   - escSupport: Indicates whether the server supports ENHANCEDSTATUSCODES.
+//
+// Returns:
+//   - A SendError with the ErrSMTPMailFrom reason if the XFORWARD command fails; otherwise,
+//     returns nil.
+func (c *Client) xforward(client *smtp.Client, message *Msg, escSupport bool) *SendError {
+	if c.originalClient == nil {
+		return nil
+	}
+	ok, supported := client.Extension("XFORWARD")
+	if !ok {
+		return nil
+	}
+	attrs := c.originalClient.attributes(supported)
+	if len(attrs) == 0 {
+		return nil
+	}
+	if err := client.XForward(attrs); err != nil {
+		return &SendError{
+			Reason: ErrSMTPMailFrom, errlist: []error{err}, isTemp: isTempError(err),
+			affectedMsg: message, errcode: errorCode(err),
+			enhancedStatusCode: enhancedStatusCode(err, escSupport),
+		}
+	}
+	return nil
+}
+
+// attributes returns the non-empty fields of the OriginalClient as XCLIENT or XFORWARD attributes,
+// limited to the space separated attribute names supported by the server.
+//
+// Parameters:
+//   - supported: The attribute names as advertised by the server in the EHLO response.
+//
+// Returns:
+//   - A map of the attribute names to their values.
+func (o *OriginalClient) attributes(supported string) map[string]string {
+	addr := o.Addr
+	if ip := net.ParseIP(addr); ip != nil && ip.To4() == nil {
+		addr = "IPV6:" + addr
+	}
+	port := ""
+	if o.Port > 0 {
+		port = strconv.Itoa(o.Port)
+	}
+	values := map[string]string{
+		"ADDR": addr, "PORT": port, "NAME": o.Name, "HELO": o.Helo, "PROTO": o.Proto, "LOGIN": o.Login,
+	}
+
+	attrs := make(map[string]string)
+	for _, name := range strings.Fields(strings.ToUpper(supported)) {
+		if value := values[name]; value != "" {
+			attrs[name] = value
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: The go-mail Authors
+//
+// SPDX-License-Identifier: MIT
+
+package mail
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWithOriginalClient(t *testing.T) {
+	original := OriginalClient{Addr: "192.0.2.1", Helo: "client.example.com"}
+	client, err := NewClient(DefaultHost, WithOriginalClient(original))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	if client.originalClient == nil || *client.originalClient != original {
+		t.Errorf("expected original client to be set, got: %+v", client.originalClient)
+	}
+}
+
+func TestOriginalClient_attributes(t *testing.T) {
+	original := &OriginalClient{
+		Addr: "2001:db8::1", Port: 2525, Name: "client.example.com", Proto: "ESMTP", Login: "toni",
+	}
+	tests := []struct {
+		name      string
+		supported string
+		want      map[string]string
+	}{
+		{
+			"all advertised attributes are returned",
+			"NAME ADDR PORT PROTO HELO LOGIN",
+			map[string]string{
+				"ADDR": "IPV6:2001:db8::1", "PORT": "2525", "NAME": "client.example.com",
+				"PROTO": "ESMTP", "LOGIN": "toni",
+			},
+		},
+		{
+			"attributes not advertised are skipped",
+			"ADDR NAME SOURCE",
+			map[string]string{"ADDR": "IPV6:2001:db8::1", "NAME": "client.example.com"},
+		},
+		{"no advertised attributes", "", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := original.attributes(tt.supported)
+			if len(attrs) != len(tt.want) {
+				t.Fatalf("expected %d attributes, got: %v", len(tt.want), attrs)
+			}
+			for name, value := range tt.want {
+				if attrs[name] != value {
+					t.Errorf("expected attribute %s to be %q, got: %q", name, value, attrs[name])
+				}
+			}
+		})
+	}
+	t.Run("IPv4 address is not prefixed", func(t *testing.T) {
+		attrs := (&OriginalClient{Addr: "192.0.2.1"}).attributes("ADDR")
+		if attrs["ADDR"] != "192.0.2.1" {
+			t.Errorf("expected IPv4 address, got: %q", attrs["ADDR"])
+		}
+	})
+}
+
+func TestClient_Send_OriginalClient(t *testing.T) {
+	original := OriginalClient{Addr: "192.0.2.1", Name: "client.example.com", Helo: "client.example.com"}
+	t.Run("XCLIENT is sent after the greeting", func(t *testing.T) {
+		server := testXClientServer(t, "", "", "XCLIENT NAME ADDR HELO LOGIN")
+		client := testXClientClient(t, server, original)
+		message := testMessage(t)
+		if err := client.DialAndSend(message); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		commands := server.commands()
+		want := "XCLIENT ADDR=192.0.2.1 HELO=client.example.com NAME=client.example.com\n" +
+			"EHLO client.example.com\n"
+		index := strings.Index(commands, want)
+		if index < 0 || strings.Count(commands[:index], "EHLO") != 1 ||
+			strings.Index(commands, "MAIL FROM:") < index {
+			t.Errorf("expected XCLIENT and EHLO after the greeting, got: %q", commands)
+		}
+	})
+	t.Run("XFORWARD is sent before each mail transaction", func(t *testing.T) {
+		server := testXClientServer(t, "", "", "XFORWARD NAME ADDR PROTO HELO SOURCE")
+		client := testXClientClient(t, server, original)
+		if err := client.DialAndSend(testMessage(t), testMessage(t)); err != nil {
+			t.Fatalf("failed to send messages: %s", err)
+		}
+		forward := "XFORWARD ADDR=192.0.2.1 HELO=client.example.com NAME=client.example.com\nMAIL FROM:"
+		if count := strings.Count(server.commands(), forward); count != 2 {
+			t.Errorf("expected 2 XFORWARD commands before MAIL FROM, got: %d in %q", count, server.commands())
+		}
+	})
+	t.Run("nothing is sent if not advertised by the server", func(t *testing.T) {
+		server := testXClientServer(t, "", "", "8BITMIME")
+		client := testXClientClient(t, server, original)
+		if err := client.DialAndSend(testMessage(t)); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if strings.Contains(server.commands(), "XCLIENT") || strings.Contains(server.commands(), "XFORWARD") {
+			t.Errorf("expected no XCLIENT or XFORWARD commands, got: %q", server.commands())
+		}
+	})
+	t.Run("nothing is sent without original client", func(t *testing.T) {
+		server := testXClientServer(t, "", "", "XCLIENT NAME ADDR", "XFORWARD NAME ADDR")
+		client, err := NewClient(DefaultHost, WithPort(server.port), WithTLSPolicy(NoTLS))
+		if err != nil {
+			t.Fatalf("failed to create new client: %s", err)
+		}
+		if err = client.DialAndSend(testMessage(t)); err != nil {
+			t.Fatalf("failed to send message: %s", err)
+		}
+		if strings.Contains(server.commands(), "XCLIENT") || strings.Contains(server.commands(), "XFORWARD") {
+			t.Errorf("expected no XCLIENT or XFORWARD commands, got: %q", server.commands())
+		}
+	})
+	t.Run("rejected XCLIENT fails the dial", func(t *testing.T) {
+		server := testXClientServer(t, "550 5.7.0 Error: insufficient authorization", "", "XCLIENT NAME ADDR")
+		client := testXClientClient(t, server, original)
+		if err := client.DialAndSend(testMessage(t)); err == nil {
+			t.Error("expected rejected XCLIENT to fail the dial")
+		}
+		if strings.Contains(server.commands(), "MAIL FROM:") {
+			t.Errorf("expected no mail transaction, got: %q", server.commands())
+		}
+	})
+	t.Run("rejected XFORWARD fails the delivery", func(t *testing.T) {
+		server := testXClientServer(t, "", "421 4.7.0 Error: insufficient authorization", "XFORWARD NAME ADDR")
+		client := testXClientClient(t, server, original)
+		message := testMessage(t)
+		err := client.DialAndSend(message)
+		var sendErr *SendError
+		if !errors.As(err, &sendErr) {
+			t.Fatalf("expected SendError, got: %s", err)
+		}
+		if sendErr.Reason != ErrSMTPMailFrom {
+			t.Errorf("expected ErrSMTPMailFrom, got: %s", sendErr.Reason)
+		}
+		if !sendErr.IsTemp() || sendErr.ErrorCode() != 421 {
+			t.Errorf("expected temporary error with code 421, got: %d", sendErr.ErrorCode())
+		}
+		if strings.Contains(server.commands(), "MAIL FROM:") {
+			t.Errorf("expected no mail transaction, got: %q", server.commands())
+		}
+	})
+}
+
+// testXClient is a minimal SMTP server for the XCLIENT and XFORWARD tests, that advertises the
+// configured extensions and replies to XCLIENT and XFORWARD with the configured replies.
+type testXClient struct {
+	mutex      sync.Mutex
+	port       int
+	log        strings.Builder
+	extensions []string
+	xclient    string
+	xforward   string
+}
+
+// commands returns the commands received by the server.
+func (s *testXClient) commands() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.log.String()
+}
+
+// testXClientServer starts a testXClient server with the given replies to XCLIENT and XFORWARD
+// that advertises the given EHLO extensions. Empty replies accept the commands.
+func testXClientServer(t *testing.T, xclient, xforward string, extensions ...string) *testXClient {
+	t.Helper()
+	PortAdder.Add(1)
+	server := &testXClient{
+		port:       int(TestServerPortBase + PortAdder.Load()),
+		extensions: extensions,
+		xclient:    xclient,
+		xforward:   xforward,
+	}
+	listener, err := net.Listen(TestServerProto, fmt.Sprintf("%s:%d", TestServerAddr, server.port))
+	if err != nil {
+		t.Fatalf("failed to listen on test server port: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, aerr := listener.Accept()
+			if aerr != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+// handle serves a single SMTP connection.
+func (s *testXClient) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	reader := bufio.NewReader(conn)
+	writeLine := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+	writeLine("220 go-mail test server ready")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		s.mutex.Lock()
+		s.log.WriteString(line + "\n")
+		s.mutex.Unlock()
+		switch {
+		case strings.HasPrefix(line, "EHLO"):
+			writeLine("250-localhost.localdomain\r\n250-" + strings.Join(s.extensions, "\r\n250-") +
+				"\r\n250 ENHANCEDSTATUSCODES")
+		case strings.HasPrefix(line, "XCLIENT"):
+			if s.xclient != "" {
+				writeLine(s.xclient)
+				continue
+			}
+			writeLine("220 go-mail test server ready")
+		case strings.HasPrefix(line, "XFORWARD"):
+			if s.xforward != "" {
+				writeLine(s.xforward)
+				continue
+			}
+			writeLine("250 2.0.0 Ok")
+		case strings.HasPrefix(line, "MAIL FROM:"), strings.HasPrefix(line, "RCPT TO:"):
+			writeLine("250 2.0.0 Ok")
+		case line == "DATA":
+			writeLine("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				data, derr := reader.ReadString('\n')
+				if derr != nil {
+					return
+				}
+				if strings.TrimSpace(data) == "." {
+					break
+				}
+			}
+			writeLine("250 2.0.0 Ok: queued as 1234567890")
+		case line == "NOOP", line == "RSET":
+			writeLine("250 2.0.0 Ok")
+		case line == "QUIT":
+			writeLine("221 2.0.0 Bye")
+			return
+		default:
+			writeLine("500 5.5.2 Error: bad syntax")
+		}
+	}
+}
+
+// testXClientClient returns a Client with the given original client information that connects
+// to the given testXClient server.
+func testXClientClient(t *testing.T, server *testXClient, original OriginalClient) *Client {
+	t.Helper()
+	client, err := NewClient(DefaultHost, WithPort(server.port), WithTLSPolicy(NoTLS),
+		WithOriginalClient(original))
+	if err != nil {
+		t.Fatalf("failed to create new client: %s", err)
+	}
+	return client
+}